@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"api-server/domain"
+	"api-server/internal/lookup"
+	"api-server/pkg/cep"
+)
+
+// chunkSize is how many rows are looked up before they are written: at most this many lookups are
+// lost on an interruption.
+const chunkSize = 100
+
+// Error codes of the error column, the same as the API codes where there is one.
+const (
+	codeMissingCEP             = "missing_cep"
+	codeInvalidCEP             = "invalid_cep"
+	codeCEPNotFound            = "cep_not_found"
+	codeTemperatureUnavailable = "temperature_unavailable"
+	codeUpstreamUnavailable    = "upstream_unavailable"
+)
+
+// lookuper is the part of lookup.Service the enricher uses.
+type lookuper interface {
+	City(ctx context.Context, c cep.CEP) (lookup.City, error)
+	Temperature(ctx context.Context, cityInfo string) (lookup.Temperature, error)
+}
+
+// enricher adds the city and the temperature of the CEP in a column to each row. Like a batch of the
+// API, each CEP is looked up only once in the whole file, and so is each city.
+type enricher struct {
+	*lookup.Batch[lookup.City, lookup.Temperature]
+	column int
+	// width is the number of columns of the input, so the added ones line up on short rows
+	width   int
+	weather bool
+	format  lookup.Format
+
+	rows   int
+	failed int
+}
+
+func newEnricher(service lookuper, workers, column, width int, weather bool, format lookup.Format) *enricher {
+	temperature := service.Temperature
+	if !weather {
+		format.Units, temperature = nil, nil
+	}
+	cityInfo := func(city lookup.City) string { return city.Info }
+	return &enricher{
+		Batch:   lookup.NewBatch(workers, service.City, cityInfo, temperature),
+		column:  column,
+		width:   width,
+		weather: weather,
+		format:  format,
+	}
+}
+
+// header is the header of the input followed by the added columns.
+func (e *enricher) header(header []string) []string {
+	added := append([]string{"city", "uf"}, e.format.Columns()...)
+	added = append(added, "provider", "error")
+	return append(e.pad(header), added...)
+}
+
+func (e *enricher) pad(row []string) []string {
+	for len(row) < e.width {
+		row = append(row, "")
+	}
+	return row
+}
+
+// run enriches the rows of r into w, a chunk at a time. On cancellation the chunk being looked up is
+// dropped, so w ends with a complete row and ctx.Err() is returned.
+func (e *enricher) run(ctx context.Context, r *csv.Reader, w *csv.Writer) error {
+	for {
+		rows, err := readChunk(r)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		enriched := e.enrich(ctx, rows)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.WriteAll(enriched); err != nil {
+			return err
+		}
+		e.rows += len(rows)
+		for _, row := range enriched {
+			if row[len(row)-1] != "" {
+				e.failed++
+			}
+		}
+	}
+}
+
+func readChunk(r *csv.Reader) ([][]string, error) {
+	var rows [][]string
+	for len(rows) < chunkSize {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (e *enricher) enrich(ctx context.Context, rows [][]string) [][]string {
+	parsed := make([]cep.CEP, len(rows))
+	codes := make([]string, len(rows))
+
+	var ceps []cep.CEP
+	for i, row := range rows {
+		if e.column >= len(row) || strings.TrimSpace(row[e.column]) == "" {
+			codes[i] = codeMissingCEP
+			continue
+		}
+		p, err := cep.Parse(strings.TrimSpace(row[e.column]))
+		if err != nil {
+			codes[i] = codeInvalidCEP + ": " + err.Error()
+			continue
+		}
+		parsed[i] = p
+		ceps = append(ceps, p)
+	}
+	e.Lookup(ctx, ceps)
+
+	out := make([][]string, len(rows))
+	for i, row := range rows {
+		out[i] = e.row(row, parsed[i], codes[i])
+	}
+	return out
+}
+
+// row is the input row with the added columns. An invalid CEP or a failed lookup fills the error
+// column instead of stopping the run.
+func (e *enricher) row(row []string, c cep.CEP, code string) []string {
+	temps := make([]string, len(e.format.Units))
+	var city lookup.City
+	if code == "" {
+		city, code = e.result(c, temps)
+	}
+	row = append(e.pad(row), city.Name, city.UF)
+	row = append(row, temps...)
+	return append(row, city.Provider, code)
+}
+
+// result is the city of the CEP and, in temps, its temperature; the city is kept when only the
+// temperature failed.
+func (e *enricher) result(c cep.CEP, temps []string) (lookup.City, string) {
+	city := e.Cities[c]
+	if city.Err != nil {
+		return lookup.City{}, cityErrorCode(city.Err)
+	}
+	if !e.weather {
+		return city.Value, ""
+	}
+	temp := e.Temps[city.Value.Info]
+	if temp.Err != nil {
+		return city.Value, temperatureErrorCode(temp.Err)
+	}
+	copy(temps, e.format.Strings(temp.Value.Celsius))
+	return city.Value, ""
+}
+
+func cityErrorCode(err error) string {
+	if errors.Is(err, domain.ErrNoProviderAvailable) {
+		return codeUpstreamUnavailable
+	}
+	return codeCEPNotFound
+}
+
+func temperatureErrorCode(err error) string {
+	if errors.Is(err, domain.ErrProviderUnavailable) {
+		return codeUpstreamUnavailable
+	}
+	return codeTemperatureUnavailable
+}
+
+// findColumn finds the CEP column by its name, regardless of case, or by its position starting at 1,
+// as in a spreadsheet. Without a header only the position is accepted.
+func findColumn(header []string, column string) (int, error) {
+	for i, name := range header {
+		// Planilhas exportadas pelo Excel começam com um BOM
+		if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), strings.TrimSpace(column)) {
+			return i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(column)
+	switch {
+	case err != nil && header == nil:
+		return 0, fmt.Errorf("-column: %q must be a position, the input has no header", column)
+	case err != nil:
+		return 0, fmt.Errorf("-column: no column %q in the header: %s", column, strings.Join(header, ", "))
+	case n < 1:
+		return 0, fmt.Errorf("-column: position %d must be at least 1", n)
+	case header != nil && n > len(header):
+		return 0, fmt.Errorf("-column: position %d out of the %d columns", n, len(header))
+	}
+	return n - 1, nil
+}
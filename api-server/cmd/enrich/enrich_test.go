@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"api-server/domain"
+	"api-server/internal/lookup"
+	"api-server/pkg/cep"
+	"api-server/pkg/temperature"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLookup resolves the CEPs of cities and counts the lookups.
+type fakeLookup struct {
+	mu      sync.Mutex
+	cities  map[string]string
+	lookups map[string]int
+	// cancel is called on the lookup of cancelOn, as an interruption would
+	cancelOn string
+	cancel   context.CancelFunc
+}
+
+func newFakeLookup() *fakeLookup {
+	return &fakeLookup{
+		cities: map[string]string{
+			"01001000": "São Paulo,SP",
+			"01310100": "São Paulo,SP",
+			"20040020": "Rio de Janeiro,RJ",
+			"30130010": "Belo Horizonte,MG",
+		},
+		lookups: make(map[string]int),
+	}
+}
+
+func (f *fakeLookup) City(ctx context.Context, c cep.CEP) (lookup.City, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups[c.Digits()]++
+	if c.Digits() == f.cancelOn {
+		f.cancel()
+	}
+	info, ok := f.cities[c.Digits()]
+	if !ok {
+		return lookup.City{}, domain.ErrCEPNotFound
+	}
+	name, uf, _ := strings.Cut(info, ",")
+	return lookup.City{Info: info, Name: name, UF: uf, Provider: domain.ProviderBrasilAPI}, nil
+}
+
+func (f *fakeLookup) Temperature(ctx context.Context, cityInfo string) (lookup.Temperature, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups[cityInfo]++
+	if cityInfo == "Belo Horizonte,MG" {
+		return lookup.Temperature{}, errors.New("weather api error")
+	}
+	return lookup.Temperature{Celsius: 25, Provider: domain.ProviderHGWeather}, nil
+}
+
+func testOptions() options {
+	return options{
+		column:    "cep",
+		delimiter: ',',
+		workers:   4,
+		temp:      lookup.Format{Units: []temperature.Unit{temperature.Celsius, temperature.Fahrenheit}, Precision: 2, Rounding: temperature.HalfUp},
+		settings:  lookup.Settings{Weather: true},
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.csv")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should add the columns and fill the error column of bad rows", func(t *testing.T) {
+		input := writeFile(t, "\ufeffNome,CEP,Obs\n"+
+			"Sé,01001-000,a\n"+
+			"Paulista,01310100,\"b, c\"\n"+
+			"Sé de novo,01001000\n"+
+			"Centro,20040020,d\n"+
+			"BH,30130010,e\n"+
+			"Nada,99999999,f\n"+
+			"Inválido,123,g\n"+
+			"Vazio,,h\n")
+		service := newFakeLookup()
+		opts := testOptions()
+		opts.column = "Cep"
+
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, run(context.Background(), service, input, opts, &stdout, &stderr))
+
+		assert.Equal(t, "\ufeffNome,CEP,Obs,city,uf,temp_C,temp_F,provider,error\n"+
+			"Sé,01001-000,a,São Paulo,SP,25,77,BrasilAPI,\n"+
+			"Paulista,01310100,\"b, c\",São Paulo,SP,25,77,BrasilAPI,\n"+
+			"Sé de novo,01001000,,São Paulo,SP,25,77,BrasilAPI,\n"+
+			"Centro,20040020,d,Rio de Janeiro,RJ,25,77,BrasilAPI,\n"+
+			"BH,30130010,e,Belo Horizonte,MG,,,BrasilAPI,temperature_unavailable\n"+
+			"Nada,99999999,f,,,,,,cep_not_found\n"+
+			"Inválido,123,g,,,,,,\"invalid_cep: cep must have 8 digits, as 00000000 or 00000-000\"\n"+
+			"Vazio,,h,,,,,,missing_cep\n", stdout.String())
+		assert.Equal(t, 1, service.lookups["01001000"], "a CEP is looked up once")
+		assert.Equal(t, 1, service.lookups["São Paulo,SP"], "a city is looked up once")
+		assert.Contains(t, stderr.String(), "8 rows enriched, 4 with errors; 5 CEPs and 3 cities looked up")
+	})
+
+	t.Run("should take the column by position and the delimiter", func(t *testing.T) {
+		input := writeFile(t, "20040020;x\n99999999;y\n")
+		opts := testOptions()
+		opts.noHeader, opts.column, opts.delimiter = true, "1", ';'
+		opts.settings.Weather = false
+
+		var stdout bytes.Buffer
+		require.NoError(t, run(context.Background(), newFakeLookup(), input, opts, &stdout, io.Discard))
+		assert.Equal(t, "20040020;x;Rio de Janeiro;RJ;BrasilAPI;\n99999999;y;;;;cep_not_found\n", stdout.String())
+	})
+
+	t.Run("should reject an unknown column", func(t *testing.T) {
+		input := writeFile(t, "nome,endereco\n")
+		err := run(context.Background(), newFakeLookup(), input, testOptions(), io.Discard, io.Discard)
+		assert.ErrorContains(t, err, `no column "cep"`)
+	})
+}
+
+func TestRun_Resume(t *testing.T) {
+	var rows strings.Builder
+	rows.WriteString("id,cep\n")
+	ceps := []string{"01001000", "20040020", "30130010", "99999999"}
+	n := 2*chunkSize + 10
+	for i := 0; i < n; i++ {
+		c := ceps[i%len(ceps)]
+		if i == chunkSize+50 {
+			c = "40010000"
+		}
+		fmt.Fprintf(&rows, "%d,%s\n", i, c)
+	}
+	input := writeFile(t, rows.String())
+	opts := testOptions()
+	opts.output = filepath.Join(t.TempDir(), "output.csv")
+
+	// Interrompido no meio do segundo bloco: só o primeiro fica na saída
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := newFakeLookup()
+	service.cancelOn, service.cancel = "40010000", cancel
+	err := run(ctx, service, input, opts, io.Discard, io.Discard)
+	assert.ErrorContains(t, err, fmt.Sprintf("interrupted after %d rows", chunkSize))
+
+	output, err := os.ReadFile(opts.output)
+	require.NoError(t, err)
+	assert.Equal(t, chunkSize+1, strings.Count(string(output), "\n"))
+
+	// Uma linha pela metade, de uma queda, é descartada
+	f, err := os.OpenFile(opts.output, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("100,01001000,São Pa")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	opts.resume = true
+	var stderr bytes.Buffer
+	require.NoError(t, run(context.Background(), newFakeLookup(), input, opts, io.Discard, &stderr))
+	assert.Contains(t, stderr.String(), fmt.Sprintf("%d rows enriched", n-chunkSize))
+	assert.Contains(t, stderr.String(), fmt.Sprintf("%d rows were already done", chunkSize))
+
+	complete := testOptions()
+	var expected bytes.Buffer
+	require.NoError(t, run(context.Background(), newFakeLookup(), input, complete, &expected, io.Discard))
+	output, err = os.ReadFile(opts.output)
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), string(output), "the resumed output is the same as a complete run")
+
+	t.Run("should do nothing on a finished output", func(t *testing.T) {
+		var stderr bytes.Buffer
+		require.NoError(t, run(context.Background(), newFakeLookup(), input, opts, io.Discard, &stderr))
+		assert.Contains(t, stderr.String(), "0 rows enriched")
+	})
+
+	t.Run("should refuse the output of another input", func(t *testing.T) {
+		err := run(context.Background(), newFakeLookup(), writeFile(t, "nome,cep\n"), opts, io.Discard, io.Discard)
+		assert.ErrorContains(t, err, "was not written from this input")
+	})
+}
+
+func TestFindColumn(t *testing.T) {
+	header := []string{"\ufeffNome", " CEP ", "1"}
+	for column, expected := range map[string]int{"nome": 0, "cep": 1, "1": 2, "2": 1} {
+		i, err := findColumn(header, column)
+		require.NoError(t, err, column)
+		assert.Equal(t, expected, i, column)
+	}
+	for _, column := range []string{"uf", "0", "4"} {
+		_, err := findColumn(header, column)
+		assert.Error(t, err, column)
+	}
+	_, err := findColumn(nil, "cep")
+	assert.ErrorContains(t, err, "no header")
+}
@@ -0,0 +1,273 @@
+// Command enrich adds the city, the UF and the current temperature of the CEPs in a column of a CSV
+// file, e.g.:
+//
+//	WEATHER_API_KEY=... enrich -column cep -o clientes_temp.csv clientes.csv
+//
+// An interrupted run continues where it stopped when run again with -resume.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"unicode/utf8"
+
+	"api-server/internal/config"
+	"api-server/internal/lookup"
+	"api-server/pkg/env"
+	"api-server/pkg/temperature"
+)
+
+type options struct {
+	output    string
+	column    string
+	noHeader  bool
+	delimiter rune
+	workers   int
+	resume    bool
+	verbose   bool
+	temp      lookup.Format
+	settings  lookup.Settings
+}
+
+func main() {
+	opts, input, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "enrich:", err)
+		os.Exit(2)
+	}
+
+	// Os logs do serviço só aparecem com -v, e sempre no stderr
+	log := lookup.NewLogger(os.Stderr, opts.verbose, opts.settings)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, lookup.New(opts.settings, log), input, opts, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "enrich:", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string, output io.Writer) (options, string, error) {
+	opts := options{
+		delimiter: ',',
+		temp:      lookup.Format{Units: []temperature.Unit{temperature.Celsius}, Precision: 2, Rounding: temperature.HalfUp},
+		settings:  lookup.DefaultSettings(),
+	}
+
+	fs := flag.NewFlagSet("enrich", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: enrich [flags] [FILE]")
+		fmt.Fprintln(fs.Output(), "\nWrites FILE, or the standard input, with the city, uf, temperature, provider and error columns added. The weather API key is read from "+config.EnvWeatherAPIKey+" or "+config.EnvWeatherAPIKey+env.FileSuffix+".")
+		fmt.Fprintln(fs.Output(), "A row whose CEP can not be looked up gets the error column filled instead of stopping the run.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&opts.output, "o", "", "output file; the standard output when empty")
+	fs.StringVar(&opts.column, "column", "cep", "name of the CEP column, or its position starting at 1")
+	fs.BoolVar(&opts.noHeader, "no-header", false, "the first row is data, not a header; -column must be a position")
+	fs.Func("delimiter", `field delimiter, e.g. ";" or "\t" (default ",")`, func(s string) error {
+		if s == `\t` {
+			s = "\t"
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return fmt.Errorf("invalid delimiter %q", s)
+		}
+		opts.delimiter = r
+		return nil
+	})
+	fs.IntVar(&opts.workers, "workers", config.Default().Batch.Workers, "concurrent lookups")
+	fs.BoolVar(&opts.resume, "resume", false, "continue an interrupted run, appending to the rows already in -o")
+	opts.temp.AddFlags(fs)
+	opts.settings.AddFlags(fs)
+	fs.BoolVar(&opts.verbose, "v", false, "print the warnings of the lookups")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, "", err
+	}
+
+	var errs []error
+	if opts.workers < 1 {
+		errs = append(errs, errors.New("-workers: must be at least 1"))
+	}
+	if opts.resume && opts.output == "" {
+		errs = append(errs, errors.New("-resume: requires -o"))
+	}
+	if err := opts.settings.ReadKey(); err != nil {
+		errs = append(errs, err)
+	}
+	if fs.NArg() > 1 {
+		errs = append(errs, errors.New("at most one input file"))
+	}
+
+	return opts, fs.Arg(0), errors.Join(errs...)
+}
+
+// run enriches input, a file or "-" for stdin, into -o or stdout and prints a summary to stderr.
+func run(ctx context.Context, service lookuper, input string, opts options, stdout, stderr io.Writer) error {
+	in := io.Reader(os.Stdin)
+	if input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := newReader(in, opts.delimiter)
+
+	var header []string
+	if !opts.noHeader {
+		var err error
+		if header, err = r.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("the input is empty")
+			}
+			return err
+		}
+	}
+	column, err := findColumn(header, opts.column)
+	if err != nil {
+		return err
+	}
+	e := newEnricher(service, opts.workers, column, max(len(header), column+1), opts.settings.Weather, opts.temp)
+
+	out := stdout
+	// written são as linhas já na saída, com o cabeçalho; done, só as de dados
+	var written, done int
+	if opts.output != "" {
+		flags := os.O_RDWR | os.O_CREATE
+		if !opts.resume {
+			flags |= os.O_TRUNC
+		}
+		f, err := os.OpenFile(opts.output, flags, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if opts.resume {
+			if written, err = skipDone(f, r, e, header, opts.delimiter); err != nil {
+				return err
+			}
+			done = max(written-1, 0)
+			if header == nil {
+				done = written
+			}
+		}
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	w.Comma = opts.delimiter
+	if header != nil && written == 0 {
+		_ = w.Write(e.header(header))
+	}
+	err = e.run(ctx, r, w)
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+
+	fmt.Fprintf(stderr, "%d rows enriched, %d with errors; %d CEPs and %d cities looked up\n", e.rows, e.failed, len(e.Cities), len(e.Temps))
+	if done > 0 {
+		fmt.Fprintf(stderr, "%d rows were already done\n", done)
+	}
+	switch {
+	case ctx.Err() != nil && opts.output != "":
+		return fmt.Errorf("interrupted after %d rows, run again with -resume to continue", done+e.rows)
+	case ctx.Err() != nil:
+		return fmt.Errorf("interrupted after %d rows", e.rows)
+	}
+	return err
+}
+
+// skipDone prepares the output of an interrupted run to be continued and skips, in r, the input rows
+// already in it. It returns how many rows the output has, header included.
+func skipDone(f *os.File, r *csv.Reader, e *enricher, header []string, comma rune) (int, error) {
+	rows, first, err := resumeOutput(f, comma)
+	if err != nil || rows == 0 {
+		return 0, err
+	}
+	skip := rows
+	if header != nil {
+		if !slices.Equal(first, e.header(header)) {
+			return 0, fmt.Errorf("%s was not written from this input with these flags: remove it or run without -resume", f.Name())
+		}
+		skip--
+	}
+
+	for i := 0; i < skip; i++ {
+		if _, err := r.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, fmt.Errorf("%s has more rows than the input", f.Name())
+			}
+			return 0, err
+		}
+	}
+	return rows, nil
+}
+
+// resumeOutput readies f to be appended to: a partial last row, left by a crash, is cut off. It
+// returns how many complete rows f has, header included, and the first one.
+func resumeOutput(f *os.File, comma rune) (int, []string, error) {
+	r := newReader(f, comma)
+	var (
+		rows      int
+		first     []string
+		end, prev int64
+	)
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("reading %s: %w", f.Name(), err)
+		}
+		if rows == 0 {
+			first = row
+		}
+		rows++
+		prev, end = end, r.InputOffset()
+	}
+
+	// Uma linha sem o \n final foi escrita pela metade
+	if end > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, end-1); err != nil {
+			return 0, nil, err
+		}
+		if last[0] != '\n' {
+			rows, end = rows-1, prev
+		}
+	}
+	if err := f.Truncate(end); err != nil {
+		return 0, nil, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return 0, nil, err
+	}
+	return rows, first, nil
+}
+
+func newReader(r io.Reader, comma rune) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	// Linhas com colunas a mais ou a menos, ou aspas soltas, não interrompem a leitura
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return cr
+}
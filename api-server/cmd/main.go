@@ -1,25 +1,39 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	"os/signal"
 	"syscall"
 
+	"api-server/domain"
 	"api-server/domain/analysis"
+	"api-server/internal/config"
 	"api-server/internal/infra/client"
+	"api-server/internal/infra/health"
+	"api-server/internal/infra/jobs"
+	"api-server/internal/infra/metrics"
+	"api-server/internal/infra/server/grpc"
 	"api-server/internal/infra/server/http"
+	"api-server/internal/infra/stream"
+	"api-server/internal/infra/tracing"
+	"api-server/pkg/apikey"
+	"api-server/pkg/circuitbreaker"
+	"api-server/pkg/cloudrun"
 	"api-server/pkg/env"
 	httpclient "api-server/pkg/http_client"
-	"log"
-)
-
-const (
-	envApplicationPort = "APP_PORT"
-	envWeatherAPIKey   = "WEATHER_API_KEY"
-
-	defaultApplicationPort = "8080"
+	"api-server/pkg/lifecycle"
+	"api-server/pkg/logger"
+	"api-server/pkg/ratelimit"
+	"api-server/pkg/temperature"
 )
 
 var (
@@ -27,39 +41,383 @@ var (
 )
 
 func main() {
-	logger := log.New(os.Stdout, "api-server-temperature - ", log.LstdFlags)
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if cfg != nil && cfg.PrintConfig {
+		if printErr := cfg.Print(os.Stdout); printErr != nil {
+			fatal(slog.Default(), "Could not print configuration", logger.Err(printErr))
+		}
+		if err == nil {
+			return
+		}
+	}
+	if err != nil {
+		fatal(slog.Default(), "Invalid configuration", logger.Err(err))
+	}
+
+	metadata := cloudrun.FromEnv()
+	level := &slog.LevelVar{}
+	log := newLogger(cfg, metadata, level)
+	slog.SetDefault(log)
+
+	httpclient.DefaultRedactor.AddSecret(cfg.Weather.APIKey)
+	httpclient.DefaultRedactor.AddSecret(cfg.Admin.Token)
+
+	log.Info("API Busca Temperatura com CEP", "version", version, "build", build, "date", date)
+	if metadata.OnCloudRun() {
+		log.Info("Running on Cloud Run", "service", metadata.Service, "revision", metadata.Revision,
+			"configuration", metadata.Configuration)
+	}
+
+	if cfg.File != "" {
+		log.Info("Configuration loaded", "file", cfg.File)
+	}
+
+	breakers := newBreakers(cfg, log)
+	tracker := health.NewTracker(domain.ProviderBrasilAPI, domain.ProviderViaCEP, domain.ProviderHGWeather)
+	readiness := &health.Readiness{}
+
+	manager := lifecycle.New(log,
+		lifecycle.WithReadiness(readiness),
+		lifecycle.WithPreStopDelay(cfg.Shutdown.PreStopDelay),
+		lifecycle.WithShutdownTimeout(cfg.Shutdown.Timeout))
+	shutdownTracing, err := tracing.Setup(context.Background(), version)
+	if err != nil {
+		fatal(log, "Could not set up tracing", logger.Err(err))
+	}
+	if tracing.Enabled() {
+		log.Info("Exporting traces over OTLP")
+	}
+	// Roda como worker para o flush acontecer depois do drain dos servidores
+	manager.Go("tracing", func(ctx context.Context) error {
+		<-ctx.Done()
+		flushCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Warn("Could not flush traces", logger.Err(err))
+		}
+		return nil
+	})
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterBreakers(breakers)
+
+	upstreamHTTPClient := httpclient.NewTrackedHTTPClient(
+		httpclient.NewHTTPClient(cfg.Upstream.Timeout, appMetrics.RoundTripper(client.ProviderForHost), tracing.Transport), manager.InFlight())
+
+	clientOpts := []client.Option{
+		client.WithRetryObserver(appMetrics),
+		client.WithRetries(cfg.Upstream.MaxRetries, cfg.Upstream.MaxRetryInterval),
+	}
 
-	env.CheckRequired(logger, envWeatherAPIKey)
+	observedBuscaCEPAPIClient := client.NewObservedBuscaCEPAPIClient(
+		client.NewBuscaCEPAPIClient(upstreamHTTPClient, log, clientOpts...), tracker)
+	buscaCEPAPIClient := client.NewBreakerBuscaCEPAPIClient(observedBuscaCEPAPIClient, breakers)
 
-	logger.Printf("API Busca Temperatura com CEP - version:%s; build:%s; date:%s", version, build, date)
+	var watches secretWatches
 
-	buscaCEPAPIClient := client.NewBuscaCEPAPIClient(httpclient.NewHTTPClient(60*time.Second), logger)
+	// A chave é relida a cada chamada, então rotação e reload não exigem restart
+	weatherAPIKey := newLiveString(cfg.Weather.APIKey)
+	if secret := envSecret(log, config.EnvWeatherAPIKey); secret != nil {
+		watches = append(watches, secretWatch{secret: secret, onChange: func(value string) {
+			httpclient.DefaultRedactor.AddSecret(value)
+			weatherAPIKey.Set(value)
+		}})
+	}
 
-	weatherAPIClient := client.NewWeatherAPIClient(httpclient.NewHTTPClient(60*time.Second), logger, getWeatherAPIKey())
+	observedWeatherAPIClient := client.NewObservedWeatherAPIClient(
+		client.NewWeatherAPIClient(upstreamHTTPClient, log, weatherAPIKey.Get, clientOpts...), tracker)
+	weatherAPIClient := client.NewBreakerWeatherAPIClient(observedWeatherAPIClient, breakers)
 
-	analysisService := analysis.NewAnalysisService(buscaCEPAPIClient, weatherAPIClient, logger)
+	analysisService := analysis.NewAnalysisService(buscaCEPAPIClient, weatherAPIClient, log,
+		analysis.WithProviderGate(breakers), analysis.WithRaceObserver(appMetrics),
+		analysis.WithSettings(analysisSettings(cfg)))
 
-	handler := http.NewHandler(analysisService, logger)
+	rateLimitPolicy := ratelimit.NewDynamicPolicy(getRateLimitPolicy(cfg, log))
+	if secret := envSecret(log, config.EnvRateLimitAPIKeys); secret != nil {
+		watches = append(watches, secretWatch{secret: secret, onChange: func(value string) {
+			apiKeyTiers, err := ratelimit.ParseAPIKeyTiers(value)
+			if err != nil {
+				log.Error("Rotated secret is invalid: keeping the previous one", "name", secret.Name(), logger.Err(err))
+				return
+			}
+			policy := rateLimitPolicy.Load()
+			policy.APIKeyTiers = apiKeyTiers
+			rateLimitPolicy.Store(policy)
+		}})
+	}
+
+	// HTTP e gRPC consomem do mesmo bucket por chave
+	rateLimitStore := ratelimit.NewMemoryStore()
+	grpcOpts := []grpc.Option{grpc.WithRateLimit(rateLimitStore, rateLimitPolicy)}
+	handlerOpts := []http.Option{
+		http.WithBreakers(breakers),
+		http.WithRateLimit(rateLimitStore, rateLimitPolicy, cfg.RateLimit.TrustedProxies),
+		http.WithTemperatureDefaults(cfg.Temperature.Precision, getTemperatureRounding(cfg, log)),
+		http.WithBatch(cfg.Batch.MaxSize, cfg.Batch.Workers),
+		http.WithHealth(readiness, tracker),
+		http.WithMetrics(appMetrics),
+		http.WithVersion(http.VersionResponse{
+			Version:       version,
+			Build:         build,
+			Date:          date,
+			Service:       metadata.Service,
+			Revision:      metadata.Revision,
+			Configuration: metadata.Configuration,
+		}),
+	}
+	if cfg.HTTP.AccessLog {
+		handlerOpts = append(handlerOpts, http.WithAccessLog())
+	}
+	if cfg.Env == config.EnvLocal {
+		handlerOpts = append(handlerOpts, http.WithDebug())
+	}
+	if apiKeys := getAPIKeys(cfg, log); apiKeys != nil {
+		handlerOpts = append(handlerOpts, http.WithAPIKeys(apiKeys))
+		grpcOpts = append(grpcOpts, grpc.WithAPIKeys(apiKeys))
+		if cfg.APIKeys.File != "" {
+			secret, err := env.NewSecret(env.FileSecret(cfg.APIKeys.File), config.EnvAPIKeysFile)
+			if err != nil {
+				fatal(log, "Could not load API keys", "path", cfg.APIKeys.File, logger.Err(err))
+			}
+			watches = append(watches, secretWatch{secret: secret, onChange: func(string) {
+				keys, err := loadAPIKeys(cfg)
+				if err == nil {
+					err = apiKeys.Replace(keys)
+				}
+				if err != nil {
+					log.Error("Rotated API keys are invalid: keeping the previous ones", "path", cfg.APIKeys.File, logger.Err(err))
+					return
+				}
+				log.Info("API keys reloaded", "keys", apiKeys.Len())
+			}})
+		}
+	}
+
+	// SIGHUP e POST /admin/reload aplicam o arquivo de configuração sem restart
+	reload := &reloader{
+		current: cfg,
+		load: func() (*config.Config, error) {
+			return config.Load(os.Args[1:], os.LookupEnv)
+		},
+		log:           log,
+		metrics:       appMetrics,
+		level:         level,
+		analysis:      analysisService,
+		rateLimit:     rateLimitPolicy,
+		weatherAPIKey: weatherAPIKey,
+		adminToken:    newLiveString(cfg.Admin.Token),
+	}
+	if secret := envSecret(log, config.EnvAdminToken); secret != nil {
+		watches = append(watches, secretWatch{secret: secret, onChange: func(value string) {
+			httpclient.DefaultRedactor.AddSecret(value)
+			reload.adminToken.Set(value)
+		}})
+	}
+	watches.run(manager, cfg.Secrets.RefreshInterval, log)
+	reload.runOnSignal(manager)
+
+	handlerOpts = append(handlerOpts, http.WithReload(func(ctx context.Context) (http.ReloadResponse, error) {
+		changed, restart, err := reload.Reload("admin endpoint")
+		return http.ReloadResponse{Changed: changed, RestartRequired: restart}, err
+	}, reload.adminToken.Get))
+
+	jobRunner, err := newJobRunner(cfg, analysisService, log)
+	if err != nil {
+		fatal(log, "Could not open job store", "dir", cfg.Jobs.Dir, logger.Err(err))
+	}
+	manager.Go("jobs", jobRunner.Run)
+	handlerOpts = append(handlerOpts, http.WithJobs(jobRunner, cfg.Jobs.MaxSize))
+
+	// Um único polling por cidade, compartilhado por todos os streams dela
+	streamHub := stream.NewHub(analysisService.GetCelsiusTemperature, log, stream.WithInterval(cfg.Stream.PollInterval))
+	handlerOpts = append(handlerOpts, http.WithStream(streamHub, cfg.Stream.Heartbeat))
+
+	handler := http.NewHandler(analysisService, log, handlerOpts...)
 
 	/*
 	 * Server...
 	 */
-	server := http.New(getApplicationPort(), handler, logger)
-	server.ListenAndServe()
+	httpServer := http.New(cfg.HTTP.Port, handler, log)
+	httpServer.RegisterOnShutdown(streamHub.Close)
+	manager.AddServer("http", httpServer)
+	manager.AddServer("grpc", grpc.New(cfg.GRPC.Port, grpc.NewTemperatureService(analysisService, log), log, grpcOpts...))
+
+	if cfg.Health.ProbeInterval > 0 {
+		prober := health.NewProber(cfg.Health.ProbeInterval, cfg.Health.ProbeTimeout, log,
+			newHealthProbes(observedBuscaCEPAPIClient, observedWeatherAPIClient)...)
+		manager.Go("health prober", func(ctx context.Context) error {
+			prober.Run(ctx)
+			return nil
+		})
+	}
 
 	/*
 	 * Graceful shutdown...
 	 */
-	stopChan := make(chan os.Signal, 1)
-	signal.Notify(stopChan, syscall.SIGTERM, syscall.SIGINT)
-	<-stopChan
-	server.Shutdown()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := manager.Run(ctx); err != nil {
+		fatal(log, "Server stopped with error", logger.Err(err))
+	}
+	log.Info("Server stopped")
+}
+
+// newLogger writes JSON, with the Cloud Logging fields on Cloud Run, or text.
+// The level is a LevelVar so a reload can change it.
+func newLogger(cfg *config.Config, metadata cloudrun.Metadata, level *slog.LevelVar) *slog.Logger {
+	format, _ := logger.ParseFormat(cfg.Log.Format)
+	parsed, _ := logger.ParseLevel(cfg.Log.Level)
+	level.Set(parsed)
+
+	opts := logger.Options{Format: format, Level: level, Redact: httpclient.DefaultRedactor.String}
+	if metadata.OnCloudRun() && format == logger.FormatJSON {
+		opts.ReplaceAttr = cloudrun.ReplaceAttr
+		opts.TraceAttrs = metadata.TraceAttrs
+		return logger.New(os.Stdout, opts).With(metadata.Labels())
+	}
+	return logger.New(os.Stdout, opts)
 }
 
-func getApplicationPort() string {
-	return env.GetString(envApplicationPort, defaultApplicationPort)
+func fatal(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
 }
 
-func getWeatherAPIKey() string {
-	return env.GetString(envWeatherAPIKey)
+func newBreakers(cfg *config.Config, log *slog.Logger) *circuitbreaker.Registry {
+	breakers := circuitbreaker.NewRegistry()
+
+	for _, provider := range []string{domain.ProviderBrasilAPI, domain.ProviderViaCEP, domain.ProviderHGWeather} {
+		settings := circuitbreaker.DefaultSettings(provider)
+		settings.FailureRatio = cfg.Breaker.FailureRatio
+		settings.MinRequests = uint32(cfg.Breaker.MinRequests)
+		settings.Interval = cfg.Breaker.Interval
+		settings.CoolDown = cfg.Breaker.CoolDown
+		settings.IsSuccessful = client.IsProviderSuccess
+		settings.OnStateChange = func(name string, from, to circuitbreaker.State) {
+			log.Warn("Circuit breaker changed state", logger.KeyProvider, name, "from", from.String(), "to", to.String())
+		}
+		breakers.Add(settings)
+	}
+
+	return breakers
+}
+
+func getRateLimitPolicy(cfg *config.Config, log *slog.Logger) ratelimit.Policy {
+	tiers, err := ratelimit.ParseTiers(strings.Join(cfg.RateLimit.Tiers, ","))
+	if err != nil {
+		fatal(log, "Invalid configuration", "key", "rate_limit.tiers", logger.Err(err))
+	}
+
+	apiKeyTiers, err := ratelimit.ParseAPIKeyTiers(strings.Join(cfg.RateLimit.APIKeys, ","))
+	if err != nil {
+		fatal(log, "Invalid configuration", "key", "rate_limit.api_keys", logger.Err(err))
+	}
+
+	return ratelimit.Policy{
+		Default: ratelimit.Limit{
+			Rate:  cfg.RateLimit.RPS,
+			Burst: cfg.RateLimit.Burst,
+		},
+		Tiers:       tiers,
+		APIKeyTiers: apiKeyTiers,
+	}
+}
+
+// getAPIKeys loads the partner API keys. Authentication stays disabled when none is configured.
+func getAPIKeys(cfg *config.Config, log *slog.Logger) *apikey.Store {
+	keys, err := loadAPIKeys(cfg)
+	if err != nil {
+		fatal(log, "Could not load API keys", logger.Err(err))
+	}
+
+	if len(keys) == 0 {
+		log.Info("No API keys configured: authentication disabled")
+		return nil
+	}
+
+	store, err := apikey.NewStore(keys)
+	if err != nil {
+		fatal(log, "Invalid API keys configuration", logger.Err(err))
+	}
+	log.Info("API key authentication enabled", "keys", store.Len())
+
+	return store
+}
+
+// loadAPIKeys reads the keys of API_KEYS_FILE and API_KEYS.
+func loadAPIKeys(cfg *config.Config) ([]apikey.Key, error) {
+	var keys []apikey.Key
+
+	if path := cfg.APIKeys.File; path != "" {
+		fileKeys, err := apikey.LoadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	envKeys, err := apikey.ParseEnv(strings.Join(cfg.APIKeys.Keys, ","))
+	if err != nil {
+		return nil, fmt.Errorf("api_keys.keys: %w", err)
+	}
+	return append(keys, envKeys...), nil
+}
+
+func getTemperatureRounding(cfg *config.Config, log *slog.Logger) temperature.RoundingMode {
+	mode, err := temperature.ParseRoundingMode(cfg.Temperature.Rounding)
+	if err != nil {
+		fatal(log, "Invalid configuration", "key", "temperature.rounding", logger.Err(err))
+	}
+	return mode
+}
+
+// newJobRunner runs the jobs with their own throttle, so a big job does not take the upstream
+// quota of the interactive requests.
+func newJobRunner(cfg *config.Config, analysisService domain.AnalysisService, log *slog.Logger) (*jobs.Runner, error) {
+	var store jobs.Store = jobs.NewMemoryStore()
+	if cfg.Jobs.Store == config.JobStoreFile {
+		fileStore, err := jobs.NewFileStore(cfg.Jobs.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	}
+
+	opts := []jobs.Option{
+		jobs.WithConcurrency(cfg.Jobs.Concurrency),
+		jobs.WithQueueSize(cfg.Jobs.QueueSize),
+		jobs.WithRetention(cfg.Jobs.Retention),
+	}
+	if cfg.Jobs.Rate > 0 {
+		opts = append(opts, jobs.WithThrottle(ratelimit.Limit{Rate: cfg.Jobs.Rate, Burst: int(math.Ceil(cfg.Jobs.Rate))}))
+	}
+
+	newProcessor := func() jobs.Processor {
+		return http.NewBatchProcessor(analysisService, cfg.Jobs.Workers)
+	}
+	return jobs.NewRunner(store, newProcessor, log, opts...), nil
+}
+
+// newHealthProbes checks each provider with a known CEP and city, bypassing the circuit breakers.
+func newHealthProbes(buscaCEPAPIClient domain.BuscaCEPAPIClient, weatherAPIClient domain.WeatherAPIClient) []health.Probe {
+	const probeCEP, probeCity = "01001000", "São Paulo,SP"
+
+	return []health.Probe{
+		{Provider: domain.ProviderBrasilAPI, Check: func(ctx context.Context) error {
+			_, err := buscaCEPAPIClient.GetBrasilAPICEP(ctx, probeCEP)
+			return err
+		}},
+		{Provider: domain.ProviderViaCEP, Check: func(ctx context.Context) error {
+			_, err := buscaCEPAPIClient.GetViaAPICEP(ctx, probeCEP)
+			return err
+		}},
+		{Provider: domain.ProviderHGWeather, Check: func(ctx context.Context) error {
+			_, err := weatherAPIClient.GetHGWeatherAPI(ctx, probeCity)
+			return err
+		}},
+	}
 }
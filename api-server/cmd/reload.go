@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"api-server/domain/analysis"
+	"api-server/internal/config"
+	"api-server/internal/infra/metrics"
+	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/lifecycle"
+	"api-server/pkg/logger"
+	"api-server/pkg/ratelimit"
+)
+
+// liveString is a string read by the requests in flight and replaced by reloads and secret rotations.
+type liveString struct {
+	value atomic.Pointer[string]
+}
+
+func newLiveString(value string) *liveString {
+	s := &liveString{}
+	s.Set(value)
+	return s
+}
+
+func (s *liveString) Get() string {
+	return *s.value.Load()
+}
+
+func (s *liveString) Set(value string) {
+	s.value.Store(&value)
+}
+
+// reloader re-reads the configuration and applies the settings marked reloadable. Each one is swapped
+// atomically, so the requests in flight finish with the settings they started with.
+type reloader struct {
+	mu      sync.Mutex
+	current *config.Config
+	load    func() (*config.Config, error)
+	log     *slog.Logger
+	metrics *metrics.Metrics
+
+	level         *slog.LevelVar
+	analysis      interface{ Apply(analysis.Settings) }
+	rateLimit     *ratelimit.DynamicPolicy
+	weatherAPIKey *liveString
+	adminToken    *liveString
+}
+
+// Reload applies the new configuration, or nothing at all when it is invalid.
+func (r *reloader) Reload(trigger string) (changed, restart []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.load()
+	if err != nil {
+		r.metrics.ObserveReload(false)
+		r.log.Error("Configuration reload failed: keeping the current one", "trigger", trigger, logger.Err(err))
+		return nil, nil, err
+	}
+
+	changed, restart = config.Diff(r.current, cfg)
+	r.apply(cfg)
+	r.current = cfg
+	r.metrics.ObserveReload(true)
+
+	r.log.Info("Configuration reloaded", "trigger", trigger, "changed", changed)
+	if len(restart) > 0 {
+		r.log.Warn("Configuration changes need a restart", "keys", restart)
+	}
+	return changed, restart, nil
+}
+
+func (r *reloader) apply(cfg *config.Config) {
+	httpclient.DefaultRedactor.AddSecret(cfg.Weather.APIKey)
+	httpclient.DefaultRedactor.AddSecret(cfg.Admin.Token)
+
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	r.level.Set(level)
+	r.analysis.Apply(analysisSettings(cfg))
+	r.rateLimit.Store(getRateLimitPolicy(cfg, r.log))
+	r.weatherAPIKey.Set(cfg.Weather.APIKey)
+	r.adminToken.Set(cfg.Admin.Token)
+}
+
+// runOnSignal reloads on every SIGHUP, in a worker stopped on shutdown.
+func (r *reloader) runOnSignal(manager *lifecycle.Manager) {
+	manager.Go("reload", func(ctx context.Context) error {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-hup:
+				_, _, _ = r.Reload("SIGHUP")
+			}
+		}
+	})
+}
+
+func analysisSettings(cfg *config.Config) analysis.Settings {
+	return analysis.Settings{
+		CEPProviders:   cfg.Providers.CEP,
+		WeatherEnabled: len(cfg.Providers.Weather) > 0,
+		CEPTimeout:     cfg.Upstream.CEPTimeout,
+		WeatherTimeout: cfg.Upstream.WeatherTimeout,
+		HedgeDelay:     cfg.Upstream.CEPHedgeDelay,
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"api-server/pkg/env"
+	"api-server/pkg/lifecycle"
+	"api-server/pkg/logger"
+)
+
+// secretWatch re-applies a secret when it rotates.
+type secretWatch struct {
+	secret   *env.Secret
+	onChange func(value string)
+}
+
+type secretWatches []secretWatch
+
+// envSecret returns the secret when it is set through NAME or NAME_FILE, or nil when it comes
+// from the configuration file and so can not rotate.
+func envSecret(log *slog.Logger, name string) *env.Secret {
+	secret, err := env.NewSecret(env.EnvSecrets{LookupEnv: os.LookupEnv}, name)
+	if err != nil {
+		log.Warn("Secret will not be watched", "name", name, logger.Err(err))
+		return nil
+	}
+	if secret.Get() == "" {
+		return nil
+	}
+	return secret
+}
+
+// run re-reads every secret each interval, in a worker stopped on shutdown.
+func (w secretWatches) run(manager *lifecycle.Manager, interval time.Duration, log *slog.Logger) {
+	if interval <= 0 || len(w) == 0 {
+		return
+	}
+
+	manager.Go("secrets", func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for _, sw := range w {
+			wg.Add(1)
+			go func(sw secretWatch) {
+				defer wg.Done()
+				sw.secret.Watch(ctx, interval, log, sw.onChange)
+			}(sw)
+		}
+		wg.Wait()
+		return nil
+	})
+}
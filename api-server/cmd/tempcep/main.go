@@ -0,0 +1,281 @@
+// Command tempcep looks up the city and the current temperature of CEPs from a terminal, with the
+// same clients and providers as the server, e.g.:
+//
+//	WEATHER_API_KEY=... tempcep -units C -v 01001000 20040-020
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"api-server/internal/config"
+	"api-server/internal/lookup"
+	"api-server/pkg/cep"
+	"api-server/pkg/env"
+	"api-server/pkg/temperature"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+type options struct {
+	format   string
+	temp     lookup.Format
+	verbose  bool
+	settings lookup.Settings
+}
+
+func main() {
+	opts, ceps, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tempcep:", err)
+		os.Exit(2)
+	}
+
+	// Os logs do serviço só aparecem com -v, e sempre no stderr
+	log := lookup.NewLogger(os.Stderr, opts.verbose, opts.settings)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	service := lookup.New(opts.settings, log)
+	results := make([]result, len(ceps))
+	for i, input := range ceps {
+		results[i] = lookupCEP(ctx, service, input, opts.settings.Weather)
+	}
+
+	if err := write(os.Stdout, results, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "tempcep:", err)
+		os.Exit(1)
+	}
+	if opts.verbose && opts.format != formatJSON {
+		writeTimings(os.Stderr, results)
+	}
+	for _, r := range results {
+		if r.err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+func parseFlags(args []string, output io.Writer) (options, []string, error) {
+	opts := options{
+		temp:     lookup.Format{Units: temperature.DefaultUnits, Precision: 2, Rounding: temperature.HalfUp},
+		settings: lookup.DefaultSettings(),
+	}
+
+	fs := flag.NewFlagSet("tempcep", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tempcep [flags] CEP...")
+		fmt.Fprintln(fs.Output(), "\nLooks up the city and the current temperature of each CEP. The weather API key is read from "+config.EnvWeatherAPIKey+" or "+config.EnvWeatherAPIKey+env.FileSuffix+".")
+		fmt.Fprintln(fs.Output(), "Exits with 1 when a lookup fails.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&opts.format, "format", formatTable, "output format: table, json or csv")
+	opts.temp.AddFlags(fs)
+	opts.settings.AddFlags(fs)
+	fs.BoolVar(&opts.verbose, "v", false, "print the time of each provider call and the warnings of the lookups")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, nil, err
+	}
+
+	var errs []error
+	switch opts.format {
+	case formatTable, formatJSON, formatCSV:
+	default:
+		errs = append(errs, fmt.Errorf("-format: must be table, json or csv, got %q", opts.format))
+	}
+	if !opts.settings.Weather {
+		// Sem temperatura, a saída não tem as colunas das unidades
+		opts.temp.Units = nil
+	}
+	if err := opts.settings.ReadKey(); err != nil {
+		errs = append(errs, err)
+	}
+	if fs.NArg() == 0 {
+		errs = append(errs, errors.New("at least one CEP is required"))
+	}
+
+	return opts, fs.Args(), errors.Join(errs...)
+}
+
+type result struct {
+	input   string
+	cep     cep.CEP
+	city    lookup.City
+	temp    *lookup.Temperature
+	err     error
+	elapsed time.Duration
+}
+
+func lookupCEP(ctx context.Context, service *lookup.Service, input string, weather bool) (r result) {
+	start := time.Now()
+	r.input = input
+	defer func() { r.elapsed = time.Since(start) }()
+
+	r.cep, r.err = cep.Parse(input)
+	if r.err != nil {
+		return r
+	}
+	r.city, r.err = service.City(ctx, r.cep)
+	if r.err != nil || !weather {
+		return r
+	}
+	temp, err := service.Temperature(ctx, r.city.Info)
+	r.temp, r.err = &temp, err
+	return r
+}
+
+func (r result) temperatures(opts options) []string {
+	if r.temp == nil || r.err != nil {
+		return make([]string, len(opts.temp.Units))
+	}
+	return opts.temp.Strings(r.temp.Celsius)
+}
+
+func (r result) cepString() string {
+	if r.cep == "" {
+		return ""
+	}
+	return r.cep.String()
+}
+
+func (r result) errString() string {
+	if r.err == nil {
+		return ""
+	}
+	return r.err.Error()
+}
+
+func write(w io.Writer, results []result, opts options) error {
+	switch opts.format {
+	case formatJSON:
+		return writeJSON(w, results, opts)
+	case formatCSV:
+		return writeCSV(w, results, opts)
+	}
+	return writeTable(w, results, opts)
+}
+
+func header(opts options) []string {
+	h := append([]string{"input", "cep", "city", "uf"}, opts.temp.Columns()...)
+	return append(h, "provider", "error")
+}
+
+func (r result) record(opts options) []string {
+	record := []string{r.input, r.cepString(), r.city.Name, r.city.UF}
+	record = append(record, r.temperatures(opts)...)
+	return append(record, r.city.Provider, r.errString())
+}
+
+func writeTable(w io.Writer, results []result, opts options) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header(opts), "\t")))
+	for _, r := range results {
+		fmt.Fprintln(tw, strings.Join(r.record(opts), "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, results []result, opts options) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header(opts))
+	for _, r := range results {
+		_ = cw.Write(r.record(opts))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonCall struct {
+	Provider  string  `json:"provider"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type jsonResult struct {
+	Input       string             `json:"input"`
+	CEP         string             `json:"cep,omitempty"`
+	City        string             `json:"city,omitempty"`
+	UF          string             `json:"uf,omitempty"`
+	Temperature map[string]float64 `json:"temperature,omitempty"`
+	Provider    string             `json:"provider,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	ElapsedMS   float64            `json:"elapsed_ms,omitempty"`
+	Calls       []jsonCall         `json:"calls,omitempty"`
+}
+
+func writeJSON(w io.Writer, results []result, opts options) error {
+	out := make([]jsonResult, len(results))
+	for i, r := range results {
+		out[i] = jsonResult{Input: r.input, CEP: r.cepString(), City: r.city.Name, UF: r.city.UF, Provider: r.city.Provider, Error: r.errString()}
+		if r.temp != nil && r.err == nil {
+			out[i].Temperature = make(map[string]float64, len(opts.temp.Units))
+			for j, v := range opts.temp.Values(r.temp.Celsius) {
+				out[i].Temperature[opts.temp.Columns()[j]] = v
+			}
+		}
+		if opts.verbose {
+			out[i].ElapsedMS = milliseconds(r.elapsed)
+			for _, call := range r.calls() {
+				c := jsonCall{Provider: call.Provider, LatencyMS: milliseconds(call.Latency)}
+				if call.Err != nil {
+					c.Error = call.Err.Error()
+				}
+				out[i].Calls = append(out[i].Calls, c)
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (r result) calls() []lookup.Call {
+	calls := r.city.Calls
+	if r.temp != nil {
+		calls = append(calls, r.temp.Calls...)
+	}
+	return calls
+}
+
+// writeTimings lists the provider calls of each lookup, e.g. to tell a slow provider from a failing one.
+func writeTimings(w io.Writer, results []result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nINPUT\tPROVIDER\tLATENCY\tRESULT")
+	for _, r := range results {
+		for _, call := range r.calls() {
+			status := "ok"
+			if call.Err != nil {
+				status = call.Err.Error()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.input, call.Provider, call.Latency.Round(time.Millisecond), status)
+		}
+		fmt.Fprintf(tw, "%s\ttotal\t%s\t\n", r.input, r.elapsed.Round(time.Millisecond))
+	}
+	_ = tw.Flush()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
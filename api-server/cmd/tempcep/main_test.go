@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"api-server/domain"
+	"api-server/internal/lookup"
+	"api-server/pkg/cep"
+	"api-server/pkg/temperature"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlags(t *testing.T) {
+	t.Setenv("WEATHER_API_KEY", "key")
+
+	t.Run("should parse the flags", func(t *testing.T) {
+		opts, ceps, err := parseFlags([]string{"-format", "csv", "-units", "C,K", "-providers", "viacep", "-retries", "0", "01001000", "20040-020"}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"01001000", "20040-020"}, ceps)
+		assert.Equal(t, formatCSV, opts.format)
+		assert.Equal(t, []temperature.Unit{temperature.Celsius, temperature.Kelvin}, opts.temp.Units)
+		assert.Equal(t, []string{domain.ProviderViaCEP}, opts.settings.CEPProviders)
+		assert.Equal(t, 0, opts.settings.MaxRetries)
+		assert.Equal(t, "key", opts.settings.WeatherAPIKey)
+	})
+
+	t.Run("should report every invalid flag", func(t *testing.T) {
+		_, _, err := parseFlags([]string{"-format", "xml"}, io.Discard)
+		require.Error(t, err)
+		for _, flag := range []string{"-format", "at least one CEP"} {
+			assert.Contains(t, err.Error(), flag)
+		}
+
+		_, _, err = parseFlags([]string{"-providers", "Correios", "01001000"}, io.Discard)
+		assert.ErrorContains(t, err, "unknown provider")
+		_, _, err = parseFlags([]string{"-units", "X", "01001000"}, io.Discard)
+		assert.ErrorContains(t, err, "-units")
+	})
+
+	t.Run("should require the weather key only for temperatures", func(t *testing.T) {
+		t.Setenv("WEATHER_API_KEY", "")
+		_, _, err := parseFlags([]string{"01001000"}, io.Discard)
+		assert.ErrorContains(t, err, "WEATHER_API_KEY")
+
+		opts, _, err := parseFlags([]string{"-temperature=false", "01001000"}, io.Discard)
+		assert.NoError(t, err)
+		assert.Empty(t, opts.temp.Units, "no temperature columns")
+	})
+}
+
+func TestWrite(t *testing.T) {
+	c, _ := cep.Parse("01001000")
+	results := []result{
+		{
+			input: "01001000",
+			cep:   c,
+			city: lookup.City{Info: "São Paulo,SP", Name: "São Paulo", UF: "SP", Provider: domain.ProviderBrasilAPI,
+				Calls: []lookup.Call{{Provider: domain.ProviderBrasilAPI, Latency: 120 * time.Millisecond}}},
+			temp: &lookup.Temperature{Celsius: 25, Provider: domain.ProviderHGWeather,
+				Calls: []lookup.Call{{Provider: domain.ProviderHGWeather, Latency: 80 * time.Millisecond}}},
+		},
+		{input: "123", err: errors.New("invalid CEP")},
+	}
+	opts := options{temp: lookup.Format{Units: []temperature.Unit{temperature.Celsius, temperature.Fahrenheit}, Precision: 1, Rounding: temperature.HalfUp}}
+
+	t.Run("csv", func(t *testing.T) {
+		opts := opts
+		opts.format = formatCSV
+		var buf bytes.Buffer
+		require.NoError(t, write(&buf, results, opts))
+		assert.Equal(t, "input,cep,city,uf,temp_C,temp_F,provider,error\n"+
+			"01001000,01001-000,São Paulo,SP,25,77,BrasilAPI,\n"+
+			"123,,,,,,,invalid CEP\n", buf.String())
+	})
+
+	t.Run("table", func(t *testing.T) {
+		opts := opts
+		opts.format = formatTable
+		var buf bytes.Buffer
+		require.NoError(t, write(&buf, results, opts))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "INPUT"))
+		assert.Contains(t, lines[1], "São Paulo")
+	})
+
+	t.Run("json with the provider timings", func(t *testing.T) {
+		opts := opts
+		opts.format = formatJSON
+		opts.verbose = true
+		var buf bytes.Buffer
+		require.NoError(t, write(&buf, results, opts))
+
+		var out []jsonResult
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		require.Len(t, out, 2)
+		assert.Equal(t, map[string]float64{"temp_C": 25, "temp_F": 77}, out[0].Temperature)
+		require.Len(t, out[0].Calls, 2)
+		assert.Equal(t, 120.0, out[0].Calls[0].LatencyMS)
+		assert.Equal(t, domain.ProviderHGWeather, out[0].Calls[1].Provider)
+		assert.Equal(t, "invalid CEP", out[1].Error)
+		assert.Nil(t, out[1].Temperature)
+	})
+}
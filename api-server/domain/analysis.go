@@ -1,8 +1,47 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	ProviderBrasilAPI = "BrasilAPI"
+	ProviderViaCEP    = "ViaCEP"
+	ProviderHGWeather = "HGWeather"
+)
+
+var (
+	ErrCEPNotFound         = errors.New("cep not found")
+	ErrProviderUnavailable = errors.New("provider unavailable")
+	ErrNoProviderAvailable = errors.New("no provider available")
+	ErrInvalidAPIKey       = errors.New("invalid api key")
+)
 
 type AnalysisService interface {
 	GetCity(c context.Context, cep string) (string, error)
 	GetCelsiusTemperature(c context.Context, city string) (int, error)
+	SearchAddresses(c context.Context, uf, city, street string) ([]Address, error)
+}
+
+// ProviderGate reports whether an upstream provider may be called right now
+// (e.g. its circuit breaker is not open).
+type ProviderGate interface {
+	Allow(provider string) bool
+}
+
+// ProviderObserver is notified of every call made to an upstream provider.
+type ProviderObserver interface {
+	ObserveProviderCall(ctx context.Context, provider string, latency time.Duration, err error)
+}
+
+// RetryObserver is notified of every retry of a call to an upstream provider.
+type RetryObserver interface {
+	ObserveRetry(provider string)
+}
+
+// RaceObserver is notified of the provider that answered first a CEP race.
+type RaceObserver interface {
+	ObserveRaceWinner(provider string)
 }
@@ -3,63 +3,194 @@ package analysis
 import (
 	"api-server/domain"
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
 	"time"
+
+	"api-server/pkg/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("api-server/domain/analysis")
+
 type analysisService struct {
 	buscaCEPAPIClient domain.BuscaCEPAPIClient
 	weatherAPIClient  domain.WeatherAPIClient
-	log               *log.Logger
+	log               *slog.Logger
+	gate              domain.ProviderGate
+	raceObserver      domain.RaceObserver
+	settings          atomic.Pointer[Settings]
+}
+
+// Settings are the lookup settings that can be changed while requests are served.
+type Settings struct {
+	// CEPProviders are the enabled CEP providers, in order of preference.
+	CEPProviders []string
+	// WeatherEnabled disables the weather lookup when false.
+	WeatherEnabled bool
+	CEPTimeout     time.Duration
+	WeatherTimeout time.Duration
+	// HedgeDelay starts the CEP providers one after the other, HedgeDelay apart; 0 races them all at once.
+	HedgeDelay time.Duration
+}
+
+// DefaultSettings races every CEP provider with a 1s timeout.
+func DefaultSettings() Settings {
+	return Settings{
+		CEPProviders:   []string{domain.ProviderBrasilAPI, domain.ProviderViaCEP},
+		WeatherEnabled: true,
+		CEPTimeout:     1 * time.Second,
+		WeatherTimeout: 1 * time.Second,
+	}
+}
+
+type Option func(*analysisService)
+
+// WithProviderGate skips providers the gate does not allow (e.g. open circuit breakers).
+func WithProviderGate(gate domain.ProviderGate) Option {
+	return func(s *analysisService) {
+		s.gate = gate
+	}
+}
+
+// WithRaceObserver reports which provider wins each CEP race.
+func WithRaceObserver(observer domain.RaceObserver) Option {
+	return func(s *analysisService) {
+		s.raceObserver = observer
+	}
+}
+
+func WithSettings(settings Settings) Option {
+	return func(s *analysisService) {
+		s.Apply(settings)
+	}
 }
 
 func NewAnalysisService(buscaCEPAPIClient domain.BuscaCEPAPIClient, weatherAPIClient domain.WeatherAPIClient,
-	log *log.Logger) *analysisService {
+	log *slog.Logger, opts ...Option) *analysisService {
 
-	return &analysisService{
+	s := &analysisService{
 		buscaCEPAPIClient: buscaCEPAPIClient,
 		weatherAPIClient:  weatherAPIClient,
 		log:               log,
 	}
+	s.Apply(DefaultSettings())
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Apply replaces the settings. Lookups already running keep the settings they started with.
+func (s *analysisService) Apply(settings Settings) {
+	settings.CEPProviders = append([]string(nil), settings.CEPProviders...)
+	s.settings.Store(&settings)
+}
+
+// Settings returns the settings in use.
+func (s *analysisService) Settings() Settings {
+	return *s.settings.Load()
 }
 
-func (s *analysisService) GetCity(c context.Context, cep string) (string, error) {
+type cepProvider struct {
+	name   string
+	lookup func(ctx context.Context, cep string) (string, error)
+}
+
+func (s *analysisService) cepProvider(name string) (cepProvider, bool) {
+	switch name {
+	case domain.ProviderBrasilAPI:
+		return cepProvider{name: name, lookup: s.buscaCEPAPIClient.GetBrasilAPICEP}, true
+	case domain.ProviderViaCEP:
+		return cepProvider{name: name, lookup: s.buscaCEPAPIClient.GetViaAPICEP}, true
+	}
+	return cepProvider{}, false
+}
+
+func (s *analysisService) allow(provider string) bool {
+	return s.gate == nil || s.gate.Allow(provider)
+}
+
+func (s *analysisService) GetCity(c context.Context, cep string) (city string, err error) {
 	type result struct {
-		Source string
-		City   string
-		Err    error
+		Source  string
+		City    string
+		Err     error
+		Elapsed time.Duration
 	}
 
-	// Timeout de 1 segundo para chamada das APIs
-	apiCtx, apiCancel := context.WithTimeout(c, 1*time.Second)
-	defer apiCancel()
+	c, span := tracer.Start(c, "GetCity", trace.WithAttributes(attribute.String("cep", cep)))
+	defer func() { endSpan(span, err) }()
 
-	resultCh := make(chan result, 2)
+	log := logger.FromContext(c, s.log).With(logger.KeyCEP, cep)
+	settings := s.Settings()
 
-	// Chamada BrasilAPI
-	go func() {
-		city, err := s.buscaCEPAPIClient.GetBrasilAPICEP(apiCtx, cep)
-		resultCh <- result{Source: "BrasilAPI", City: city, Err: err}
-	}()
+	providers := make([]cepProvider, 0, len(settings.CEPProviders))
+	for _, name := range settings.CEPProviders {
+		p, ok := s.cepProvider(name)
+		if !ok {
+			continue
+		}
+		if !s.allow(p.name) {
+			log.WarnContext(c, "CEP provider skipped: circuit breaker open", logger.KeyProvider, p.name)
+			span.AddEvent("provider skipped", trace.WithAttributes(attribute.String("provider", p.name)))
+			continue
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return "", domain.ErrNoProviderAvailable
+	}
 
-	// Chamada ViaCEP
-	go func() {
-		city, err := s.buscaCEPAPIClient.GetViaAPICEP(apiCtx, cep)
-		resultCh <- result{Source: "ViaCEP", City: city, Err: err}
-	}()
+	// Timeout para chamada das APIs (1 segundo por padrão)
+	apiCtx, apiCancel := context.WithTimeout(c, settings.CEPTimeout)
+	defer apiCancel()
+
+	resultCh := make(chan result, len(providers))
+
+	// Corrida entre as APIs de CEP disponíveis, escalonadas pela ordem de preferência quando há hedge
+	for i, p := range providers {
+		go func(p cepProvider, delay time.Duration) {
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-apiCtx.Done():
+					resultCh <- result{Source: p.name, Err: apiCtx.Err()}
+					return
+				}
+			}
+			ctx, span := tracer.Start(apiCtx, "lookup "+p.name, trace.WithAttributes(attribute.String("provider", p.name)))
+			start := time.Now()
+			city, err := p.lookup(ctx, cep)
+			endSpan(span, err)
+			resultCh <- result{Source: p.name, City: city, Err: err, Elapsed: time.Since(start)}
+		}(p, time.Duration(i)*settings.HedgeDelay)
+	}
 
 	var lastErr error
-	for i := 0; i < 2; i++ {
+	for i := 0; i < len(providers); i++ {
 		select {
 		case res := <-resultCh:
 			if res.Err == nil {
-				s.log.Printf("Resposta recebida da API %s: %s", res.Source, res.City)
+				log.InfoContext(c, "CEP resolved", logger.KeyProvider, res.Source, logger.KeyCity, res.City, logger.Duration(res.Elapsed))
+				span.SetAttributes(attribute.String("cep.race.winner", res.Source))
+				if s.raceObserver != nil {
+					s.raceObserver.ObserveRaceWinner(res.Source)
+				}
 				return res.City, nil
 			}
-			s.log.Printf("Erro ao buscar CEP na API %s: %v", res.Source, res.Err)
+			log.WarnContext(c, "CEP lookup failed", logger.KeyProvider, res.Source, logger.Duration(res.Elapsed), logger.Err(res.Err))
 			lastErr = res.Err
 		case <-apiCtx.Done():
-			s.log.Printf("Timeout ao buscar CEP nas APIs")
+			log.WarnContext(c, "CEP lookup timed out")
 			return "", apiCtx.Err()
 		}
 	}
@@ -67,17 +198,33 @@ func (s *analysisService) GetCity(c context.Context, cep string) (string, error)
 	return "", lastErr
 }
 
-func (s *analysisService) GetCelsiusTemperature(c context.Context, city string) (int, error) {
+func (s *analysisService) GetCelsiusTemperature(c context.Context, city string) (temp int, err error) {
 	type result struct {
 		Temp int
 		Err  error
 	}
 
-	// Timeout de 1 segundo para chamada das APIs
-	apiCtx, apiCancel := context.WithTimeout(c, 1*time.Second)
+	c, span := tracer.Start(c, "GetCelsiusTemperature", trace.WithAttributes(attribute.String("city", city)))
+	defer func() { endSpan(span, err) }()
+
+	log := logger.FromContext(c, s.log).With(logger.KeyCity, city, logger.KeyProvider, domain.ProviderHGWeather)
+	settings := s.Settings()
+
+	if !settings.WeatherEnabled {
+		log.WarnContext(c, "Weather provider skipped: disabled")
+		return 0, fmt.Errorf("%s: %w", domain.ProviderHGWeather, domain.ErrProviderUnavailable)
+	}
+	if !s.allow(domain.ProviderHGWeather) {
+		log.WarnContext(c, "Weather provider skipped: circuit breaker open")
+		return 0, fmt.Errorf("%s: %w", domain.ProviderHGWeather, domain.ErrProviderUnavailable)
+	}
+
+	// Timeout para chamada das APIs (1 segundo por padrão)
+	apiCtx, apiCancel := context.WithTimeout(c, settings.WeatherTimeout)
 	defer apiCancel()
 
 	resultCh := make(chan result, 1)
+	start := time.Now()
 
 	// Chamada HG Weather API
 	go func() {
@@ -88,14 +235,53 @@ func (s *analysisService) GetCelsiusTemperature(c context.Context, city string)
 	select {
 	case res := <-resultCh:
 		if res.Err != nil {
-			s.log.Printf("Error Getting Temperature on the API for the City %s: %v", city, res.Err)
+			log.WarnContext(c, "Temperature lookup failed", logger.Duration(time.Since(start)), logger.Err(res.Err))
 			return 0, res.Err
 		}
-		s.log.Printf("Response from the Temperature API: %v", res.Temp)
+		log.InfoContext(c, "Temperature found", "temp_c", res.Temp, logger.Duration(time.Since(start)))
 		return res.Temp, nil
 	case <-apiCtx.Done():
-		s.log.Printf("Timeout in API Searching for Temperature")
+		log.WarnContext(c, "Temperature lookup timed out")
 		return 0, apiCtx.Err()
 	}
 
 }
+
+// SearchAddresses finds the CEPs of a street through ViaCEP, the only provider with an address search.
+func (s *analysisService) SearchAddresses(c context.Context, uf, city, street string) (addresses []domain.Address, err error) {
+	c, span := tracer.Start(c, "SearchAddresses", trace.WithAttributes(
+		attribute.String("uf", uf), attribute.String("city", city), attribute.String("street", street)))
+	defer func() { endSpan(span, err) }()
+
+	log := logger.FromContext(c, s.log).With("uf", uf, logger.KeyCity, city, "street", street, logger.KeyProvider, domain.ProviderViaCEP)
+	settings := s.Settings()
+
+	if !slices.Contains(settings.CEPProviders, domain.ProviderViaCEP) {
+		log.WarnContext(c, "Address search skipped: provider disabled")
+		return nil, domain.ErrNoProviderAvailable
+	}
+	if !s.allow(domain.ProviderViaCEP) {
+		log.WarnContext(c, "Address search skipped: circuit breaker open")
+		return nil, domain.ErrNoProviderAvailable
+	}
+
+	apiCtx, apiCancel := context.WithTimeout(c, settings.CEPTimeout)
+	defer apiCancel()
+
+	start := time.Now()
+	addresses, err = s.buscaCEPAPIClient.SearchViaAPICEP(apiCtx, uf, city, street)
+	if err != nil {
+		log.WarnContext(c, "Address search failed", logger.Duration(time.Since(start)), logger.Err(err))
+		return nil, err
+	}
+	log.InfoContext(c, "Addresses found", "count", len(addresses), logger.Duration(time.Since(start)))
+	return addresses, nil
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
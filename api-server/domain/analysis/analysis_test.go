@@ -3,17 +3,17 @@ package analysis
 import (
 	"context"
 	"errors"
-	"log"
-	"os"
+	"log/slog"
 	"testing"
 	"time"
 
+	"api-server/domain"
 	"api-server/domain/mocks"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestAnalysisService_GetCity(t *testing.T) {
-	logger := log.New(os.Stdout, "test - ", log.LstdFlags)
+	logger := slog.Default()
 
 	t.Run("should return city from BrasilAPI first", func(t *testing.T) {
 		mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
@@ -87,7 +87,7 @@ func TestAnalysisService_GetCity(t *testing.T) {
 }
 
 func TestAnalysisService_GetCelsiusTemperature(t *testing.T) {
-	logger := log.New(os.Stdout, "test - ", log.LstdFlags)
+	logger := slog.Default()
 
 	t.Run("should return temperature successfully", func(t *testing.T) {
 		mockWeatherClient := &mocks.MockWeatherAPIClient{
@@ -131,3 +131,164 @@ func TestAnalysisService_GetCelsiusTemperature(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+type gate map[string]bool
+
+func (g gate) Allow(provider string) bool {
+	allowed, ok := g[provider]
+	return !ok || allowed
+}
+
+func TestAnalysisService_ProviderGate(t *testing.T) {
+	logger := slog.Default()
+
+	t.Run("should skip CEP providers whose breaker is open", func(t *testing.T) {
+		mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+			GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				t.Error("BrasilAPI should have been skipped")
+				return "City From BrasilAPI", nil
+			},
+			GetViaAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				return "City From ViaCEP", nil
+			},
+		}
+		service := NewAnalysisService(mockBuscaCEPClient, nil, logger,
+			WithProviderGate(gate{domain.ProviderBrasilAPI: false}))
+
+		city, err := service.GetCity(context.Background(), "12345678")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "City From ViaCEP", city)
+	})
+
+	t.Run("should fail fast when every CEP provider is open", func(t *testing.T) {
+		service := NewAnalysisService(&mocks.MockBuscaCEPAPIClient{}, nil, logger,
+			WithProviderGate(gate{domain.ProviderBrasilAPI: false, domain.ProviderViaCEP: false}))
+
+		_, err := service.GetCity(context.Background(), "12345678")
+
+		assert.ErrorIs(t, err, domain.ErrNoProviderAvailable)
+	})
+
+	t.Run("should fail fast when the weather provider is open", func(t *testing.T) {
+		service := NewAnalysisService(nil, &mocks.MockWeatherAPIClient{}, logger,
+			WithProviderGate(gate{domain.ProviderHGWeather: false}))
+
+		_, err := service.GetCelsiusTemperature(context.Background(), "São Paulo")
+
+		assert.ErrorIs(t, err, domain.ErrProviderUnavailable)
+	})
+}
+
+type raceWinners []string
+
+func (r *raceWinners) ObserveRaceWinner(provider string) {
+	*r = append(*r, provider)
+}
+
+func TestAnalysisService_RaceObserver(t *testing.T) {
+	logger := slog.Default()
+
+	mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+		GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+			return "", errors.New("timeout")
+		},
+		GetViaAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+			return "City From ViaCEP", nil
+		},
+	}
+	winners := &raceWinners{}
+	service := NewAnalysisService(mockBuscaCEPClient, nil, logger, WithRaceObserver(winners))
+
+	_, err := service.GetCity(context.Background(), "12345678")
+
+	assert.NoError(t, err)
+	assert.Equal(t, raceWinners{domain.ProviderViaCEP}, *winners)
+}
+
+func TestAnalysisService_Settings(t *testing.T) {
+	logger := slog.Default()
+
+	t.Run("should only race the enabled providers", func(t *testing.T) {
+		mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+			GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				t.Error("BrasilAPI should be disabled")
+				return "City From BrasilAPI", nil
+			},
+			GetViaAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				return "City From ViaCEP", nil
+			},
+		}
+		settings := DefaultSettings()
+		settings.CEPProviders = []string{domain.ProviderViaCEP}
+		service := NewAnalysisService(mockBuscaCEPClient, nil, logger, WithSettings(settings))
+
+		city, err := service.GetCity(context.Background(), "12345678")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "City From ViaCEP", city)
+	})
+
+	t.Run("should not start the next provider before the hedge delay", func(t *testing.T) {
+		mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+			GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				t.Error("BrasilAPI is second and the first answered within the hedge delay")
+				return "City From BrasilAPI", nil
+			},
+			GetViaAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+				return "City From ViaCEP", nil
+			},
+		}
+		service := NewAnalysisService(mockBuscaCEPClient, nil, logger)
+		settings := service.Settings()
+		settings.CEPProviders = []string{domain.ProviderViaCEP, domain.ProviderBrasilAPI}
+		settings.HedgeDelay = 200 * time.Millisecond
+		service.Apply(settings)
+
+		city, err := service.GetCity(context.Background(), "12345678")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "City From ViaCEP", city)
+		time.Sleep(300 * time.Millisecond)
+	})
+
+	t.Run("should fail fast when the weather provider is disabled", func(t *testing.T) {
+		settings := DefaultSettings()
+		settings.WeatherEnabled = false
+		service := NewAnalysisService(nil, &mocks.MockWeatherAPIClient{}, logger, WithSettings(settings))
+
+		_, err := service.GetCelsiusTemperature(context.Background(), "São Paulo")
+
+		assert.ErrorIs(t, err, domain.ErrProviderUnavailable)
+	})
+}
+
+func TestAnalysisService_SearchAddresses(t *testing.T) {
+	logger := slog.Default()
+	mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+		SearchViaAPICEPFunc: func(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			return []domain.Address{{CEP: "01001-000", Street: street, City: city, UF: uf}}, nil
+		},
+	}
+
+	t.Run("should search through ViaCEP", func(t *testing.T) {
+		service := NewAnalysisService(mockBuscaCEPClient, nil, logger)
+
+		addresses, err := service.SearchAddresses(context.Background(), "SP", "São Paulo", "Praça da Sé")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Address{{CEP: "01001-000", Street: "Praça da Sé", City: "São Paulo", UF: "SP"}}, addresses)
+	})
+
+	t.Run("should fail when ViaCEP is disabled", func(t *testing.T) {
+		settings := DefaultSettings()
+		settings.CEPProviders = []string{domain.ProviderBrasilAPI}
+		service := NewAnalysisService(mockBuscaCEPClient, nil, logger, WithSettings(settings))
+
+		_, err := service.SearchAddresses(context.Background(), "SP", "São Paulo", "Praça da Sé")
+
+		assert.ErrorIs(t, err, domain.ErrNoProviderAvailable)
+	})
+}
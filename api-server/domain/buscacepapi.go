@@ -28,7 +28,19 @@ type ViaCEPAPIResponse struct {
 	Erro        string `json:"erro,omitempty"`
 }
 
+// Address is a street found by an address search, with its CEP.
+type Address struct {
+	CEP          string
+	Street       string
+	Complement   string
+	Neighborhood string
+	City         string
+	UF           string
+}
+
 type BuscaCEPAPIClient interface {
 	GetBrasilAPICEP(ctx context.Context, cep string) (string, error)
 	GetViaAPICEP(ctx context.Context, cep string) (string, error)
+	// SearchViaAPICEP finds the CEPs of the streets of a city matching street.
+	SearchViaAPICEP(ctx context.Context, uf, city, street string) ([]Address, error)
 }
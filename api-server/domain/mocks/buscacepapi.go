@@ -3,11 +3,14 @@ package mocks
 import (
 	"context"
 	"time"
+
+	"api-server/domain"
 )
 
 type MockBuscaCEPAPIClient struct {
 	GetBrasilAPICEPFunc func(ctx context.Context, cep string) (string, error)
 	GetViaAPICEPFunc    func(ctx context.Context, cep string) (string, error)
+	SearchViaAPICEPFunc func(ctx context.Context, uf, city, street string) ([]domain.Address, error)
 }
 
 func (m *MockBuscaCEPAPIClient) GetBrasilAPICEP(ctx context.Context, cep string) (string, error) {
@@ -25,3 +28,7 @@ func (m *MockBuscaCEPAPIClient) GetViaAPICEP(ctx context.Context, cep string) (s
 	}
 	return m.GetViaAPICEPFunc(ctx, cep)
 }
+
+func (m *MockBuscaCEPAPIClient) SearchViaAPICEP(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+	return m.SearchViaAPICEPFunc(ctx, uf, city, street)
+}
@@ -0,0 +1,387 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/domain"
+	"api-server/pkg/apikey"
+	"api-server/pkg/circuitbreaker"
+	"api-server/pkg/logger"
+	"api-server/pkg/ratelimit"
+	"api-server/pkg/temperature"
+)
+
+// EnvLocal runs the service for local development: text logs and the gin debug logger.
+const EnvLocal = "local"
+
+// Config is the effective configuration of the service.
+type Config struct {
+	Env string
+
+	HTTP struct {
+		Port      string
+		AccessLog bool
+	}
+	GRPC struct {
+		Port string
+	}
+	Admin struct {
+		Token string
+	}
+	Weather struct {
+		APIKey string
+	}
+	Providers struct {
+		CEP     []string
+		Weather []string
+	}
+	Upstream struct {
+		Timeout          time.Duration
+		CEPTimeout       time.Duration
+		CEPHedgeDelay    time.Duration
+		WeatherTimeout   time.Duration
+		MaxRetries       int
+		MaxRetryInterval time.Duration
+	}
+	Breaker struct {
+		FailureRatio float64
+		MinRequests  int
+		Interval     time.Duration
+		CoolDown     time.Duration
+	}
+	RateLimit struct {
+		RPS            float64
+		Burst          int
+		Tiers          []string
+		APIKeys        []string
+		TrustedProxies int
+	}
+	APIKeys struct {
+		Keys []string
+		File string
+	}
+	Temperature struct {
+		Precision int
+		Rounding  string
+	}
+	Batch struct {
+		MaxSize int
+		Workers int
+	}
+	Jobs struct {
+		Store       string
+		Dir         string
+		MaxSize     int
+		Workers     int
+		Concurrency int
+		QueueSize   int
+		Rate        float64
+		Retention   time.Duration
+	}
+	Stream struct {
+		PollInterval time.Duration
+		Heartbeat    time.Duration
+	}
+	Health struct {
+		ProbeInterval time.Duration
+		ProbeTimeout  time.Duration
+	}
+	Log struct {
+		Format string
+		Level  string
+	}
+	Shutdown struct {
+		PreStopDelay time.Duration
+		Timeout      time.Duration
+	}
+	Secrets struct {
+		RefreshInterval time.Duration
+	}
+
+	// File is the configuration file loaded, if any.
+	File string
+	// PrintConfig asks to dump the effective configuration and exit.
+	PrintConfig bool
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	c := &Config{}
+	c.HTTP.Port = "8080"
+	c.GRPC.Port = "9090"
+
+	c.Providers.CEP = []string{domain.ProviderBrasilAPI, domain.ProviderViaCEP}
+	c.Providers.Weather = []string{domain.ProviderHGWeather}
+
+	c.Upstream.Timeout = 60 * time.Second
+	c.Upstream.CEPTimeout = 1 * time.Second
+	c.Upstream.WeatherTimeout = 1 * time.Second
+	c.Upstream.MaxRetries = 5
+	c.Upstream.MaxRetryInterval = 1 * time.Second
+
+	breaker := circuitbreaker.DefaultSettings("")
+	c.Breaker.FailureRatio = breaker.FailureRatio
+	c.Breaker.MinRequests = int(breaker.MinRequests)
+	c.Breaker.Interval = breaker.Interval
+	c.Breaker.CoolDown = breaker.CoolDown
+
+	c.RateLimit.RPS = 10
+	c.RateLimit.Burst = 20
+	c.RateLimit.TrustedProxies = 1
+
+	c.Temperature.Precision = 2
+	c.Temperature.Rounding = temperature.HalfUp.String()
+
+	c.Batch.MaxSize = 500
+	c.Batch.Workers = 8
+
+	c.Jobs.Store = JobStoreMemory
+	c.Jobs.Dir = "jobs"
+	c.Jobs.MaxSize = 50000
+	c.Jobs.Workers = 4
+	c.Jobs.Concurrency = 1
+	c.Jobs.QueueSize = 100
+	c.Jobs.Rate = 20
+	c.Jobs.Retention = 24 * time.Hour
+
+	c.Stream.PollInterval = 60 * time.Second
+	c.Stream.Heartbeat = 15 * time.Second
+
+	c.Health.ProbeTimeout = 5 * time.Second
+
+	c.Log.Level = "info"
+
+	// Cloud Run dá 10s entre o SIGTERM e o SIGKILL
+	c.Shutdown.Timeout = 8 * time.Second
+
+	c.Secrets.RefreshInterval = 30 * time.Second
+	return c
+}
+
+// field binds a setting to its file key, environment variables (the first one set wins) and flag.
+// Secrets are masked when printed; with file set they can also be mounted as a file named by NAME_FILE.
+// reload marks the settings applied by a reload, the others need a restart.
+type field struct {
+	key    string
+	env    []string
+	usage  string
+	secret bool
+	file   bool
+	reload bool
+	value  any
+}
+
+// Job stores.
+const (
+	JobStoreMemory = "memory"
+	JobStoreFile   = "file"
+)
+
+// Variables of the secrets watched for rotation.
+const (
+	EnvAdminToken       = "ADMIN_TOKEN"
+	EnvWeatherAPIKey    = "WEATHER_API_KEY"
+	EnvRateLimitAPIKeys = "RATE_LIMIT_API_KEYS"
+	EnvAPIKeysFile      = "API_KEYS_FILE"
+)
+
+func (c *Config) fields() []field {
+	return []field{
+		{key: "env", env: []string{"ENV"}, usage: `"local" for text logs and the gin debug logger`, value: &c.Env},
+
+		{key: "http.port", env: []string{"PORT", "APP_PORT"}, usage: "HTTP port (PORT is set by Cloud Run)", value: &c.HTTP.Port},
+		{key: "http.access_log", env: []string{"ACCESS_LOG"}, usage: "log every request (default on Cloud Run)", value: &c.HTTP.AccessLog},
+		{key: "grpc.port", env: []string{"GRPC_PORT"}, usage: "gRPC port", value: &c.GRPC.Port},
+
+		{key: "admin.token", env: []string{EnvAdminToken}, usage: "bearer token of the admin endpoints that change state", secret: true, file: true, reload: true, value: &c.Admin.Token},
+		{key: "weather.api_key", env: []string{EnvWeatherAPIKey}, usage: "HG Weather API key", secret: true, file: true, reload: true, value: &c.Weather.APIKey},
+
+		{key: "providers.cep", env: []string{"CEP_PROVIDERS"}, usage: "enabled CEP providers, in order of preference", reload: true, value: &c.Providers.CEP},
+		{key: "providers.weather", env: []string{"WEATHER_PROVIDERS"}, usage: "enabled weather providers (empty disables the temperature lookup)", reload: true, value: &c.Providers.Weather},
+
+		{key: "upstream.timeout", env: []string{"UPSTREAM_TIMEOUT"}, usage: "timeout of each upstream HTTP call", value: &c.Upstream.Timeout},
+		{key: "upstream.cep_timeout", env: []string{"CEP_LOOKUP_TIMEOUT"}, usage: "timeout of the CEP provider race", reload: true, value: &c.Upstream.CEPTimeout},
+		{key: "upstream.cep_hedge_delay", env: []string{"CEP_HEDGE_DELAY"}, usage: "delay between the CEP providers, in order (0 races them all at once)", reload: true, value: &c.Upstream.CEPHedgeDelay},
+		{key: "upstream.weather_timeout", env: []string{"WEATHER_LOOKUP_TIMEOUT"}, usage: "timeout of the weather lookup", reload: true, value: &c.Upstream.WeatherTimeout},
+		{key: "upstream.max_retries", env: []string{"UPSTREAM_MAX_RETRIES"}, usage: "retries of a failed upstream call", value: &c.Upstream.MaxRetries},
+		{key: "upstream.max_retry_interval", env: []string{"UPSTREAM_MAX_RETRY_INTERVAL"}, usage: "longest backoff between retries", value: &c.Upstream.MaxRetryInterval},
+
+		{key: "breaker.failure_ratio", env: []string{"BREAKER_FAILURE_RATIO"}, usage: "failure ratio opening a circuit breaker", value: &c.Breaker.FailureRatio},
+		{key: "breaker.min_requests", env: []string{"BREAKER_MIN_REQUESTS"}, usage: "requests in the interval before a breaker can open", value: &c.Breaker.MinRequests},
+		{key: "breaker.interval", env: []string{"BREAKER_INTERVAL"}, usage: "window the failure ratio is measured over", value: &c.Breaker.Interval},
+		{key: "breaker.cool_down", env: []string{"BREAKER_COOL_DOWN"}, usage: "time an open breaker waits before probing", value: &c.Breaker.CoolDown},
+
+		{key: "rate_limit.rps", env: []string{"RATE_LIMIT_RPS"}, usage: "default requests per second per client (0 disables)", reload: true, value: &c.RateLimit.RPS},
+		{key: "rate_limit.burst", env: []string{"RATE_LIMIT_BURST"}, usage: "default burst per client", reload: true, value: &c.RateLimit.Burst},
+		{key: "rate_limit.tiers", env: []string{"RATE_LIMIT_TIERS"}, usage: "tiers as name=rate:burst", reload: true, value: &c.RateLimit.Tiers},
+		{key: "rate_limit.api_keys", env: []string{EnvRateLimitAPIKeys}, usage: "API key tiers as key=tier", secret: true, file: true, reload: true, value: &c.RateLimit.APIKeys},
+		{key: "rate_limit.trusted_proxies", env: []string{"RATE_LIMIT_TRUSTED_PROXIES"}, usage: "proxies in front of the service (X-Forwarded-For hops)", value: &c.RateLimit.TrustedProxies},
+
+		{key: "api_keys.keys", env: []string{"API_KEYS"}, usage: "partner API keys as name:secret[:quota[:tier]]", secret: true, value: &c.APIKeys.Keys},
+		{key: "api_keys.file", env: []string{EnvAPIKeysFile}, usage: "JSON file with the partner API keys", value: &c.APIKeys.File},
+
+		{key: "temperature.precision", env: []string{"TEMPERATURE_PRECISION"}, usage: "default decimal places", value: &c.Temperature.Precision},
+		{key: "temperature.rounding", env: []string{"TEMPERATURE_ROUNDING"}, usage: "default rounding mode", value: &c.Temperature.Rounding},
+
+		{key: "batch.max_size", env: []string{"BATCH_MAX_SIZE"}, usage: "maximum CEPs per batch request", value: &c.Batch.MaxSize},
+		{key: "batch.workers", env: []string{"BATCH_WORKERS"}, usage: "concurrent lookups of a batch request", value: &c.Batch.Workers},
+
+		{key: "jobs.store", env: []string{"JOBS_STORE"}, usage: "where jobs are kept: memory or file", value: &c.Jobs.Store},
+		{key: "jobs.dir", env: []string{"JOBS_DIR"}, usage: "directory of the file job store", value: &c.Jobs.Dir},
+		{key: "jobs.max_size", env: []string{"JOBS_MAX_SIZE"}, usage: "maximum CEPs per job", value: &c.Jobs.MaxSize},
+		{key: "jobs.workers", env: []string{"JOBS_WORKERS"}, usage: "concurrent lookups of a job", value: &c.Jobs.Workers},
+		{key: "jobs.concurrency", env: []string{"JOBS_CONCURRENCY"}, usage: "jobs running at the same time", value: &c.Jobs.Concurrency},
+		{key: "jobs.queue_size", env: []string{"JOBS_QUEUE_SIZE"}, usage: "jobs waiting to run before new ones are refused", value: &c.Jobs.QueueSize},
+		{key: "jobs.rate", env: []string{"JOBS_RATE"}, usage: "CEPs per second looked up by all the jobs together (0 disables the throttle)", value: &c.Jobs.Rate},
+		{key: "jobs.retention", env: []string{"JOBS_RETENTION"}, usage: "time finished jobs are kept (0 keeps them forever)", value: &c.Jobs.Retention},
+
+		{key: "stream.poll_interval", env: []string{"STREAM_POLL_INTERVAL"}, usage: "how often a streamed city is polled", value: &c.Stream.PollInterval},
+		{key: "stream.heartbeat", env: []string{"STREAM_HEARTBEAT"}, usage: "interval of the heartbeat comments of a stream", value: &c.Stream.Heartbeat},
+
+		{key: "health.probe_interval", env: []string{"HEALTH_PROBE_INTERVAL"}, usage: "interval of the provider probes (0 disables them)", value: &c.Health.ProbeInterval},
+		{key: "health.probe_timeout", env: []string{"HEALTH_PROBE_TIMEOUT"}, usage: "timeout of each provider probe", value: &c.Health.ProbeTimeout},
+
+		{key: "log.format", env: []string{"LOG_FORMAT"}, usage: "json or text (default json, text when env is local)", value: &c.Log.Format},
+		{key: "log.level", env: []string{"LOG_LEVEL"}, usage: "debug, info, warn or error", reload: true, value: &c.Log.Level},
+
+		{key: "shutdown.pre_stop_delay", env: []string{"PRE_STOP_DELAY"}, usage: "time to keep serving after SIGTERM while unready", value: &c.Shutdown.PreStopDelay},
+		{key: "shutdown.timeout", env: []string{"SHUTDOWN_TIMEOUT"}, usage: "time allowed to drain on shutdown", value: &c.Shutdown.Timeout},
+
+		{key: "secrets.refresh_interval", env: []string{"SECRETS_REFRESH_INTERVAL"}, usage: "how often mounted secrets are re-read (0 disables)", value: &c.Secrets.RefreshInterval},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+	checkErr := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check(validPort(c.HTTP.Port), "http.port", "must be a port number, got %q", c.HTTP.Port)
+	check(validPort(c.GRPC.Port), "grpc.port", "must be a port number, got %q", c.GRPC.Port)
+	check(c.Weather.APIKey != "", "weather.api_key", "is required")
+	check(len(c.Providers.CEP) > 0, "providers.cep", "must enable at least one provider")
+	checkProviders(&errs, "providers.cep", c.Providers.CEP, domain.ProviderBrasilAPI, domain.ProviderViaCEP)
+	checkProviders(&errs, "providers.weather", c.Providers.Weather, domain.ProviderHGWeather)
+
+	check(c.Upstream.Timeout > 0, "upstream.timeout", "must be positive")
+	check(c.Upstream.CEPTimeout > 0, "upstream.cep_timeout", "must be positive")
+	check(c.Upstream.CEPHedgeDelay >= 0, "upstream.cep_hedge_delay", "must not be negative")
+	check(c.Upstream.WeatherTimeout > 0, "upstream.weather_timeout", "must be positive")
+	check(c.Upstream.MaxRetries >= 0, "upstream.max_retries", "must not be negative")
+	check(c.Upstream.MaxRetryInterval > 0, "upstream.max_retry_interval", "must be positive")
+
+	check(c.Breaker.FailureRatio > 0 && c.Breaker.FailureRatio <= 1, "breaker.failure_ratio", "must be in (0, 1]")
+	check(c.Breaker.MinRequests >= 1, "breaker.min_requests", "must be at least 1")
+	check(c.Breaker.Interval >= 0, "breaker.interval", "must not be negative")
+	check(c.Breaker.CoolDown > 0, "breaker.cool_down", "must be positive")
+
+	check(c.RateLimit.RPS >= 0, "rate_limit.rps", "must not be negative")
+	check(c.RateLimit.RPS == 0 || c.RateLimit.Burst >= 1, "rate_limit.burst", "must be at least 1")
+	check(c.RateLimit.TrustedProxies >= 0, "rate_limit.trusted_proxies", "must not be negative")
+	_, err := ratelimit.ParseTiers(strings.Join(c.RateLimit.Tiers, ","))
+	checkErr("rate_limit.tiers", err)
+	_, err = ratelimit.ParseAPIKeyTiers(strings.Join(c.RateLimit.APIKeys, ","))
+	checkErr("rate_limit.api_keys", err)
+
+	_, err = apikey.ParseEnv(strings.Join(c.APIKeys.Keys, ","))
+	checkErr("api_keys.keys", err)
+
+	check(c.Temperature.Precision >= 0 && c.Temperature.Precision <= temperature.MaxPrecision,
+		"temperature.precision", "must be between 0 and %d", temperature.MaxPrecision)
+	_, err = temperature.ParseRoundingMode(c.Temperature.Rounding)
+	checkErr("temperature.rounding", err)
+
+	check(c.Batch.MaxSize >= 1, "batch.max_size", "must be at least 1")
+	check(c.Batch.Workers >= 1, "batch.workers", "must be at least 1")
+
+	check(c.Jobs.Store == JobStoreMemory || c.Jobs.Store == JobStoreFile, "jobs.store", "must be %s or %s, got %q", JobStoreMemory, JobStoreFile, c.Jobs.Store)
+	check(c.Jobs.Store != JobStoreFile || c.Jobs.Dir != "", "jobs.dir", "is required by the file store")
+	check(c.Jobs.MaxSize >= 1, "jobs.max_size", "must be at least 1")
+	check(c.Jobs.Workers >= 1, "jobs.workers", "must be at least 1")
+	check(c.Jobs.Concurrency >= 1, "jobs.concurrency", "must be at least 1")
+	check(c.Jobs.QueueSize >= 1, "jobs.queue_size", "must be at least 1")
+	check(c.Jobs.Rate >= 0, "jobs.rate", "must not be negative")
+	check(c.Jobs.Retention >= 0, "jobs.retention", "must not be negative")
+
+	check(c.Stream.PollInterval >= time.Second, "stream.poll_interval", "must be at least 1s")
+	check(c.Stream.Heartbeat > 0, "stream.heartbeat", "must be positive")
+
+	check(c.Health.ProbeInterval >= 0, "health.probe_interval", "must not be negative")
+	check(c.Health.ProbeTimeout > 0, "health.probe_timeout", "must be positive")
+
+	_, err = logger.ParseFormat(c.Log.Format)
+	checkErr("log.format", err)
+	_, err = logger.ParseLevel(c.Log.Level)
+	checkErr("log.level", err)
+
+	check(c.Shutdown.PreStopDelay >= 0, "shutdown.pre_stop_delay", "must not be negative")
+	check(c.Shutdown.Timeout > 0, "shutdown.timeout", "must be positive")
+	check(c.Secrets.RefreshInterval >= 0, "secrets.refresh_interval", "must not be negative")
+
+	return errors.Join(errs...)
+}
+
+func checkProviders(errs *[]error, key string, providers []string, known ...string) {
+	seen := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		if !slices.Contains(known, p) {
+			*errs = append(*errs, fmt.Errorf("%s: unknown provider %q: must be one of %s", key, p, strings.Join(known, ", ")))
+		}
+		if seen[p] {
+			*errs = append(*errs, fmt.Errorf("%s: duplicated provider %q", key, p))
+		}
+		seen[p] = true
+	}
+}
+
+// canonicalProviders matches provider names regardless of case: viacep is ViaCEP.
+func canonicalProviders(providers []string) []string {
+	known := []string{domain.ProviderBrasilAPI, domain.ProviderViaCEP, domain.ProviderHGWeather}
+	out := make([]string, len(providers))
+	for i, p := range providers {
+		out[i] = p
+		for _, k := range known {
+			if strings.EqualFold(p, k) {
+				out[i] = k
+			}
+		}
+	}
+	return out
+}
+
+// Diff lists the keys whose value differ between two configurations: those a reload applies
+// and those needing a restart.
+func Diff(old, new *Config) (reloaded, restart []string) {
+	oldFields, newFields := old.fields(), new.fields()
+	for i, f := range newFields {
+		if reflect.DeepEqual(reflect.ValueOf(oldFields[i].value).Elem().Interface(), reflect.ValueOf(f.value).Elem().Interface()) {
+			continue
+		}
+		if f.reload {
+			reloaded = append(reloaded, f.key)
+		} else {
+			restart = append(restart, f.key)
+		}
+	}
+	return reloaded, restart
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load(nil, lookup(map[string]string{"WEATHER_API_KEY": "k"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "8080", c.HTTP.Port)
+	assert.Equal(t, time.Second, c.Upstream.CEPTimeout)
+	assert.Equal(t, 5, c.Upstream.MaxRetries)
+	assert.Equal(t, "json", c.Log.Format)
+	assert.False(t, c.HTTP.AccessLog)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+http:
+  port: "8000"
+  access_log: true
+upstream:
+  timeout: 30s
+  cep_timeout: 2s
+  max_retries: 3
+rate_limit:
+  tiers: [gold=100:200, silver=50:100]
+log:
+  level: debug
+`)
+	env := map[string]string{
+		"WEATHER_API_KEY":    "k",
+		"CONFIG_FILE":        path,
+		"CEP_LOOKUP_TIMEOUT": "3s",
+		"LOG_LEVEL":          "warn",
+		"ENV":                "local",
+	}
+
+	c, err := Load([]string{"--log-level", "error", "--upstream-max-retries=0"}, lookup(env))
+	require.NoError(t, err)
+
+	assert.Equal(t, path, c.File)
+	assert.Equal(t, "8000", c.HTTP.Port, "file over default")
+	assert.True(t, c.HTTP.AccessLog)
+	assert.Equal(t, 30*time.Second, c.Upstream.Timeout)
+	assert.Equal(t, 3*time.Second, c.Upstream.CEPTimeout, "env over file")
+	assert.Equal(t, "error", c.Log.Level, "flag over env")
+	assert.Equal(t, 0, c.Upstream.MaxRetries, "flag over file")
+	assert.Equal(t, []string{"gold=100:200", "silver=50:100"}, c.RateLimit.Tiers)
+	assert.Equal(t, "text", c.Log.Format, "text logs by default when ENV=local")
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[weather]
+api_key = "k"
+
+[breaker]
+failure_ratio = 1
+cool_down = "1m"
+
+[api_keys]
+keys = ["partner:s3cr3t"]
+`)
+
+	c, err := Load([]string{"--config", path}, lookup(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, c.Breaker.FailureRatio)
+	assert.Equal(t, time.Minute, c.Breaker.CoolDown)
+	assert.Equal(t, []string{"partner:s3cr3t"}, c.APIKeys.Keys)
+}
+
+func TestLoadPortFromCloudRun(t *testing.T) {
+	c, err := Load(nil, lookup(map[string]string{"WEATHER_API_KEY": "k", "PORT": "8081", "APP_PORT": "9000", "K_SERVICE": "api"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "8081", c.HTTP.Port)
+	assert.True(t, c.HTTP.AccessLog, "access log on by default on Cloud Run")
+}
+
+func TestLoadSecretsFromFiles(t *testing.T) {
+	env := map[string]string{
+		"WEATHER_API_KEY_FILE":     writeFile(t, "weather", "s3cr3t\n"),
+		"RATE_LIMIT_API_KEYS_FILE": writeFile(t, "tiers", "k1=partner\nk2=internal\n"),
+	}
+
+	c, err := Load(nil, lookup(env))
+	require.NoError(t, err)
+
+	assert.Equal(t, "s3cr3t", c.Weather.APIKey)
+	assert.Equal(t, []string{"k1=partner", "k2=internal"}, c.RateLimit.APIKeys)
+
+	env["WEATHER_API_KEY"] = "other"
+	_, err = Load(nil, lookup(env))
+	assert.ErrorContains(t, err, "set either WEATHER_API_KEY or WEATHER_API_KEY_FILE")
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+upstream:
+  timeout: 30
+  retries: 3
+`)
+	env := map[string]string{
+		"CONFIG_FILE":       path,
+		"APP_PORT":          "http",
+		"BREAKER_COOL_DOWN": "soon",
+		"ACCESS_LOG":        "maybe",
+		"LOG_FORMAT":        "xml",
+		"RATE_LIMIT_TIERS":  "gold",
+	}
+
+	_, err := Load([]string{"--temperature-precision", "9"}, lookup(env))
+	require.Error(t, err)
+
+	for _, want := range []string{
+		`upstream.timeout: invalid duration "30"`,
+		`unknown key "upstream.retries"`,
+		"BREAKER_COOL_DOWN: invalid duration",
+		`ACCESS_LOG: invalid boolean "maybe"`,
+		`http.port: must be a port number, got "http"`,
+		"weather.api_key: is required",
+		"temperature.precision: must be between 0 and 6",
+		"log.format:",
+		"rate_limit.tiers:",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestLoadRejectsUnknownFlag(t *testing.T) {
+	_, err := Load([]string{"--nope"}, lookup(nil))
+	assert.Error(t, err)
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	env := map[string]string{
+		"WEATHER_API_KEY":     "s3cr3t-weather",
+		"API_KEYS":            "partner:s3cr3t-partner",
+		"RATE_LIMIT_API_KEYS": "s3cr3t-partner=gold",
+	}
+	c, err := Load([]string{"--print-config"}, lookup(env))
+	require.NoError(t, err)
+	assert.True(t, c.PrintConfig)
+
+	var out bytes.Buffer
+	require.NoError(t, c.Print(&out))
+
+	assert.NotContains(t, out.String(), "s3cr3t")
+	assert.Contains(t, out.String(), "api_key: REDACTED")
+	assert.Contains(t, out.String(), "cep_timeout: 1s")
+	assert.Contains(t, out.String(), `port: "8080"`)
+}
+
+func TestLoadProviders(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+providers:
+  cep: [viacep]
+  weather: []
+`)
+	c, err := Load([]string{"--config", path}, lookup(map[string]string{"WEATHER_API_KEY": "k"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ViaCEP"}, c.Providers.CEP)
+	assert.Empty(t, c.Providers.Weather)
+
+	_, err = Load(nil, lookup(map[string]string{"WEATHER_API_KEY": "k", "CEP_PROVIDERS": "ViaCEP,Correios,viacep"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown provider "Correios"`)
+	assert.Contains(t, err.Error(), `duplicated provider "ViaCEP"`)
+}
+
+func TestDiff(t *testing.T) {
+	old, err := Load(nil, lookup(map[string]string{"WEATHER_API_KEY": "k"}))
+	require.NoError(t, err)
+	new, err := Load([]string{"--log-level", "debug", "--http-port", "9090"},
+		lookup(map[string]string{"WEATHER_API_KEY": "k2", "CEP_PROVIDERS": "ViaCEP"}))
+	require.NoError(t, err)
+
+	reloaded, restart := Diff(old, new)
+	assert.Equal(t, []string{"weather.api_key", "providers.cep", "log.level"}, reloaded)
+	assert.Equal(t, []string{"http.port"}, restart)
+
+	reloaded, restart = Diff(old, old)
+	assert.Empty(t, reloaded)
+	assert.Empty(t, restart)
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/pkg/env"
+	"api-server/pkg/logger"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envConfigFile = "CONFIG_FILE"
+	envCloudRun   = "K_SERVICE"
+)
+
+// Load reads the configuration from, in increasing precedence, the defaults, the YAML or TOML file
+// given by --config (or CONFIG_FILE), the environment and the command-line flags, and validates it.
+// Every problem found is reported at once. It returns flag.ErrHelp when -h is passed.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	fields := c.fields()
+
+	getenv := func(name string) string {
+		value, _ := lookupEnv(name)
+		return value
+	}
+	if getenv(envCloudRun) != "" {
+		c.HTTP.AccessLog = true
+	}
+
+	flags, err := parseFlags(c, fields, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if c.File == "" {
+		c.File = getenv(envConfigFile)
+	}
+	if c.File != "" {
+		errs = append(errs, loadFile(c.File, fields))
+	}
+
+	secrets := env.EnvSecrets{LookupEnv: lookupEnv}
+	for _, f := range fields {
+		for _, name := range f.env {
+			value := getenv(name)
+			if f.file {
+				var err error
+				if value, _, err = secrets.Secret(name); err != nil {
+					errs = append(errs, err)
+					break
+				}
+			}
+			if value != "" {
+				if err := f.set(value); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				}
+				break
+			}
+		}
+	}
+
+	for _, fv := range flags {
+		if err := fv.field.set(fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", flagName(fv.field.key), err))
+		}
+	}
+
+	c.Providers.CEP = canonicalProviders(c.Providers.CEP)
+	c.Providers.Weather = canonicalProviders(c.Providers.Weather)
+
+	if c.Log.Format == "" {
+		c.Log.Format = logger.FormatJSON
+		if c.Env == EnvLocal {
+			c.Log.Format = logger.FormatText
+		}
+	}
+
+	return c, errors.Join(append(errs, c.Validate())...)
+}
+
+type flagValue struct {
+	field field
+	value string
+}
+
+// parseFlags registers a flag per field but the secrets. The values are only recorded here, and applied after
+// the file and the environment.
+func parseFlags(c *Config, fields []field, args []string) ([]flagValue, error) {
+	fs := flag.NewFlagSet("api-server", flag.ContinueOnError)
+	fs.StringVar(&c.File, "config", "", "YAML or TOML configuration file (also CONFIG_FILE)")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the effective configuration, secrets masked, and exit")
+
+	var values []flagValue
+	for _, f := range fields {
+		f := f
+		// Segredos não têm flag: a linha de comando é visível para todos os processos
+		if f.secret {
+			continue
+		}
+		usage := f.usage
+		if len(f.env) > 0 {
+			usage += " (" + strings.Join(f.env, ", ") + ")"
+		}
+		fs.Func(flagName(f.key), usage, func(s string) error {
+			values = append(values, flagValue{field: f, value: s})
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return values, nil
+}
+
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+func loadFile(path string, fields []field) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	doc := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &doc)
+	case ".toml":
+		err = toml.Unmarshal(content, &doc)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q: must be .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]any{}
+	flatten("", doc, values)
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		f, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+			continue
+		}
+		if err := f.set(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flatten turns nested tables into dotted keys: {upstream: {timeout: 2s}} -> upstream.timeout.
+func flatten(prefix string, doc map[string]any, out map[string]any) {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if table, ok := value.(map[string]any); ok {
+			flatten(key, table, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// set parses v, a string from the environment or a flag, or a value decoded from the file.
+func (f field) set(v any) error {
+	if list, ok := f.value.(*[]string); ok {
+		switch v := v.(type) {
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+			}
+			*list = items
+		default:
+			*list = splitList(fmt.Sprint(v))
+		}
+		return nil
+	}
+
+	s := strings.TrimSpace(fmt.Sprint(v))
+	switch p := f.value.(type) {
+	case *string:
+		*p = s
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		*p = n
+	case *float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: use a unit, e.g. 500ms or 2s", s)
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported type %T", f.value)
+	}
+	return nil
+}
+
+// splitList parses values separated by commas or lines, ignoring blanks.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Print writes the effective configuration as YAML, with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	doc := map[string]any{}
+	for _, f := range c.fields() {
+		setPath(doc, strings.Split(f.key, "."), f.printable())
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+const masked = "REDACTED"
+
+func (f field) printable() any {
+	switch p := f.value.(type) {
+	case *string:
+		if f.secret && *p != "" {
+			return masked
+		}
+		return *p
+	case *[]string:
+		list := make([]string, len(*p))
+		for i, item := range *p {
+			if f.secret {
+				item = masked
+			}
+			list[i] = item
+		}
+		return list
+	case *time.Duration:
+		return p.String()
+	case *int:
+		return *p
+	case *float64:
+		return *p
+	case *bool:
+		return *p
+	}
+	return nil
+}
+
+func setPath(doc map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := doc[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			doc[key] = next
+		}
+		doc = next
+	}
+	doc[path[len(path)-1]] = value
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"api-server/domain"
+	"api-server/pkg/circuitbreaker"
+)
+
+// IsProviderSuccess is the circuit breaker success predicate for upstream providers:
+// a CEP that does not exist is a valid answer, not a provider failure.
+func IsProviderSuccess(err error) bool {
+	return circuitbreaker.DefaultIsSuccessful(err) || errors.Is(err, domain.ErrCEPNotFound)
+}
+
+// BreakerBuscaCEPAPIClient wraps a domain.BuscaCEPAPIClient with one circuit breaker per provider.
+type BreakerBuscaCEPAPIClient struct {
+	next     domain.BuscaCEPAPIClient
+	breakers *circuitbreaker.Registry
+}
+
+func NewBreakerBuscaCEPAPIClient(next domain.BuscaCEPAPIClient, breakers *circuitbreaker.Registry) *BreakerBuscaCEPAPIClient {
+	return &BreakerBuscaCEPAPIClient{
+		next:     next,
+		breakers: breakers,
+	}
+}
+
+func (bc *BreakerBuscaCEPAPIClient) GetBrasilAPICEP(ctx context.Context, cep string) (string, error) {
+	return withBreaker(bc.breakers.Get(domain.ProviderBrasilAPI), func() (string, error) {
+		return bc.next.GetBrasilAPICEP(ctx, cep)
+	})
+}
+
+func (bc *BreakerBuscaCEPAPIClient) GetViaAPICEP(ctx context.Context, cep string) (string, error) {
+	return withBreaker(bc.breakers.Get(domain.ProviderViaCEP), func() (string, error) {
+		return bc.next.GetViaAPICEP(ctx, cep)
+	})
+}
+
+func (bc *BreakerBuscaCEPAPIClient) SearchViaAPICEP(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+	return withBreaker(bc.breakers.Get(domain.ProviderViaCEP), func() ([]domain.Address, error) {
+		return bc.next.SearchViaAPICEP(ctx, uf, city, street)
+	})
+}
+
+// BreakerWeatherAPIClient wraps a domain.WeatherAPIClient with the HG Weather circuit breaker.
+type BreakerWeatherAPIClient struct {
+	next     domain.WeatherAPIClient
+	breakers *circuitbreaker.Registry
+}
+
+func NewBreakerWeatherAPIClient(next domain.WeatherAPIClient, breakers *circuitbreaker.Registry) *BreakerWeatherAPIClient {
+	return &BreakerWeatherAPIClient{
+		next:     next,
+		breakers: breakers,
+	}
+}
+
+func (bc *BreakerWeatherAPIClient) GetHGWeatherAPI(ctx context.Context, city string) (int, error) {
+	return withBreaker(bc.breakers.Get(domain.ProviderHGWeather), func() (int, error) {
+		return bc.next.GetHGWeatherAPI(ctx, city)
+	})
+}
+
+func withBreaker[T any](breaker *circuitbreaker.Breaker, fn func() (T, error)) (T, error) {
+	if breaker == nil {
+		return fn()
+	}
+
+	var res T
+	err := breaker.Execute(func() error {
+		var err error
+		res, err = fn()
+		return err
+	})
+	return res, err
+}
@@ -5,43 +5,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
 
 	"api-server/domain"
 	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/logger"
 
 	"github.com/cenkalti/backoff"
 )
 
 type BuscaCEPAPIClient struct {
 	httpClient httpclient.HTTPClient
-	log        *log.Logger
+	log        *slog.Logger
+	options
 }
 
 // type BuscaCEPAPI interface {
 // 	GetDolarQuote(c context.Context) (*domain.BuscaCEPAPIResponse, error)
 // }
 
-func NewBuscaCEPAPIClient(httpClient httpclient.HTTPClient, log *log.Logger) *BuscaCEPAPIClient {
+func NewBuscaCEPAPIClient(httpClient httpclient.HTTPClient, log *slog.Logger, opts ...Option) *BuscaCEPAPIClient {
 	return &BuscaCEPAPIClient{
 		httpClient: httpClient,
 		log:        log,
+		options:    newOptions(opts),
 	}
 }
 
-func (awc *BuscaCEPAPIClient) getCEP(ctx context.Context, url string) ([]byte, error) {
+func (awc *BuscaCEPAPIClient) getCEP(ctx context.Context, provider, url string) ([]byte, error) {
 	var resBody []byte
 
-	ebo := backoff.NewExponentialBackOff()
-	ebo.MaxInterval = 1 * time.Second
+	log := logger.FromContext(ctx, awc.log).With(logger.KeyProvider, provider, "url", httpclient.DefaultRedactor.URL(url))
 
-	if err := backoff.Retry(func() error {
+	attempt := 0
+	if err := backoff.RetryNotify(func() (err error) {
+		attempt++
+		attemptCtx, span := startAttempt(ctx, provider, attempt)
+		defer func() { endAttempt(span, err) }()
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		log := log.With(logger.KeyAttempt, attempt)
+		start := time.Now()
+
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
 		if err != nil {
-			awc.log.Printf("error to create request to search CEP through URL: %s. [Error]: %s", url, err.Error())
+			err = httpclient.DefaultRedactor.Error(err)
+			log.ErrorContext(attemptCtx, "Could not create request", logger.Err(err))
 			return err
 		}
 
@@ -49,33 +60,37 @@ func (awc *BuscaCEPAPIClient) getCEP(ctx context.Context, url string) ([]byte, e
 
 		res, err := awc.httpClient.Do(req)
 		if err != nil {
-			awc.log.Printf("error to search CEP through URL: %s. [Error]: %s", url, err.Error())
-			return err
+			// Os erros do net/http incluem a URL completa, com eventuais chaves de API
+			err = httpclient.DefaultRedactor.Error(err)
+			log.WarnContext(attemptCtx, "Request failed", logger.Duration(time.Since(start)), logger.Err(err))
+			return requestError(err)
 		}
 		defer func() {
-			err = res.Body.Close()
+			err := res.Body.Close()
 			if err != nil {
-				awc.log.Printf("error to close response body from URL: %s. [Erro]: %s", url, err.Error())
+				log.WarnContext(attemptCtx, "Could not close response body", logger.Err(err))
 				return
 			}
 		}()
 
 		bodyBytes, err := io.ReadAll(res.Body)
 		if err != nil {
-			awc.log.Printf("error to read response body from URL: %s. [Error]: %s", url, err.Error())
+			log.WarnContext(attemptCtx, "Could not read response body", logger.Duration(time.Since(start)), logger.Err(err))
 			return err
 		}
 
 		if res.StatusCode != 200 {
-			awc.log.Printf("Search CEP through URL [%s]- API status code %d: %s", url, res.StatusCode, bodyBytes)
-			return err
+			log.WarnContext(attemptCtx, "Unexpected response status", logger.KeyStatus, res.StatusCode,
+				logger.Duration(time.Since(start)), "body", httpclient.DefaultRedactor.String(string(bodyBytes)))
+			return statusError(provider, res.StatusCode, true)
 		}
 
+		log.DebugContext(attemptCtx, "Request succeeded", logger.KeyStatus, res.StatusCode, logger.Duration(time.Since(start)))
 		resBody = bodyBytes
 		return nil
 
-	}, backoff.WithContext(backoff.WithMaxRetries(ebo, uint64(5)), ctx)); err != nil {
-		awc.log.Printf("error to search CEP through URL: %s. [Error]: %s", url, err.Error())
+	}, awc.backOff(ctx), awc.notifyRetry(provider)); err != nil {
+		log.WarnContext(ctx, "Giving up after retries", logger.KeyAttempt, attempt, logger.Err(err))
 		return []byte{}, err
 	}
 
@@ -86,14 +101,14 @@ func (awc *BuscaCEPAPIClient) GetBrasilAPICEP(ctx context.Context, cep string) (
 	var brasilAPIResponse *domain.BrasilAPIResponse
 	brasilAPIUrl := "https://brasilapi.com.br/api/cep/v1/" + cep
 
-	resBody, err := awc.getCEP(ctx, brasilAPIUrl)
+	resBody, err := awc.getCEP(ctx, domain.ProviderBrasilAPI, brasilAPIUrl)
 	if err != nil {
-		awc.log.Printf("error to search CEP in BrasilAPI: %s. [Erro]: %s", cep, err.Error())
 		return "", err
 	}
 
 	if err := json.Unmarshal(resBody, &brasilAPIResponse); err != nil {
-		awc.log.Printf("error to translate response Body to BrasilAPI pattern: %s", err.Error())
+		logger.FromContext(ctx, awc.log).ErrorContext(ctx, "Could not decode response",
+			logger.KeyProvider, domain.ProviderBrasilAPI, logger.KeyCEP, cep, logger.Err(err))
 		return "", err
 	}
 
@@ -106,23 +121,53 @@ func (awc *BuscaCEPAPIClient) GetViaAPICEP(ctx context.Context, cep string) (str
 	var viaAPIResponse *domain.ViaCEPAPIResponse
 	viaAPIUrl := "https://viacep.com.br/ws/" + cep + "/json/"
 
-	resBody, err := awc.getCEP(ctx, viaAPIUrl)
+	resBody, err := awc.getCEP(ctx, domain.ProviderViaCEP, viaAPIUrl)
 	if err != nil {
-		awc.log.Printf("error to search CEP in ViaAPI: %s. [Erro]: %s", cep, err.Error())
 		return "", err
 	}
 
 	if err := json.Unmarshal(resBody, &viaAPIResponse); err != nil {
-		awc.log.Printf("error to translate response Body to ViaAPI pattern: %s", err.Error())
+		logger.FromContext(ctx, awc.log).ErrorContext(ctx, "Could not decode response",
+			logger.KeyProvider, domain.ProviderViaCEP, logger.KeyCEP, cep, logger.Err(err))
 		return "", err
 	}
 
 	if viaAPIResponse.Erro != "" {
-		awc.log.Printf("error returned by ViaAPI for CEP: %s. [Erro]: %s", cep, viaAPIResponse.Erro)
-		return "", fmt.Errorf("unable to find CEP in ViaAPI")
+		logger.FromContext(ctx, awc.log).InfoContext(ctx, "CEP not found",
+			logger.KeyProvider, domain.ProviderViaCEP, logger.KeyCEP, cep)
+		return "", fmt.Errorf("unable to find CEP in ViaAPI: %w", domain.ErrCEPNotFound)
 	}
 
 	cityInfo := fmt.Sprint(viaAPIResponse.Localidade, ",", viaAPIResponse.Uf)
 
 	return cityInfo, nil
 }
+
+func (awc *BuscaCEPAPIClient) SearchViaAPICEP(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+	var viaAPIResponse []domain.ViaCEPAPIResponse
+	viaAPIUrl := "https://viacep.com.br/ws/" + url.PathEscape(uf) + "/" + url.PathEscape(city) + "/" + url.PathEscape(street) + "/json/"
+
+	resBody, err := awc.getCEP(ctx, domain.ProviderViaCEP, viaAPIUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(resBody, &viaAPIResponse); err != nil {
+		logger.FromContext(ctx, awc.log).ErrorContext(ctx, "Could not decode response",
+			logger.KeyProvider, domain.ProviderViaCEP, "uf", uf, logger.KeyCity, city, "street", street, logger.Err(err))
+		return nil, err
+	}
+
+	addresses := make([]domain.Address, 0, len(viaAPIResponse))
+	for _, r := range viaAPIResponse {
+		addresses = append(addresses, domain.Address{
+			CEP:          r.Cep,
+			Street:       r.Logradouro,
+			Complement:   r.Complemento,
+			Neighborhood: r.Bairro,
+			City:         r.Localidade,
+			UF:           r.Uf,
+		})
+	}
+	return addresses, nil
+}
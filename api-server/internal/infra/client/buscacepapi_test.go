@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-server/domain"
+	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuscaCEPAPIClientSearchViaAPICEP(t *testing.T) {
+	var path string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		_, _ = w.Write([]byte(`[{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP"}]`))
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: upstreamTransport{host: strings.TrimPrefix(upstream.URL, "http://")}}
+	client := NewBuscaCEPAPIClient(httpClient, logger.Discard())
+
+	addresses, err := client.SearchViaAPICEP(context.Background(), "SP", "São Paulo", "Praça da Sé")
+
+	require.NoError(t, err)
+	assert.Equal(t, "/ws/SP/S%C3%A3o%20Paulo/Pra%C3%A7a%20da%20S%C3%A9/json/", path)
+	assert.Equal(t, []domain.Address{{
+		CEP: "01001-000", Street: "Praça da Sé", Complement: "lado ímpar", Neighborhood: "Sé", City: "São Paulo", UF: "SP",
+	}}, addresses)
+}
+
+func TestBuscaCEPAPIClientStatus(t *testing.T) {
+	var calls int
+	status := http.StatusOK
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"message":"error"}`))
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: upstreamTransport{host: strings.TrimPrefix(upstream.URL, "http://")}}
+	client := NewBuscaCEPAPIClient(httpClient, logger.Discard(), WithRetries(2, time.Millisecond))
+
+	tests := []struct {
+		status   int
+		calls    int
+		notFound bool
+	}{
+		{status: http.StatusNotFound, calls: 1, notFound: true},
+		{status: http.StatusBadRequest, calls: 1},
+		{status: http.StatusServiceUnavailable, calls: 3},
+		{status: http.StatusTooManyRequests, calls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			calls, status = 0, tt.status
+			_, err := client.GetBrasilAPICEP(context.Background(), "01001000")
+
+			var statusErr *StatusError
+			require.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, tt.status, statusErr.StatusCode)
+			assert.Equal(t, tt.notFound, errors.Is(err, domain.ErrCEPNotFound))
+			assert.Equal(t, tt.notFound, IsProviderSuccess(err), "only an unknown CEP spares the circuit breaker")
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+// closedTracker refuses every call, as the tracker does once the shutdown stopped waiting for new ones.
+type closedTracker struct{ starts int }
+
+func (c *closedTracker) Start() (func(), bool) {
+	c.starts++
+	return nil, false
+}
+
+func TestBuscaCEPAPIClientShuttingDown(t *testing.T) {
+	tracker := &closedTracker{}
+	client := NewBuscaCEPAPIClient(httpclient.NewTrackedHTTPClient(http.DefaultClient, tracker), logger.Discard(),
+		WithRetries(2, time.Millisecond))
+
+	_, err := client.GetBrasilAPICEP(context.Background(), "01001000")
+
+	assert.ErrorIs(t, err, httpclient.ErrShuttingDown)
+	assert.Equal(t, 1, tracker.starts, "not retried")
+}
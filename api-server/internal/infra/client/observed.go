@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"api-server/domain"
+)
+
+// ObservedBuscaCEPAPIClient reports every CEP provider call to an observer.
+type ObservedBuscaCEPAPIClient struct {
+	next     domain.BuscaCEPAPIClient
+	observer domain.ProviderObserver
+}
+
+func NewObservedBuscaCEPAPIClient(next domain.BuscaCEPAPIClient, observer domain.ProviderObserver) *ObservedBuscaCEPAPIClient {
+	return &ObservedBuscaCEPAPIClient{
+		next:     next,
+		observer: observer,
+	}
+}
+
+func (oc *ObservedBuscaCEPAPIClient) GetBrasilAPICEP(ctx context.Context, cep string) (string, error) {
+	return observe(ctx, oc.observer, domain.ProviderBrasilAPI, func() (string, error) {
+		return oc.next.GetBrasilAPICEP(ctx, cep)
+	})
+}
+
+func (oc *ObservedBuscaCEPAPIClient) GetViaAPICEP(ctx context.Context, cep string) (string, error) {
+	return observe(ctx, oc.observer, domain.ProviderViaCEP, func() (string, error) {
+		return oc.next.GetViaAPICEP(ctx, cep)
+	})
+}
+
+func (oc *ObservedBuscaCEPAPIClient) SearchViaAPICEP(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+	return observe(ctx, oc.observer, domain.ProviderViaCEP, func() ([]domain.Address, error) {
+		return oc.next.SearchViaAPICEP(ctx, uf, city, street)
+	})
+}
+
+// ObservedWeatherAPIClient reports every weather provider call to an observer.
+type ObservedWeatherAPIClient struct {
+	next     domain.WeatherAPIClient
+	observer domain.ProviderObserver
+}
+
+func NewObservedWeatherAPIClient(next domain.WeatherAPIClient, observer domain.ProviderObserver) *ObservedWeatherAPIClient {
+	return &ObservedWeatherAPIClient{
+		next:     next,
+		observer: observer,
+	}
+}
+
+func (oc *ObservedWeatherAPIClient) GetHGWeatherAPI(ctx context.Context, city string) (int, error) {
+	return observe(ctx, oc.observer, domain.ProviderHGWeather, func() (int, error) {
+		return oc.next.GetHGWeatherAPI(ctx, city)
+	})
+}
+
+func observe[T any](ctx context.Context, observer domain.ProviderObserver, provider string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	res, err := fn()
+	observer.ObserveProviderCall(ctx, provider, time.Since(start), err)
+	return res, err
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"api-server/domain"
+
+	"github.com/cenkalti/backoff"
+)
+
+// Option configures the upstream clients.
+type Option func(*options)
+
+type options struct {
+	retryObserver    domain.RetryObserver
+	maxRetries       uint64
+	maxRetryInterval time.Duration
+}
+
+const (
+	defaultMaxRetries       = 5
+	defaultMaxRetryInterval = 1 * time.Second
+)
+
+// WithRetryObserver reports every retry of the backoff loops.
+func WithRetryObserver(observer domain.RetryObserver) Option {
+	return func(o *options) {
+		o.retryObserver = observer
+	}
+}
+
+// WithRetries bounds the backoff loops: at most maxRetries retries, maxInterval apart.
+func WithRetries(maxRetries int, maxInterval time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries = uint64(maxRetries)
+		o.maxRetryInterval = maxInterval
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{maxRetries: defaultMaxRetries, maxRetryInterval: defaultMaxRetryInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o options) backOff(ctx context.Context) backoff.BackOff {
+	ebo := backoff.NewExponentialBackOff()
+	ebo.MaxInterval = o.maxRetryInterval
+	return backoff.WithContext(backoff.WithMaxRetries(ebo, o.maxRetries), ctx)
+}
+
+func (o options) notifyRetry(provider string) backoff.Notify {
+	return func(error, time.Duration) {
+		if o.retryObserver != nil {
+			o.retryObserver.ObserveRetry(provider)
+		}
+	}
+}
+
+var providerHosts = map[string]string{
+	"brasilapi.com.br": domain.ProviderBrasilAPI,
+	"viacep.com.br":    domain.ProviderViaCEP,
+	"api.hgbrasil.com": domain.ProviderHGWeather,
+}
+
+// ProviderForHost returns the provider served by host, or "" for unknown hosts.
+func ProviderForHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return providerHosts[host]
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"api-server/domain"
+	httpclient "api-server/pkg/http_client"
+
+	"github.com/cenkalti/backoff"
+)
+
+// StatusError is an unexpected response status of a provider.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded with status %d", e.Provider, e.StatusCode)
+}
+
+// statusError classifies a non-200 response for the backoff loops. A 404 of a CEP lookup is a CEP
+// that does not exist, other 4xx are not retried and the rest (5xx, 429) are.
+func statusError(provider string, status int, notFound bool) error {
+	err := &StatusError{Provider: provider, StatusCode: status}
+	switch {
+	case status == http.StatusNotFound && notFound:
+		return backoff.Permanent(fmt.Errorf("%w: %w", domain.ErrCEPNotFound, err))
+	case status == http.StatusTooManyRequests:
+		return err
+	case status >= 400 && status < 500:
+		return backoff.Permanent(err)
+	}
+	return err
+}
+
+// requestError classifies a failed request for the backoff loops: once the shutdown refuses new
+// upstream calls, no retry can succeed.
+func requestError(err error) error {
+	if errors.Is(err, httpclient.ErrShuttingDown) {
+		return backoff.Permanent(err)
+	}
+	return err
+}
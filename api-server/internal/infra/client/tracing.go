@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("api-server/internal/infra/client")
+
+// startAttempt starts the span of one attempt of the backoff loop.
+func startAttempt(ctx context.Context, provider string, attempt int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, provider+" attempt", trace.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.Int("attempt", attempt),
+	))
+}
+
+func endAttempt(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -5,42 +5,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
 	"api-server/domain"
 	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/logger"
 
 	"github.com/cenkalti/backoff"
 )
 
 type WeatherAPIClient struct {
 	httpClient httpclient.HTTPClient
-	log        *log.Logger
-	apiKey     string
+	log        *slog.Logger
+	apiKey     func() string
+	options
 }
 
-func NewWeatherAPIClient(httpClient httpclient.HTTPClient, log *log.Logger, apiKey string) *WeatherAPIClient {
+// NewWeatherAPIClient reads the API key through apiKey on every call, so a rotated key is
+// picked up without a restart.
+func NewWeatherAPIClient(httpClient httpclient.HTTPClient, log *slog.Logger, apiKey func() string, opts ...Option) *WeatherAPIClient {
 	return &WeatherAPIClient{
 		httpClient: httpClient,
 		log:        log,
 		apiKey:     apiKey,
+		options:    newOptions(opts),
 	}
 }
 
+// StaticKey returns a fixed API key.
+func StaticKey(key string) func() string {
+	return func() string { return key }
+}
+
 func (awc *WeatherAPIClient) getTemperature(ctx context.Context, url string) ([]byte, error) {
 	var resBody []byte
 
-	ebo := backoff.NewExponentialBackOff()
-	ebo.MaxInterval = 1 * time.Second
+	log := logger.FromContext(ctx, awc.log).With(logger.KeyProvider, domain.ProviderHGWeather, "url", httpclient.DefaultRedactor.URL(url))
+
+	attempt := 0
+	if err := backoff.RetryNotify(func() (err error) {
+		attempt++
+		attemptCtx, span := startAttempt(ctx, domain.ProviderHGWeather, attempt)
+		defer func() { endAttempt(span, err) }()
 
-	if err := backoff.Retry(func() error {
+		log := log.With(logger.KeyAttempt, attempt)
+		start := time.Now()
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
 		if err != nil {
-			awc.log.Printf("error to create request to search Temperature through URL: %s. [Error]: %s", url, err.Error())
+			err = httpclient.DefaultRedactor.Error(err)
+			log.ErrorContext(attemptCtx, "Could not create request", logger.Err(err))
 			return err
 		}
 
@@ -48,33 +65,37 @@ func (awc *WeatherAPIClient) getTemperature(ctx context.Context, url string) ([]
 
 		res, err := awc.httpClient.Do(req)
 		if err != nil {
-			awc.log.Printf("error to search Temperature through URL: %s. [Error]: %s", url, err.Error())
-			return err
+			// Os erros do net/http incluem a URL completa, com eventuais chaves de API
+			err = httpclient.DefaultRedactor.Error(err)
+			log.WarnContext(attemptCtx, "Request failed", logger.Duration(time.Since(start)), logger.Err(err))
+			return requestError(err)
 		}
 		defer func() {
-			err = res.Body.Close()
+			err := res.Body.Close()
 			if err != nil {
-				awc.log.Printf("error to close response body from URL: %s. [Error]: %s", url, err.Error())
+				log.WarnContext(attemptCtx, "Could not close response body", logger.Err(err))
 				return
 			}
 		}()
 
 		bodyBytes, err := io.ReadAll(res.Body)
 		if err != nil {
-			awc.log.Printf("error to read response body from URL: %s. [Error]: %s", url, err.Error())
+			log.WarnContext(attemptCtx, "Could not read response body", logger.Duration(time.Since(start)), logger.Err(err))
 			return err
 		}
 
 		if res.StatusCode != 200 {
-			awc.log.Printf("Search Temperature through URL [%s]- API status code %d: %s", url, res.StatusCode, bodyBytes)
-			return err
+			log.WarnContext(attemptCtx, "Unexpected response status", logger.KeyStatus, res.StatusCode,
+				logger.Duration(time.Since(start)), "body", httpclient.DefaultRedactor.String(string(bodyBytes)))
+			return statusError(domain.ProviderHGWeather, res.StatusCode, false)
 		}
 
+		log.DebugContext(attemptCtx, "Request succeeded", logger.KeyStatus, res.StatusCode, logger.Duration(time.Since(start)))
 		resBody = bodyBytes
 		return nil
 
-	}, backoff.WithContext(backoff.WithMaxRetries(ebo, uint64(5)), ctx)); err != nil {
-		awc.log.Printf("error to search Temperature through URL: %s. [Error]: %s", url, err.Error())
+	}, awc.backOff(ctx), awc.notifyRetry(domain.ProviderHGWeather)); err != nil {
+		log.WarnContext(ctx, "Giving up after retries", logger.KeyAttempt, attempt, logger.Err(err))
 		return []byte{}, err
 	}
 
@@ -87,23 +108,24 @@ func (awc *WeatherAPIClient) GetHGWeatherAPI(ctx context.Context, city string) (
 	baseURL := "https://api.hgbrasil.com/weather"
 	params := url.Values{}
 	params.Add("city_name", city)
-	params.Add("key", awc.apiKey)
+	params.Add("key", awc.apiKey())
 	weatherAPIUrl := baseURL + "?" + params.Encode()
 
 	resBody, err := awc.getTemperature(ctx, weatherAPIUrl)
 	if err != nil {
-		awc.log.Printf("error on get info from HG WeatherAPI to the city: %s. [Erro]: %s", city, err.Error())
 		return 0, err
 	}
 
 	if err := json.Unmarshal(resBody, &weatherAPIResponse); err != nil {
-		awc.log.Printf("error to translate response Body to HG WeatherAPI pattern: %s", err.Error())
+		logger.FromContext(ctx, awc.log).ErrorContext(ctx, "Could not decode response",
+			logger.KeyProvider, domain.ProviderHGWeather, logger.KeyCity, city, logger.Err(err))
 		return 0, err
 	}
 
 	if !weatherAPIResponse.ValidKey {
-		awc.log.Printf("invalid API Key provided for HG WeatherAPI")
-		return 0, fmt.Errorf("invalid API Key provided for HG WeatherAPI")
+		logger.FromContext(ctx, awc.log).ErrorContext(ctx, "Invalid API key",
+			logger.KeyProvider, domain.ProviderHGWeather)
+		return 0, fmt.Errorf("invalid API Key provided for HG WeatherAPI: %w", domain.ErrInvalidAPIKey)
 	}
 
 	return weatherAPIResponse.Results.Temp, nil
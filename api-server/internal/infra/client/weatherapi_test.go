@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAPIKey = "s3cr3t-weather-key"
+
+// upstreamTransport routes the HG Weather calls to a test server, failing the "down" city.
+type upstreamTransport struct {
+	host string
+}
+
+func (t upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("city_name") == "down" {
+		return nil, errors.New("connection refused")
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWeatherAPIClientNeverLogsAPIKey(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("city_name") {
+		case "echo":
+			// Upstream ecoando a requisição no corpo do erro
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("bad gateway: GET " + r.URL.String()))
+		case "garbage":
+			_, _ = w.Write([]byte("not json"))
+		case "badkey":
+			_, _ = w.Write([]byte(`{"valid_key":false}`))
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: upstreamTransport{host: strings.TrimPrefix(upstream.URL, "http://")}}
+
+	for _, city := range []string{"down", "echo", "garbage", "badkey", "slow"} {
+		t.Run(city, func(t *testing.T) {
+			var out bytes.Buffer
+			log := logger.New(&out, logger.Options{Level: slog.LevelDebug})
+			client := NewWeatherAPIClient(httpClient, log, StaticKey(testAPIKey))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := client.GetHGWeatherAPI(ctx, city)
+
+			assert.Error(t, err)
+			assert.NotContains(t, err.Error(), testAPIKey)
+			assert.NotEmpty(t, out.String())
+			assert.NotContains(t, out.String(), testAPIKey)
+		})
+	}
+}
+
+func TestWeatherAPIClientRotatedKey(t *testing.T) {
+	var keys []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.URL.Query().Get("key"))
+		_, _ = w.Write([]byte(`{"valid_key":true,"results":{"temp":25}}`))
+	}))
+	defer upstream.Close()
+
+	key := "v1-key"
+	httpClient := &http.Client{Transport: upstreamTransport{host: strings.TrimPrefix(upstream.URL, "http://")}}
+	client := NewWeatherAPIClient(httpClient, logger.Discard(), func() string { return key })
+
+	_, err := client.GetHGWeatherAPI(context.Background(), "Recife")
+	assert.NoError(t, err)
+	key = "v2-key"
+	_, err = client.GetHGWeatherAPI(context.Background(), "Recife")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"v1-key", "v2-key"}, keys)
+}
+
+func TestWeatherAPIClientShuttingDown(t *testing.T) {
+	tracker := &closedTracker{}
+	client := NewWeatherAPIClient(httpclient.NewTrackedHTTPClient(http.DefaultClient, tracker), logger.Discard(),
+		StaticKey(testAPIKey), WithRetries(2, time.Millisecond))
+
+	_, err := client.GetHGWeatherAPI(context.Background(), "Recife")
+
+	assert.ErrorIs(t, err, httpclient.ErrShuttingDown)
+	assert.Equal(t, 1, tracker.starts, "not retried")
+}
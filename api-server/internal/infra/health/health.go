@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api-server/domain"
+	"api-server/pkg/logger"
+)
+
+// Readiness tells whether the service should receive traffic: only after startup
+// and never while draining for shutdown.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r != nil && r.ready.Load()
+}
+
+// ProviderStatus is what is known about an upstream provider from its last calls.
+type ProviderStatus struct {
+	Name          string     `json:"name"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastFailure   *time.Time `json:"last_failure,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastLatencyMS int64      `json:"last_latency_ms"`
+	Successes     uint64     `json:"successes"`
+	Failures      uint64     `json:"failures"`
+	// APIKeyValid is only set for providers that require an API key, once it was checked.
+	APIKeyValid *bool `json:"api_key_valid,omitempty"`
+}
+
+// Healthy is true when the last call observed succeeded.
+func (p ProviderStatus) Healthy() bool {
+	if p.LastFailure == nil {
+		return true
+	}
+	return p.LastSuccess != nil && p.LastSuccess.After(*p.LastFailure)
+}
+
+// Tracker implements domain.ProviderObserver, keeping the status of each provider.
+type Tracker struct {
+	mu        sync.RWMutex
+	providers map[string]*ProviderStatus
+	now       func() time.Time
+}
+
+func NewTracker(providers ...string) *Tracker {
+	t := &Tracker{providers: make(map[string]*ProviderStatus), now: time.Now}
+	for _, p := range providers {
+		t.providers[p] = &ProviderStatus{Name: p}
+	}
+	return t
+}
+
+func (t *Tracker) ObserveProviderCall(_ context.Context, provider string, latency time.Duration, err error) {
+	// Cancelamento pelo chamador (corrida perdida, cliente desconectou) não diz nada sobre o provider
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.providers[provider]
+	if !ok {
+		p = &ProviderStatus{Name: provider}
+		t.providers[provider] = p
+	}
+
+	now := t.now()
+	p.LastLatencyMS = latency.Milliseconds()
+
+	valid := true
+	switch {
+	case err == nil || errors.Is(err, domain.ErrCEPNotFound):
+		p.LastSuccess = &now
+		p.Successes++
+	case errors.Is(err, domain.ErrInvalidAPIKey):
+		valid = false
+		fallthrough
+	default:
+		p.LastFailure = &now
+		p.LastError = err.Error()
+		p.Failures++
+	}
+
+	if provider == domain.ProviderHGWeather && (err == nil || !valid) {
+		p.APIKeyValid = &valid
+	}
+}
+
+// Statuses returns a copy of every provider status sorted by name.
+func (t *Tracker) Statuses() []ProviderStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(t.providers))
+	for _, p := range t.providers {
+		statuses = append(statuses, *p)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Probe is an active check of one provider.
+type Probe struct {
+	Provider string
+	Check    func(ctx context.Context) error
+}
+
+// Prober runs the probes on a schedule, so /status is fresh without probing per request.
+// Probe results reach the Tracker through the instrumented clients the checks call.
+type Prober struct {
+	probes   []Probe
+	interval time.Duration
+	timeout  time.Duration
+	log      *slog.Logger
+}
+
+func NewProber(interval, timeout time.Duration, log *slog.Logger, probes ...Probe) *Prober {
+	return &Prober{
+		probes:   probes,
+		interval: interval,
+		timeout:  timeout,
+		log:      log,
+	}
+}
+
+// Run probes every interval until ctx is done.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probeAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Prober) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, probe := range p.probes {
+		wg.Add(1)
+		go func(probe Probe) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+
+			if err := probe.Check(probeCtx); err != nil {
+				p.log.WarnContext(ctx, "Health probe failed", logger.KeyProvider, probe.Provider, logger.Err(err))
+			}
+		}(probe)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-server/domain"
+	"api-server/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness(t *testing.T) {
+	var r *Readiness
+	assert.False(t, r.Ready())
+
+	r = &Readiness{}
+	assert.False(t, r.Ready())
+	r.SetReady(true)
+	assert.True(t, r.Ready())
+	r.SetReady(false)
+	assert.False(t, r.Ready())
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2024, 8, 10, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(domain.ProviderBrasilAPI, domain.ProviderHGWeather)
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	t.Run("should record successes and failures", func(t *testing.T) {
+		tracker.ObserveProviderCall(ctx, domain.ProviderBrasilAPI, 120*time.Millisecond, nil)
+		now = now.Add(time.Second)
+		tracker.ObserveProviderCall(ctx, domain.ProviderBrasilAPI, 900*time.Millisecond, errors.New("timeout"))
+
+		status := tracker.Statuses()[0]
+		assert.Equal(t, domain.ProviderBrasilAPI, status.Name)
+		assert.Equal(t, uint64(1), status.Successes)
+		assert.Equal(t, uint64(1), status.Failures)
+		assert.Equal(t, int64(900), status.LastLatencyMS)
+		assert.Equal(t, "timeout", status.LastError)
+		assert.False(t, status.Healthy())
+	})
+
+	t.Run("should ignore cancellations and count not found CEPs as success", func(t *testing.T) {
+		now = now.Add(time.Second)
+		tracker.ObserveProviderCall(ctx, domain.ProviderBrasilAPI, time.Millisecond, context.Canceled)
+		assert.False(t, tracker.Statuses()[0].Healthy())
+
+		tracker.ObserveProviderCall(ctx, domain.ProviderBrasilAPI, time.Millisecond, fmt.Errorf("x: %w", domain.ErrCEPNotFound))
+		assert.True(t, tracker.Statuses()[0].Healthy())
+	})
+
+	t.Run("should track the weather API key validity", func(t *testing.T) {
+		assert.Nil(t, tracker.Statuses()[1].APIKeyValid)
+
+		tracker.ObserveProviderCall(ctx, domain.ProviderHGWeather, time.Millisecond, fmt.Errorf("x: %w", domain.ErrInvalidAPIKey))
+		require.NotNil(t, tracker.Statuses()[1].APIKeyValid)
+		assert.False(t, *tracker.Statuses()[1].APIKeyValid)
+
+		tracker.ObserveProviderCall(ctx, domain.ProviderHGWeather, time.Millisecond, errors.New("timeout"))
+		assert.False(t, *tracker.Statuses()[1].APIKeyValid)
+
+		tracker.ObserveProviderCall(ctx, domain.ProviderHGWeather, time.Millisecond, nil)
+		assert.True(t, *tracker.Statuses()[1].APIKeyValid)
+	})
+}
+
+func TestProber(t *testing.T) {
+	var calls atomic.Int32
+	prober := NewProber(10*time.Millisecond, time.Second, logger.Discard(),
+		Probe{Provider: "a", Check: func(ctx context.Context) error { calls.Add(1); return nil }},
+		Probe{Provider: "b", Check: func(ctx context.Context) error { calls.Add(1); return errors.New("down") }},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	prober.Run(ctx)
+
+	assert.GreaterOrEqual(t, calls.Load(), int32(4))
+}
@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	jobFile     = "job.json"
+	inputsFile  = "inputs.json"
+	resultsFile = "results.ndjson"
+)
+
+// FileStore keeps every job in a directory of its own, so the jobs survive a restart:
+// job.json, inputs.json and results.ndjson, where results are appended as they come.
+type FileStore struct {
+	dir  string
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// NewFileStore opens the store in dir, creating it when missing.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{dir: dir, keys: make(map[string]string)}
+	jobs, err := s.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.IdempotencyKey != "" {
+			s.keys[idempotencyIndex(job.Owner, job.IdempotencyKey)] = job.ID
+		}
+		if !job.Status.Done() {
+			if err := s.repairResults(job.ID); err != nil {
+				return nil, fmt.Errorf("job %s: %w", job.ID, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *FileStore) path(id, name string) string {
+	return filepath.Join(s.dir, id, name)
+}
+
+func (s *FileStore) Create(ctx context.Context, job Job, inputs []string) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.IdempotencyKey != "" {
+		if id, ok := s.keys[idempotencyIndex(job.Owner, job.IdempotencyKey)]; ok {
+			existing, err := s.Get(ctx, id)
+			if err != nil {
+				return Job{}, false, err
+			}
+			return checkIdempotency(existing, job.RequestHash)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, job.ID), 0o750); err != nil {
+		return Job{}, false, err
+	}
+	if err := writeJSON(s.path(job.ID, inputsFile), inputs); err != nil {
+		return Job{}, false, err
+	}
+	if err := writeJSON(s.path(job.ID, jobFile), job); err != nil {
+		return Job{}, false, err
+	}
+	if job.IdempotencyKey != "" {
+		s.keys[idempotencyIndex(job.Owner, job.IdempotencyKey)] = job.ID
+	}
+	return job, true, nil
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (Job, error) {
+	var job Job
+	if err := readJSON(s.path(filepath.Base(id), jobFile), &job); err != nil {
+		return Job{}, err
+	}
+	return job, nil
+}
+
+func (s *FileStore) Update(_ context.Context, job Job) error {
+	if _, err := os.Stat(s.path(job.ID, jobFile)); err != nil {
+		return notFound(err)
+	}
+	return writeJSON(s.path(job.ID, jobFile), job)
+}
+
+func (s *FileStore) Inputs(_ context.Context, id string) ([]string, error) {
+	var inputs []string
+	if err := readJSON(s.path(filepath.Base(id), inputsFile), &inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (s *FileStore) AppendResults(_ context.Context, id string, results []Result) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.path(id, resultsFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return notFound(err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FileStore) Results(ctx context.Context, id string, fn func(Result) error) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	f, err := os.Open(s.path(filepath.Base(id), resultsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Uma linha sem \n ainda está sendo escrita
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var result Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			return fmt.Errorf("job %s: %w", id, err)
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		job, err := s.Get(ctx, e.Name())
+		if errors.Is(err, ErrNotFound) {
+			// Criação interrompida antes do job.json
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if job.IdempotencyKey != "" {
+		delete(s.keys, idempotencyIndex(job.Owner, job.IdempotencyKey))
+	}
+	return os.RemoveAll(filepath.Join(s.dir, job.ID))
+}
+
+// repairResults drops the line a crash left half written, so the next results start on a line of their own.
+func (s *FileStore) repairResults(id string) error {
+	content, err := os.ReadFile(s.path(id, resultsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(content) == 0 || content[len(content)-1] == '\n' {
+		return nil
+	}
+	return os.Truncate(s.path(id, resultsFile), int64(bytes.LastIndexByte(content, '\n')+1))
+}
+
+// writeJSON replaces the file atomically, so readers never see it half written.
+func writeJSON(path string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSON(path string, v any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return notFound(err)
+	}
+	return json.Unmarshal(content, v)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
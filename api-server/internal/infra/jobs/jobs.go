@@ -0,0 +1,110 @@
+// Package jobs runs large batches of CEP lookups in background, storing their progress and results.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether the job reached a final status.
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+var (
+	ErrNotFound            = errors.New("job not found")
+	ErrIdempotencyConflict = errors.New("idempotency key already used with a different request")
+	ErrFinished            = errors.New("job already finished")
+	ErrQueueFull           = errors.New("job queue is full")
+)
+
+type Job struct {
+	ID             string     `json:"id"`
+	Owner          string     `json:"owner,omitempty"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	RequestHash    string     `json:"request_hash"`
+	Status         Status     `json:"status"`
+	Total          int        `json:"total"`
+	Processed      int        `json:"processed"`
+	Failed         int        `json:"failed"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Result is the outcome of one CEP of the job, Index being its position in the request.
+type Result struct {
+	Index   int      `json:"index"`
+	Input   string   `json:"input"`
+	CEP     string   `json:"cep,omitempty"`
+	City    string   `json:"city,omitempty"`
+	Celsius *float64 `json:"celsius,omitempty"`
+	Error   *Error   `json:"error,omitempty"`
+}
+
+// Error is the problem the CEP would have got from a single lookup.
+type Error struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Store keeps the jobs, their inputs and their results.
+type Store interface {
+	// Create saves a new job with its inputs, unless its owner already created one with the same
+	// idempotency key: that job is returned instead, with created false.
+	Create(ctx context.Context, job Job, inputs []string) (stored Job, created bool, err error)
+	Get(ctx context.Context, id string) (Job, error)
+	Update(ctx context.Context, job Job) error
+	Inputs(ctx context.Context, id string) ([]string, error)
+	AppendResults(ctx context.Context, id string, results []Result) error
+	// Results calls fn with every result stored so far, in order.
+	Results(ctx context.Context, id string, fn func(Result) error) error
+	List(ctx context.Context) ([]Job, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Processor looks up the CEPs of a job, one chunk at a time. A processor is created per job and
+// called sequentially, so it may keep what earlier chunks fetched, e.g. the temperature of a city.
+type Processor interface {
+	Process(ctx context.Context, inputs []string) []Result
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestHash tells apart two requests reusing an idempotency key.
+func requestHash(inputs []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func idempotencyIndex(owner, key string) string {
+	return owner + "\x00" + key
+}
+
+func checkIdempotency(existing Job, hash string) (Job, bool, error) {
+	if existing.RequestHash != hash {
+		return Job{}, false, ErrIdempotencyConflict
+	}
+	return existing, false, nil
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryJob struct {
+	job     Job
+	inputs  []string
+	results []Result
+}
+
+// MemoryStore keeps the jobs in the process: they are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*memoryJob
+	keys map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*memoryJob),
+		keys: make(map[string]string),
+	}
+}
+
+func (m *MemoryStore) Create(_ context.Context, job Job, inputs []string) (Job, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job.IdempotencyKey != "" {
+		if id, ok := m.keys[idempotencyIndex(job.Owner, job.IdempotencyKey)]; ok {
+			return checkIdempotency(m.jobs[id].job, job.RequestHash)
+		}
+		m.keys[idempotencyIndex(job.Owner, job.IdempotencyKey)] = job.ID
+	}
+	m.jobs[job.ID] = &memoryJob{job: job, inputs: append([]string(nil), inputs...)}
+	return job, true, nil
+}
+
+func (m *MemoryStore) Get(_ context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j.job, nil
+}
+
+func (m *MemoryStore) Update(_ context.Context, job Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[job.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	j.job = job
+	return nil
+}
+
+func (m *MemoryStore) Inputs(_ context.Context, id string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return j.inputs, nil
+}
+
+func (m *MemoryStore) AppendResults(_ context.Context, id string, results []Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	j.results = append(j.results, results...)
+	return nil
+}
+
+func (m *MemoryStore) Results(_ context.Context, id string, fn func(Result) error) error {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	var results []Result
+	if ok {
+		// Os resultados só crescem: a cópia do slice basta para ler sem o lock
+		results = j.results[:len(j.results):len(j.results)]
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	for _, r := range results {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) List(_ context.Context) ([]Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.job)
+	}
+	return jobs, nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil
+	}
+	if j.job.IdempotencyKey != "" {
+		delete(m.keys, idempotencyIndex(j.job.Owner, j.job.IdempotencyKey))
+	}
+	delete(m.jobs, id)
+	return nil
+}
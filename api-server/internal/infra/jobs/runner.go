@@ -0,0 +1,376 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"api-server/pkg/logger"
+	"api-server/pkg/ratelimit"
+)
+
+const (
+	defaultChunkSize   = 100
+	defaultQueueSize   = 1000
+	defaultConcurrency = 1
+	throttleKey        = "jobs"
+)
+
+// Runner queues the jobs and processes them in background, a chunk at a time.
+type Runner struct {
+	store        Store
+	newProcessor func() Processor
+	log          *slog.Logger
+	queue        chan string
+	limiter      *ratelimit.MemoryStore
+	now          func() time.Time
+
+	chunkSize   int
+	concurrency int
+	throttle    ratelimit.Limit
+	retention   time.Duration
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+type Option func(*Runner)
+
+// WithConcurrency sets how many jobs run at the same time.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) {
+		r.concurrency = n
+	}
+}
+
+// WithThrottle bounds the CEPs looked up per second by all the jobs together, leaving the
+// upstream providers to the interactive requests.
+func WithThrottle(limit ratelimit.Limit) Option {
+	return func(r *Runner) {
+		r.throttle = limit
+	}
+}
+
+// WithRetention deletes the finished jobs once they are older than d.
+func WithRetention(d time.Duration) Option {
+	return func(r *Runner) {
+		r.retention = d
+	}
+}
+
+// WithQueueSize bounds the jobs waiting to run; Submit fails with ErrQueueFull beyond it.
+func WithQueueSize(n int) Option {
+	return func(r *Runner) {
+		r.queue = make(chan string, n)
+	}
+}
+
+func NewRunner(store Store, newProcessor func() Processor, log *slog.Logger, opts ...Option) *Runner {
+	r := &Runner{
+		store:        store,
+		newProcessor: newProcessor,
+		log:          log,
+		queue:        make(chan string, defaultQueueSize),
+		limiter:      ratelimit.NewMemoryStore(),
+		now:          time.Now,
+		chunkSize:    defaultChunkSize,
+		concurrency:  defaultConcurrency,
+		cancels:      make(map[string]context.CancelFunc),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Submit creates a job for inputs and queues it. With an idempotency key already used by the
+// owner, the existing job is returned with created false.
+func (r *Runner) Submit(ctx context.Context, owner, idempotencyKey string, inputs []string) (Job, bool, error) {
+	job, created, err := r.store.Create(ctx, Job{
+		ID:             newID(),
+		Owner:          owner,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    requestHash(inputs),
+		Status:         StatusQueued,
+		Total:          len(inputs),
+		CreatedAt:      r.now().UTC(),
+	}, inputs)
+	if err != nil || !created {
+		return job, created, err
+	}
+
+	select {
+	case r.queue <- job.ID:
+		return job, true, nil
+	default:
+		_ = r.store.Delete(ctx, job.ID)
+		return Job{}, false, ErrQueueFull
+	}
+}
+
+// Get returns the job when it belongs to owner.
+func (r *Runner) Get(ctx context.Context, owner, id string) (Job, error) {
+	job, err := r.store.Get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Owner != owner {
+		return Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+// Results streams the results stored so far of a job of owner.
+func (r *Runner) Results(ctx context.Context, owner, id string, fn func(Result) error) error {
+	if _, err := r.Get(ctx, owner, id); err != nil {
+		return err
+	}
+	return r.store.Results(ctx, id, fn)
+}
+
+// Cancel stops a queued or running job. The results stored so far are kept.
+func (r *Runner) Cancel(ctx context.Context, owner, id string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, err := r.Get(ctx, owner, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Status.Done() {
+		return job, ErrFinished
+	}
+
+	now := r.now().UTC()
+	job.Status = StatusCanceled
+	job.FinishedAt = &now
+	if err := r.store.Update(ctx, job); err != nil {
+		return Job{}, err
+	}
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+	}
+	return job, nil
+}
+
+// Run processes the queue until ctx is done. The jobs left running by a previous process are
+// resumed where they stopped; a job interrupted by shutdown stays running to be resumed in turn.
+func (r *Runner) Run(ctx context.Context) error {
+	if err := r.recover(ctx); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-r.queue:
+					r.process(ctx, id)
+				}
+			}
+		}()
+	}
+
+	if r.retention > 0 {
+		ticker := time.NewTicker(min(r.retention, time.Hour))
+		defer ticker.Stop()
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+				r.expire(ctx)
+			}
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (r *Runner) recover(ctx context.Context) error {
+	jobs, err := r.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Status.Done() {
+			continue
+		}
+		select {
+		case r.queue <- job.ID:
+			r.log.Info("Job resumed", "job_id", job.ID, "processed", job.Processed, "total", job.Total)
+		default:
+			r.log.Warn("Job not resumed: queue full", "job_id", job.ID)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) expire(ctx context.Context) {
+	jobs, err := r.store.List(ctx)
+	if err != nil {
+		r.log.Warn("Could not list jobs", logger.Err(err))
+		return
+	}
+	for _, job := range jobs {
+		if job.Status.Done() && job.FinishedAt != nil && r.now().Sub(*job.FinishedAt) > r.retention {
+			if err := r.store.Delete(ctx, job.ID); err != nil {
+				r.log.Warn("Could not delete expired job", "job_id", job.ID, logger.Err(err))
+			}
+		}
+	}
+}
+
+func (r *Runner) process(ctx context.Context, id string) {
+	log := r.log.With("job_id", id)
+
+	job, err := r.store.Get(ctx, id)
+	if err != nil || job.Status.Done() {
+		return
+	}
+	inputs, err := r.store.Inputs(ctx, id)
+	if err != nil {
+		r.fail(ctx, job, err)
+		return
+	}
+
+	// Os resultados gravados valem mais que o progresso salvo, que pode ter ficado para trás num crash
+	job.Processed, job.Failed = 0, 0
+	if err := r.store.Results(ctx, id, func(res Result) error {
+		job.Processed++
+		if res.Error != nil {
+			job.Failed++
+		}
+		return nil
+	}); err != nil {
+		r.fail(ctx, job, err)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+	}()
+
+	job.Status = StatusRunning
+	if job.StartedAt == nil {
+		now := r.now().UTC()
+		job.StartedAt = &now
+	}
+	if !r.save(ctx, job) {
+		return
+	}
+	log.Info("Job started", "total", job.Total, "processed", job.Processed)
+
+	processor := r.newProcessor()
+	for job.Processed < len(inputs) {
+		end := min(job.Processed+r.chunkSize, len(inputs))
+		if err := r.wait(jobCtx, end-job.Processed); err != nil {
+			break
+		}
+
+		results := processor.Process(jobCtx, inputs[job.Processed:end])
+		if jobCtx.Err() != nil {
+			// O chunk interrompido é refeito quando o job for retomado
+			break
+		}
+		for i := range results {
+			results[i].Index = job.Processed + i
+			if results[i].Error != nil {
+				job.Failed++
+			}
+		}
+		if err := r.store.AppendResults(ctx, id, results); err != nil {
+			r.fail(ctx, job, err)
+			return
+		}
+		job.Processed = end
+		if !r.save(ctx, job) {
+			return
+		}
+	}
+
+	if jobCtx.Err() != nil {
+		if ctx.Err() != nil {
+			log.Info("Job interrupted by shutdown", "processed", job.Processed, "total", job.Total)
+		} else {
+			log.Info("Job canceled", "processed", job.Processed, "total", job.Total)
+		}
+		return
+	}
+
+	now := r.now().UTC()
+	job.Status = StatusSucceeded
+	job.FinishedAt = &now
+	if r.save(ctx, job) {
+		log.Info("Job finished", "total", job.Total, "failed", job.Failed, logger.Duration(now.Sub(*job.StartedAt)))
+	}
+}
+
+// save stores the progress unless the job was canceled meanwhile, reporting whether it goes on.
+func (r *Runner) save(ctx context.Context, job Job) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.store.Get(ctx, job.ID)
+	if err == nil && current.Status == StatusCanceled {
+		return false
+	}
+	if err == nil {
+		err = r.store.Update(ctx, job)
+	}
+	if err != nil {
+		r.log.Error("Could not save job", "job_id", job.ID, logger.Err(err))
+		return false
+	}
+	return true
+}
+
+func (r *Runner) fail(ctx context.Context, job Job, err error) {
+	r.log.Error("Job failed", "job_id", job.ID, logger.Err(err))
+	now := r.now().UTC()
+	job.Status = StatusFailed
+	job.Error = err.Error()
+	job.FinishedAt = &now
+	r.save(ctx, job)
+}
+
+// wait takes n tokens of the throttle, sleeping while it is empty.
+func (r *Runner) wait(ctx context.Context, n int) error {
+	if !r.throttle.Enabled() {
+		return ctx.Err()
+	}
+	for n > 0 {
+		res, err := r.limiter.Take(ctx, throttleKey, r.throttle)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			n--
+			continue
+		}
+
+		timer := time.NewTimer(res.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"api-server/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processorFunc looks up every input with fn, failing the inputs it returns an error for.
+type processorFunc func(ctx context.Context, input string) (float64, *Error)
+
+func (f processorFunc) Process(ctx context.Context, inputs []string) []Result {
+	results := make([]Result, len(inputs))
+	for i, input := range inputs {
+		results[i].Input = input
+		celsius, err := f(ctx, input)
+		if err != nil {
+			results[i].Error = err
+			continue
+		}
+		results[i].Celsius = &celsius
+	}
+	return results
+}
+
+func startRunner(t *testing.T, store Store, fn processorFunc, opts ...Option) *Runner {
+	t.Helper()
+	r := NewRunner(store, func() Processor { return fn }, slog.Default(), opts...)
+	r.chunkSize = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, r.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return r
+}
+
+func waitStatus(t *testing.T, r *Runner, owner, id string, status Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = r.Get(context.Background(), owner, id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond, "job never got %s", status)
+	return job
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should process the job in chunks and keep the results in order", func(t *testing.T) {
+		r := startRunner(t, NewMemoryStore(), func(ctx context.Context, input string) (float64, *Error) {
+			if strings.HasPrefix(input, "9") {
+				return 0, &Error{Status: 404, Code: "cep_not_found"}
+			}
+			return 25, nil
+		})
+
+		job, created, err := r.Submit(ctx, "erp", "", []string{"01001000", "99999999", "20040020", "01310100", "30130000"})
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		job = waitStatus(t, r, "erp", job.ID, StatusSucceeded)
+		assert.Equal(t, 5, job.Processed)
+		assert.Equal(t, 1, job.Failed)
+		assert.NotNil(t, job.StartedAt)
+		assert.NotNil(t, job.FinishedAt)
+
+		var indexes []int
+		require.NoError(t, r.Results(ctx, "erp", job.ID, func(res Result) error {
+			indexes = append(indexes, res.Index)
+			return nil
+		}))
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, indexes)
+
+		_, err = r.Get(ctx, "crm", job.ID)
+		assert.ErrorIs(t, err, ErrNotFound, "jobs are visible to their owner only")
+		_, err = r.Cancel(ctx, "erp", job.ID)
+		assert.ErrorIs(t, err, ErrFinished)
+	})
+
+	t.Run("should stop a canceled job and keep its results", func(t *testing.T) {
+		release := make(chan struct{})
+		r := startRunner(t, NewMemoryStore(), func(ctx context.Context, input string) (float64, *Error) {
+			if input == "blocked" {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}
+			return 25, nil
+		})
+
+		job, _, err := r.Submit(ctx, "", "", []string{"01001000", "20040020", "blocked", "01310100"})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			job, _ = r.Get(ctx, "", job.ID)
+			return job.Processed == 2
+		}, 2*time.Second, 5*time.Millisecond)
+
+		job, err = r.Cancel(ctx, "", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCanceled, job.Status)
+		close(release)
+
+		time.Sleep(20 * time.Millisecond)
+		job = waitStatus(t, r, "", job.ID, StatusCanceled)
+		assert.Equal(t, 2, job.Processed)
+	})
+
+	t.Run("should resume the jobs left unfinished", func(t *testing.T) {
+		store, err := NewFileStore(t.TempDir())
+		require.NoError(t, err)
+		inputs := []string{"01001000", "20040020", "01310100"}
+		job, _, err := store.Create(ctx, newJob("", "", inputs), inputs)
+		require.NoError(t, err)
+		job.Status = StatusRunning
+		require.NoError(t, store.Update(ctx, job))
+		require.NoError(t, store.AppendResults(ctx, job.ID, []Result{{Index: 0, Input: "01001000"}}))
+
+		var mu sync.Mutex
+		var looked []string
+		r := startRunner(t, store, func(ctx context.Context, input string) (float64, *Error) {
+			mu.Lock()
+			looked = append(looked, input)
+			mu.Unlock()
+			return 25, nil
+		})
+
+		job = waitStatus(t, r, "", job.ID, StatusSucceeded)
+		assert.Equal(t, 3, job.Processed)
+		mu.Lock()
+		assert.Equal(t, []string{"20040020", "01310100"}, looked)
+		mu.Unlock()
+	})
+
+	t.Run("should refuse jobs beyond the queue", func(t *testing.T) {
+		r := NewRunner(NewMemoryStore(), nil, slog.Default(), WithQueueSize(1))
+		_, _, err := r.Submit(ctx, "", "", []string{"01001000"})
+		require.NoError(t, err)
+		_, _, err = r.Submit(ctx, "", "", []string{"01001000"})
+		assert.ErrorIs(t, err, ErrQueueFull)
+	})
+
+	t.Run("should throttle the lookups", func(t *testing.T) {
+		r := startRunner(t, NewMemoryStore(), func(ctx context.Context, input string) (float64, *Error) {
+			return 25, nil
+		}, WithThrottle(ratelimit.Limit{Rate: 50, Burst: 1}))
+
+		start := time.Now()
+		job, _, err := r.Submit(ctx, "", "", []string{"1", "2", "3", "4", "5", "6"})
+		require.NoError(t, err)
+		waitStatus(t, r, "", job.ID, StatusSucceeded)
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+}
@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJob(owner, key string, inputs []string) Job {
+	return Job{
+		ID:             newID(),
+		Owner:          owner,
+		IdempotencyKey: key,
+		RequestHash:    requestHash(inputs),
+		Status:         StatusQueued,
+		Total:          len(inputs),
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			return NewMemoryStore()
+		},
+		"file": func(t *testing.T) Store {
+			s, err := NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("should keep the job, its inputs and its results", func(t *testing.T) {
+				s := newStore(t)
+				inputs := []string{"01001000", "20040020"}
+				job, created, err := s.Create(ctx, newJob("erp", "", inputs), inputs)
+				require.NoError(t, err)
+				assert.True(t, created)
+
+				got, err := s.Get(ctx, job.ID)
+				require.NoError(t, err)
+				assert.Equal(t, job, got)
+
+				stored, err := s.Inputs(ctx, job.ID)
+				require.NoError(t, err)
+				assert.Equal(t, inputs, stored)
+
+				celsius := 25.0
+				require.NoError(t, s.AppendResults(ctx, job.ID, []Result{{Index: 0, Input: "01001000", Celsius: &celsius}}))
+				require.NoError(t, s.AppendResults(ctx, job.ID, []Result{{Index: 1, Input: "20040020", Error: &Error{Status: 404, Code: "cep_not_found"}}}))
+
+				var results []Result
+				require.NoError(t, s.Results(ctx, job.ID, func(r Result) error {
+					results = append(results, r)
+					return nil
+				}))
+				require.Len(t, results, 2)
+				assert.Equal(t, 25.0, *results[0].Celsius)
+				assert.Equal(t, "cep_not_found", results[1].Error.Code)
+
+				job.Status = StatusRunning
+				job.Processed = 2
+				require.NoError(t, s.Update(ctx, job))
+				got, _ = s.Get(ctx, job.ID)
+				assert.Equal(t, 2, got.Processed)
+
+				jobs, err := s.List(ctx)
+				require.NoError(t, err)
+				assert.Len(t, jobs, 1)
+
+				require.NoError(t, s.Delete(ctx, job.ID))
+				_, err = s.Get(ctx, job.ID)
+				assert.ErrorIs(t, err, ErrNotFound)
+				assert.ErrorIs(t, s.Update(ctx, job), ErrNotFound)
+			})
+
+			t.Run("should return the job created with the same idempotency key", func(t *testing.T) {
+				s := newStore(t)
+				inputs := []string{"01001000"}
+				first, _, err := s.Create(ctx, newJob("erp", "key-1", inputs), inputs)
+				require.NoError(t, err)
+
+				again, created, err := s.Create(ctx, newJob("erp", "key-1", inputs), inputs)
+				require.NoError(t, err)
+				assert.False(t, created)
+				assert.Equal(t, first.ID, again.ID)
+
+				_, _, err = s.Create(ctx, newJob("erp", "key-1", []string{"20040020"}), []string{"20040020"})
+				assert.ErrorIs(t, err, ErrIdempotencyConflict)
+
+				// A chave é por dono
+				other, created, err := s.Create(ctx, newJob("crm", "key-1", inputs), inputs)
+				require.NoError(t, err)
+				assert.True(t, created)
+				assert.NotEqual(t, first.ID, other.ID)
+			})
+		})
+	}
+}
+
+func TestFileStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+	inputs := []string{"01001000", "20040020"}
+	job, _, err := s.Create(ctx, newJob("", "key-1", inputs), inputs)
+	require.NoError(t, err)
+	require.NoError(t, s.AppendResults(ctx, job.ID, []Result{{Index: 0, Input: "01001000"}}))
+
+	// Crash no meio da escrita de um resultado
+	f, err := os.OpenFile(filepath.Join(dir, job.ID, resultsFile), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"index":1,"inp`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = NewFileStore(dir)
+	require.NoError(t, err)
+
+	_, created, err := s.Create(ctx, newJob("", "key-1", inputs), inputs)
+	require.NoError(t, err)
+	assert.False(t, created, "idempotency keys survive a restart")
+
+	require.NoError(t, s.AppendResults(ctx, job.ID, []Result{{Index: 1, Input: "20040020"}}))
+	var indexes []int
+	require.NoError(t, s.Results(ctx, job.ID, func(r Result) error {
+		indexes = append(indexes, r.Index)
+		return nil
+	}))
+	assert.Equal(t, []int{0, 1}, indexes)
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-server/pkg/circuitbreaker"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "api_server"
+
+// Metrics holds the Prometheus collectors of the service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	httpInFlight     prometheus.Gauge
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	raceWins         *prometheus.CounterVec
+	retries          *prometheus.CounterVec
+	reloads          *prometheus.CounterVec
+	lastReload       prometheus.Gauge
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests served, by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "HTTP calls to upstream providers, by provider and outcome (status code class or error).",
+		}, []string{"provider", "outcome"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Latency of the HTTP calls to upstream providers, by provider and outcome.",
+			Buckets:   []float64{.025, .05, .1, .25, .5, .75, 1, 2.5, 5},
+		}, []string{"provider", "outcome"}),
+		raceWins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cep_race_wins_total",
+			Help:      "CEP lookups answered first by each provider.",
+		}, []string{"provider"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_retries_total",
+			Help:      "Retries of calls to upstream providers by the backoff loops.",
+		}, []string{"provider"}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reloads_total",
+			Help:      "Configuration reloads, by result (success or failure).",
+		}, []string{"result"}),
+		lastReload: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Unix time of the last successful configuration reload, or of the start.",
+		}),
+	}
+	m.lastReload.SetToCurrentTime()
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.httpInFlight,
+		m.upstreamRequests, m.upstreamDuration, m.raceWins, m.retries,
+		m.reloads, m.lastReload,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted counts a request in flight and returns the func that records its outcome.
+func (m *Metrics) RequestStarted() func(route, method string, status int, elapsed time.Duration) {
+	m.httpInFlight.Inc()
+	return func(route, method string, status int, elapsed time.Duration) {
+		m.httpInFlight.Dec()
+		code := strconv.Itoa(status)
+		m.httpRequests.WithLabelValues(route, method, code).Inc()
+		m.httpDuration.WithLabelValues(route, method, code).Observe(elapsed.Seconds())
+	}
+}
+
+func (m *Metrics) ObserveRetry(provider string) {
+	m.retries.WithLabelValues(provider).Inc()
+}
+
+func (m *Metrics) ObserveRaceWinner(provider string) {
+	m.raceWins.WithLabelValues(provider).Inc()
+}
+
+func (m *Metrics) ObserveReload(success bool) {
+	if !success {
+		m.reloads.WithLabelValues("failure").Inc()
+		return
+	}
+	m.reloads.WithLabelValues("success").Inc()
+	m.lastReload.SetToCurrentTime()
+}
+
+// RegisterBreakers exports the state of every breaker of the registry,
+// read at scrape time so open breakers show up as half-open once cooled down.
+func (m *Metrics) RegisterBreakers(breakers *circuitbreaker.Registry) {
+	m.registry.MustRegister(&breakerCollector{
+		breakers: breakers,
+		state: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "circuit_breaker_state"),
+			"State of the circuit breaker of each provider: 0 closed, 1 half-open, 2 open.",
+			[]string{"provider"}, nil),
+	})
+}
+
+type breakerCollector struct {
+	breakers *circuitbreaker.Registry
+	state    *prometheus.Desc
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.breakers.Snapshots() {
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, breakerStateValue(s.State), s.Name)
+	}
+}
+
+func breakerStateValue(state string) float64 {
+	switch state {
+	case circuitbreaker.StateHalfOpen.String():
+		return 1
+	case circuitbreaker.StateOpen.String():
+		return 2
+	default:
+		return 0
+	}
+}
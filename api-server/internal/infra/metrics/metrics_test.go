@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-server/pkg/circuitbreaker"
+	httpclient "api-server/pkg/http_client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetrics_RoundTripper(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	m := New()
+	client := httpclient.NewHTTPClient(time.Second, m.RoundTripper(func(host string) string {
+		if host == "127.0.0.1" {
+			return "BrasilAPI"
+		}
+		return ""
+	}))
+
+	for _, path := range []string{"/ok", "/ok", "/missing"} {
+		res, err := client.Get(upstream.URL + path)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+	_, err := client.Get("http://unknown.invalid/")
+	require.Error(t, err)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `api_server_upstream_requests_total{outcome="2xx",provider="BrasilAPI"} 2`)
+	assert.Contains(t, body, `api_server_upstream_requests_total{outcome="4xx",provider="BrasilAPI"} 1`)
+	assert.Contains(t, body, `api_server_upstream_requests_total{outcome="error",provider="other"} 1`)
+	assert.Contains(t, body, `api_server_upstream_request_duration_seconds_count{outcome="2xx",provider="BrasilAPI"} 2`)
+}
+
+func TestMetrics_Observers(t *testing.T) {
+	m := New()
+	m.ObserveRaceWinner("ViaCEP")
+	m.ObserveRaceWinner("ViaCEP")
+	m.ObserveRetry("HGWeather")
+	m.ObserveReload(true)
+	m.ObserveReload(false)
+
+	done := m.RequestStarted()
+	assert.Contains(t, scrape(t, m), "api_server_http_requests_in_flight 1")
+	done("/v1/tempForCep/:cep", "GET", 200, 30*time.Millisecond)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `api_server_cep_race_wins_total{provider="ViaCEP"} 2`)
+	assert.Contains(t, body, `api_server_upstream_retries_total{provider="HGWeather"} 1`)
+	assert.Contains(t, body, `api_server_config_reloads_total{result="success"} 1`)
+	assert.Contains(t, body, `api_server_config_reloads_total{result="failure"} 1`)
+	assert.Contains(t, body, "api_server_config_last_reload_success_timestamp_seconds")
+	assert.Contains(t, body, "api_server_http_requests_in_flight 0")
+	assert.Contains(t, body, `api_server_http_requests_total{method="GET",route="/v1/tempForCep/:cep",status="200"} 1`)
+}
+
+func TestMetrics_RegisterBreakers(t *testing.T) {
+	breakers := circuitbreaker.NewRegistry()
+	breakers.Add(circuitbreaker.Settings{Name: "BrasilAPI"})
+	viaCEP := breakers.Add(circuitbreaker.Settings{Name: "ViaCEP", CoolDown: time.Minute})
+	_ = viaCEP.Execute(func() error { return errors.New("timeout") })
+
+	m := New()
+	m.RegisterBreakers(breakers)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `api_server_circuit_breaker_state{provider="BrasilAPI"} 0`)
+	assert.Contains(t, body, `api_server_circuit_breaker_state{provider="ViaCEP"} 2`)
+}
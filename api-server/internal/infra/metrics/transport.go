@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	httpclient "api-server/pkg/http_client"
+)
+
+// RoundTripper instruments the calls of next by provider; providerFor maps the request
+// host to a provider name. Unknown hosts are reported as "other".
+func (m *Metrics) RoundTripper(providerFor func(host string) string) httpclient.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			provider := providerFor(req.URL.Hostname())
+			if provider == "" {
+				provider = "other"
+			}
+
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+
+			outcome := "error"
+			if err == nil {
+				outcome = statusClass(res.StatusCode)
+			}
+			m.upstreamRequests.WithLabelValues(provider, outcome).Inc()
+			m.upstreamDuration.WithLabelValues(provider, outcome).Observe(time.Since(start).Seconds())
+
+			return res, err
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func statusClass(status int) string {
+	switch {
+	case status >= 500:
+		return "5xx"
+	case status >= 400:
+		return "4xx"
+	case status >= 300:
+		return "3xx"
+	default:
+		return "2xx"
+	}
+}
@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/pkg/apikey"
+	"api-server/pkg/logger"
+	temperaturev1 "api-server/pkg/pb/temperature/v1"
+	"api-server/pkg/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metadataAPIKey carries the API key, as the X-API-Key header does over HTTP.
+const metadataAPIKey = "x-api-key"
+
+type Option func(*Server)
+
+// WithAPIKeys requires a valid API key in the x-api-key metadata of the temperature service calls
+// and counts each call, or each Watch opened, against the key's daily quota.
+func WithAPIKeys(keys *apikey.Store) Option {
+	return func(s *Server) {
+		s.apiKeys = keys
+	}
+}
+
+// WithRateLimit limits the temperature service calls per API key (or peer IP for anonymous callers)
+// with the same store and policy as the HTTP server, so both count against one bucket.
+func WithRateLimit(store ratelimit.Store, policy *ratelimit.DynamicPolicy) Option {
+	return func(s *Server) {
+		s.limiter = store
+		s.policy = policy
+	}
+}
+
+func (s *Server) admitUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	header, err := s.admit(ctx, info.FullMethod)
+	if len(header) > 0 {
+		_ = grpc.SetHeader(ctx, header)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) admitStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	header, err := s.admit(ss.Context(), info.FullMethod)
+	if len(header) > 0 {
+		_ = ss.SetHeader(header)
+	}
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// admit applies to a temperature service call the API key, rate limit and quota checks of the
+// HTTP routes. Health checks and reflection are left open. header is sent back to the caller.
+func (s *Server) admit(ctx context.Context, fullMethod string) (metadata.MD, error) {
+	if !strings.HasPrefix(fullMethod, "/"+temperaturev1.TemperatureService_ServiceDesc.ServiceName+"/") {
+		return nil, nil
+	}
+
+	plain := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(metadataAPIKey); len(values) > 0 {
+			plain = values[0]
+		}
+	}
+
+	var key apikey.Key
+	authenticated := false
+	if s.apiKeys != nil {
+		var err error
+		if key, err = s.apiKeys.Authenticate(plain); err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing api key")
+		}
+		if !key.Allows(fullMethod) {
+			s.log.WarnContext(ctx, "API key not allowed on method", "api_key", key.Name, "method", fullMethod)
+			return nil, status.Error(codes.PermissionDenied, "method not allowed for api key")
+		}
+		authenticated = true
+	}
+
+	header := metadata.MD{}
+	if err := s.allow(ctx, header, plain, key, authenticated); err != nil {
+		return header, err
+	}
+
+	if authenticated {
+		usage, err := s.apiKeys.Consume(key)
+		if key.DailyQuota > 0 {
+			header.Set("x-quota-limit", strconv.Itoa(usage.Quota))
+			header.Set("x-quota-remaining", strconv.Itoa(usage.Remaining))
+		}
+		if errors.Is(err, apikey.ErrQuotaExceeded) {
+			header.Set("retry-after", strconv.Itoa(ceilSeconds(time.Until(usage.ResetAt))))
+			return header, status.Error(codes.ResourceExhausted, "daily quota exceeded")
+		}
+	}
+	return header, nil
+}
+
+// allow takes a token from the caller's bucket, keyed as the HTTP rate limit does.
+func (s *Server) allow(ctx context.Context, header metadata.MD, plain string, key apikey.Key, authenticated bool) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	var tier, bucket string
+	var limit ratelimit.Limit
+	policy := s.policy.Load()
+	if authenticated {
+		tier, limit = policy.LimitForTier(key.Tier)
+		bucket = "key:" + key.Name
+	} else {
+		tier, limit = policy.LimitFor(plain)
+		bucket = "ip:" + peerIP(ctx)
+		if tier != "default" {
+			bucket = "key:" + apikey.Hash(plain)
+		}
+	}
+	if !limit.Enabled() {
+		return nil
+	}
+
+	res, err := s.limiter.Take(ctx, bucket, limit)
+	if err != nil {
+		// Falha do store não deve derrubar a API: deixa passar
+		s.log.ErrorContext(ctx, "Rate limit store failed", "tier", tier, logger.Err(err))
+		return nil
+	}
+	header.Set("ratelimit-limit", strconv.Itoa(res.Limit))
+	header.Set("ratelimit-remaining", strconv.Itoa(res.Remaining))
+	header.Set("ratelimit-reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	if !res.Allowed {
+		header.Set("retry-after", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return nil
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
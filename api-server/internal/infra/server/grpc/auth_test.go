@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"api-server/pkg/apikey"
+	temperaturev1 "api-server/pkg/pb/temperature/v1"
+	"api-server/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Auth(t *testing.T) {
+	keys, err := apikey.NewStore([]apikey.Key{
+		{Name: "partner", Hash: apikey.Hash("secret"), DailyQuota: 2},
+		{Name: "address-only", Hash: apikey.Hash("address"), Endpoints: []string{"/temperature.v1.TemperatureService/GetAddress"}},
+	})
+	require.NoError(t, err)
+	_, conn, _, _ := setupServer(t, WithAPIKeys(keys))
+	client := temperaturev1.NewTemperatureServiceClient(conn)
+	req := &temperaturev1.GetTemperatureRequest{Cep: "01001000"}
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), metadataAPIKey, key)
+	}
+
+	t.Run("should reject calls without a valid api key", func(t *testing.T) {
+		_, err := client.GetTemperature(context.Background(), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, err = client.GetTemperature(withKey("wrong"), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		stream, err := client.Watch(context.Background(), &temperaturev1.WatchRequest{Cep: "01001000"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should reject methods not allowed for the key", func(t *testing.T) {
+		_, err := client.GetTemperature(withKey("address"), req)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = client.GetAddress(withKey("address"), &temperaturev1.GetAddressRequest{Cep: "01001000"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should count calls against the daily quota", func(t *testing.T) {
+		var header metadata.MD
+		_, err := client.GetTemperature(withKey("secret"), req, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, header.Get("x-quota-remaining"))
+
+		_, err = client.GetTemperature(withKey("secret"), req)
+		require.NoError(t, err)
+
+		_, err = client.GetTemperature(withKey("secret"), req, grpc.Header(&header))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.NotEmpty(t, header.Get("retry-after"))
+	})
+
+	t.Run("should leave health checks open", func(t *testing.T) {
+		_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	policy := ratelimit.NewDynamicPolicy(ratelimit.Policy{Default: ratelimit.Limit{Rate: 0.001, Burst: 2}})
+	_, conn, _, _ := setupServer(t, WithRateLimit(ratelimit.NewMemoryStore(), policy))
+	client := temperaturev1.NewTemperatureServiceClient(conn)
+	req := &temperaturev1.GetTemperatureRequest{Cep: "01001000"}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetTemperature(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	var header metadata.MD
+	_, err := client.GetTemperature(context.Background(), req, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err, "health checks are not rate limited")
+}
@@ -0,0 +1,138 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+
+	"api-server/pkg/apikey"
+	temperaturev1 "api-server/pkg/pb/temperature/v1"
+	"api-server/pkg/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Server struct {
+	addr   string
+	lis    net.Listener
+	server *grpc.Server
+	health *health.Server
+	log    *slog.Logger
+	// stopping é cancelado no início do Shutdown e encerra os streams abertos, como o Watch
+	stopping   context.Context
+	endStreams context.CancelFunc
+
+	apiKeys *apikey.Store
+	limiter ratelimit.Store
+	policy  *ratelimit.DynamicPolicy
+}
+
+func New(port string, service temperaturev1.TemperatureServiceServer, log *slog.Logger, opts ...Option) *Server {
+	stopping, endStreams := context.WithCancel(context.Background())
+	s := &Server{
+		addr:       ":" + port,
+		health:     health.NewServer(),
+		log:        log,
+		stopping:   stopping,
+		endStreams: endStreams,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.admitUnary),
+		grpc.ChainStreamInterceptor(s.endOnShutdown, s.admitStream),
+	)
+
+	temperaturev1.RegisterTemperatureServiceServer(s.server, service)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+	return s
+}
+
+// Listen binds the port, so a busy port fails the startup instead of a background goroutine.
+func (s *Server) Listen() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.lis = lis
+	return nil
+}
+
+// Serve blocks serving the bound port until Shutdown.
+func (s *Server) Serve() error {
+	s.setServing(true)
+
+	s.log.Info("gRPC server running", "addr", s.lis.Addr().String())
+	if err := s.server.Serve(s.lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown waits for in-flight RPCs until ctx is done, then stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.log.Info("Shutting down gRPC server")
+	s.setServing(false)
+	// Streams só terminam quando o cliente desiste: sem isso o GracefulStop espera até o prazo
+	s.endStreams()
+	// Fecha o listener mesmo se o Serve nunca chegou a rodar
+	defer s.closeListener()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		s.log.Info("gRPC server gracefully stopped")
+		return nil
+	case <-ctx.Done():
+		s.log.Warn("Could not shutdown gRPC server gracefully: forcing stop")
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// endOnShutdown cancels the context of a stream when Shutdown starts, so long-lived streams end
+// cleanly and GracefulStop does not wait for them until the deadline.
+func (s *Server) endOnShutdown(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.stopping, cancel)
+	defer stop()
+
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Server) closeListener() {
+	if s.lis != nil {
+		_ = s.lis.Close()
+	}
+}
+
+func (s *Server) setServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(temperaturev1.TemperatureService_ServiceDesc.ServiceName, status)
+}
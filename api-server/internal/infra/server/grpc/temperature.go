@@ -0,0 +1,146 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"api-server/domain"
+	"api-server/pkg/cep"
+	"api-server/pkg/logger"
+	temperaturev1 "api-server/pkg/pb/temperature/v1"
+	"api-server/pkg/temperature"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultWatchInterval = 60 * time.Second
+	minWatchInterval     = 10 * time.Second
+	temperaturePrecision = 2
+)
+
+type temperatureService struct {
+	temperaturev1.UnimplementedTemperatureServiceServer
+
+	analisysService domain.AnalysisService
+	log             *slog.Logger
+}
+
+func NewTemperatureService(analisysService domain.AnalysisService, log *slog.Logger) temperaturev1.TemperatureServiceServer {
+	return &temperatureService{
+		analisysService: analisysService,
+		log:             log,
+	}
+}
+
+func (s *temperatureService) GetAddress(ctx context.Context, req *temperaturev1.GetAddressRequest) (*temperaturev1.GetAddressResponse, error) {
+	cityInfo, err := s.getCity(ctx, req.GetCep())
+	if err != nil {
+		return nil, err
+	}
+
+	city, state := splitCityInfo(cityInfo)
+	return &temperaturev1.GetAddressResponse{Cep: req.GetCep(), City: city, State: state}, nil
+}
+
+func (s *temperatureService) GetTemperature(ctx context.Context, req *temperaturev1.GetTemperatureRequest) (*temperaturev1.GetTemperatureResponse, error) {
+	cityInfo, err := s.getCity(ctx, req.GetCep())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getTemperature(ctx, req.GetCep(), cityInfo)
+}
+
+func (s *temperatureService) Watch(req *temperaturev1.WatchRequest, stream temperaturev1.TemperatureService_WatchServer) error {
+	ctx := stream.Context()
+
+	interval := defaultWatchInterval
+	if req.GetIntervalSeconds() > 0 {
+		interval = max(time.Duration(req.GetIntervalSeconds())*time.Second, minWatchInterval)
+	}
+
+	cityInfo, err := s.getCity(ctx, req.GetCep())
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *temperaturev1.GetTemperatureResponse
+	for {
+		temp, err := s.getTemperature(ctx, req.GetCep(), cityInfo)
+		switch {
+		case err != nil:
+			// Falha pontual no polling não encerra o stream
+			logger.FromContext(ctx, s.log).WarnContext(ctx, "Watch lookup failed", logger.KeyCEP, req.GetCep(), logger.Err(err))
+		case last == nil || temp.GetTempC() != last.GetTempC():
+			last = temp
+			if err := stream.Send(&temperaturev1.WatchResponse{Temperature: temp, ObservedAt: timestamppb.Now()}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *temperatureService) getCity(ctx context.Context, input string) (string, error) {
+	parsed, err := cep.Parse(input)
+	if errors.Is(err, cep.ErrUnallocated) {
+		return "", status.Error(codes.InvalidArgument, "zipcode range not allocated to any UF")
+	}
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "invalid zipcode")
+	}
+
+	cityInfo, err := s.analisysService.GetCity(ctx, parsed.Digits())
+	if errors.Is(err, domain.ErrNoProviderAvailable) {
+		return "", status.Error(codes.Unavailable, "upstream providers are temporarily unavailable")
+	}
+	if err != nil {
+		return "", status.Error(codes.NotFound, "can not find zipcode")
+	}
+	return cityInfo, nil
+}
+
+func (s *temperatureService) getTemperature(ctx context.Context, cep, cityInfo string) (*temperaturev1.GetTemperatureResponse, error) {
+	celsiusTemp, err := s.analisysService.GetCelsiusTemperature(ctx, cityInfo)
+	if errors.Is(err, domain.ErrProviderUnavailable) {
+		return nil, status.Error(codes.Unavailable, "upstream providers are temporarily unavailable")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can not find temperature in Celsius for City: "+cityInfo+".")
+	}
+
+	temp := temperature.FromCelsius(float64(celsiusTemp))
+	city, state := splitCityInfo(cityInfo)
+
+	return &temperaturev1.GetTemperatureResponse{
+		Cep:   cep,
+		City:  city,
+		State: state,
+		TempC: temp.Rounded(temperature.Celsius, temperaturePrecision, temperature.HalfUp),
+		TempF: temp.Rounded(temperature.Fahrenheit, temperaturePrecision, temperature.HalfUp),
+		TempK: temp.Rounded(temperature.Kelvin, temperaturePrecision, temperature.HalfUp),
+	}, nil
+}
+
+// splitCityInfo splits the "City,UF" returned by the CEP providers.
+func splitCityInfo(cityInfo string) (string, string) {
+	idx := strings.LastIndex(cityInfo, ",")
+	if idx < 0 {
+		return cityInfo, ""
+	}
+	return cityInfo[:idx], cityInfo[idx+1:]
+}
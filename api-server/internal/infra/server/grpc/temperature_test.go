@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"api-server/domain/analysis"
+	"api-server/domain/mocks"
+	temperaturev1 "api-server/pkg/pb/temperature/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupServer(t *testing.T, opts ...Option) (*Server, *grpc.ClientConn, *mocks.MockBuscaCEPAPIClient, *mocks.MockWeatherAPIClient) {
+	logger := slog.Default()
+
+	mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{
+		GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+			if cep == "99999999" {
+				return "", errors.New("not found")
+			}
+			return "São Paulo,SP", nil
+		},
+	}
+	mockBuscaCEPClient.GetViaAPICEPFunc = mockBuscaCEPClient.GetBrasilAPICEPFunc
+	mockWeatherClient := &mocks.MockWeatherAPIClient{
+		GetHGWeatherAPIFunc: func(ctx context.Context, city string) (int, error) {
+			return 25, nil
+		},
+	}
+	analysisService := analysis.NewAnalysisService(mockBuscaCEPClient, mockWeatherClient, logger)
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := New("0", NewTemperatureService(analysisService, logger), logger, opts...)
+	server.lis = lis
+	go func() { _ = server.Serve() }()
+	t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return server, conn, mockBuscaCEPClient, mockWeatherClient
+}
+
+func TestTemperatureService(t *testing.T) {
+	_, conn, _, mockWeather := setupServer(t)
+	client := temperaturev1.NewTemperatureServiceClient(conn)
+	ctx := context.Background()
+
+	t.Run("should return the temperature", func(t *testing.T) {
+		res, err := client.GetTemperature(ctx, &temperaturev1.GetTemperatureRequest{Cep: "01001000"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "São Paulo", res.GetCity())
+		assert.Equal(t, "SP", res.GetState())
+		assert.Equal(t, float64(25), res.GetTempC())
+		assert.Equal(t, float64(77), res.GetTempF())
+		assert.Equal(t, 298.15, res.GetTempK())
+	})
+
+	t.Run("should return the address", func(t *testing.T) {
+		res, err := client.GetAddress(ctx, &temperaturev1.GetAddressRequest{Cep: "01001000"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "01001000", res.GetCep())
+		assert.Equal(t, "São Paulo", res.GetCity())
+		assert.Equal(t, "SP", res.GetState())
+	})
+
+	t.Run("should map errors to status codes", func(t *testing.T) {
+		_, err := client.GetTemperature(ctx, &temperaturev1.GetTemperatureRequest{Cep: "123"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.GetTemperature(ctx, &temperaturev1.GetTemperatureRequest{Cep: "00012345"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.GetAddress(ctx, &temperaturev1.GetAddressRequest{Cep: "99999999"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 0, errors.New("weather api error")
+		}
+		defer func() {
+			mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) { return 25, nil }
+		}()
+		_, err = client.GetTemperature(ctx, &temperaturev1.GetTemperatureRequest{Cep: "01001000"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+
+	t.Run("should stream the current temperature on watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := client.Watch(watchCtx, &temperaturev1.WatchRequest{Cep: "01001000"})
+		require.NoError(t, err)
+
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, float64(25), res.GetTemperature().GetTempC())
+		assert.NotNil(t, res.GetObservedAt())
+	})
+
+	t.Run("should report serving on health checks", func(t *testing.T) {
+		res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+			Service: temperaturev1.TemperatureService_ServiceDesc.ServiceName,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+	})
+
+	t.Run("should list the service through reflection", func(t *testing.T) {
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}))
+		res, err := stream.Recv()
+		require.NoError(t, err)
+
+		var services []string
+		for _, s := range res.GetListServicesResponse().GetService() {
+			services = append(services, s.GetName())
+		}
+		assert.Contains(t, services, temperaturev1.TemperatureService_ServiceDesc.ServiceName)
+		_ = stream.CloseSend()
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server, conn, _, _ := setupServer(t)
+
+	stream, err := temperaturev1.NewTemperatureServiceClient(conn).Watch(context.Background(), &temperaturev1.WatchRequest{Cep: "01001000"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, server.Shutdown(ctx), "an open Watch must not hold the shutdown")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF, "the stream ends cleanly")
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"api-server/pkg/cloudrun"
+	"api-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithAccessLog logs one entry per request with its Cloud Logging httpRequest.
+func WithAccessLog() Option {
+	return func(h *handler) {
+		h.accessLogEnabled = true
+	}
+}
+
+// requestLogger carries a logger with the request ID, and the X-Cloud-Trace-Context trace
+// set by the Cloud Run front end, in the request context for the service and client logs.
+func (h *handler) requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := logger.WithContext(c.Request.Context(), h.log.With(logger.KeyRequestID, requestIDFromContext(c)))
+		ctx = cloudrun.ContextWithTrace(ctx, c.GetHeader(cloudrun.HeaderTraceContext))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+func (h *handler) logger(c *gin.Context) *slog.Logger {
+	return logger.FromContext(c.Request.Context(), h.log)
+}
+
+func (h *handler) accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := h.now()
+		c.Next()
+
+		// As probes do Cloud Run só poluiriam o log
+		if c.FullPath() == "/healthz" || c.FullPath() == "/readyz" {
+			return
+		}
+
+		status := c.Writer.Status()
+		elapsed := h.now().Sub(start)
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int(logger.KeyStatus, status),
+			logger.Duration(elapsed),
+			slog.Any(cloudrun.KeyHTTPRequest, cloudrun.HTTPRequest{
+				RequestMethod: c.Request.Method,
+				RequestURL:    c.Request.URL.RequestURI(),
+				Status:        status,
+				UserAgent:     c.Request.UserAgent(),
+				RemoteIP:      c.ClientIP(),
+				Latency:       formatLatency(elapsed),
+			}),
+		}
+		if err := c.Errors.Last(); err != nil {
+			attrs = append(attrs, logger.Err(err.Err))
+		}
+
+		ctx := c.Request.Context()
+		h.logger(c).LogAttrs(ctx, levelFor(status), "Request served", attrs...)
+	}
+}
+
+func levelFor(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// formatLatency renders d as a protobuf Duration string ("0.123s").
+func formatLatency(d time.Duration) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.9f", d.Seconds()), "0"), ".") + "s"
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api-server/domain/analysis"
+	"api-server/domain/mocks"
+	"api-server/pkg/cloudrun"
+	"api-server/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_CloudRun(t *testing.T) {
+	var out bytes.Buffer
+	md := cloudrun.Metadata{Service: "api-server", Revision: "api-server-00042-abc", Project: "my-project"}
+	log := logger.New(&out, logger.Options{ReplaceAttr: cloudrun.ReplaceAttr, TraceAttrs: md.TraceAttrs})
+
+	mockBuscaCEP, mockWeather := &mocks.MockBuscaCEPAPIClient{}, &mocks.MockWeatherAPIClient{}
+	router := NewHandler(analysis.NewAnalysisService(mockBuscaCEP, mockWeather, log), log,
+		WithAccessLog(),
+		WithVersion(VersionResponse{Version: "1.2.0", Build: "abc123", Date: "2024-06-01", Service: md.Service, Revision: md.Revision}))
+
+	t.Run("should expose the version and revision", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/version", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"version":"1.2.0","build":"abc123","date":"2024-06-01","service":"api-server","revision":"api-server-00042-abc"}`, w.Body.String())
+	})
+
+	t.Run("should log failed requests correlated with the Cloud Run trace", func(t *testing.T) {
+		out.Reset()
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "São Paulo,SP", nil
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 0, errors.New("connection reset")
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/tempForCep/01001000", nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+
+		for _, entry := range entries {
+			assert.Equal(t, "req-1", entry["request_id"], "every entry of the request carries its ID: %v", entry["message"])
+			assert.Equal(t, "projects/my-project/traces/105445aa7843bc8bf206b12000100000", entry["logging.googleapis.com/trace"])
+		}
+
+		access := entries[len(entries)-1]
+		assert.Equal(t, "Request served", access["message"])
+		assert.Equal(t, "ERROR", access["severity"])
+		assert.Equal(t, float64(500), access["httpRequest"].(map[string]any)["status"])
+		assert.Contains(t, access["error"], "connection reset")
+	})
+
+	t.Run("should not log health probes", func(t *testing.T) {
+		out.Reset()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/healthz", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, out.String())
+	})
+}
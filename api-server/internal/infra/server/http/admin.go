@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"api-server/pkg/circuitbreaker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithReload serves POST /admin/reload, authenticated by the bearer token returned by token.
+// The endpoint answers 403 while the token is empty.
+func WithReload(reload func(ctx context.Context) (ReloadResponse, error), token func() string) Option {
+	return func(h *handler) {
+		h.reload = reload
+		h.adminToken = token
+	}
+}
+
+func (h *handler) GetBreakers(c *gin.Context) {
+	breakers := []circuitbreaker.Snapshot{}
+	if h.breakers != nil {
+		breakers = h.breakers.Snapshots()
+	}
+
+	c.JSON(http.StatusOK, BreakersResponse{Breakers: breakers})
+}
+
+// authenticateAdmin checks the Authorization: Bearer header against the admin token.
+func (h *handler) authenticateAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := ""
+		if h.adminToken != nil {
+			token = h.adminToken()
+		}
+		if token == "" {
+			abortWithError(c, errAdminDisabled)
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, errUnauthorized.withDetail("invalid or missing admin token"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (h *handler) Reload(c *gin.Context) {
+	res, err := h.reload(c.Request.Context())
+	if err != nil {
+		abortWithError(c, errInvalidConfig.withDetail(err.Error()))
+		return
+	}
+	if res.Changed == nil {
+		res.Changed = []string{}
+	}
+	if res.RestartRequired == nil {
+		res.RestartRequired = []string{}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
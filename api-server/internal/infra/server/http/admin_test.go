@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Reload(t *testing.T) {
+	token := "admin-secret"
+	reloads := 0
+	valid := true
+	router, _, _ := setupFullRouter(t, WithReload(func(ctx context.Context) (ReloadResponse, error) {
+		reloads++
+		if !valid {
+			return ReloadResponse{}, errors.New("upstream.cep_timeout: must be positive")
+		}
+		return ReloadResponse{Changed: []string{"log.level"}}, nil
+	}, func() string { return token }))
+	doc := getSpec(t, router)
+
+	doReload := func(authorization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/reload", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should reject a missing or wrong token", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer wrong", "admin-secret"} {
+			w := doReload(authorization)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assertDocumentedResponse(t, doc, "/admin/reload", "POST", w)
+		}
+		assert.Zero(t, reloads)
+	})
+
+	t.Run("should return the changed keys", func(t *testing.T) {
+		w := doReload("Bearer admin-secret")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"changed":["log.level"],"restart_required":[]}`, w.Body.String())
+		assertDocumentedResponse(t, doc, "/admin/reload", "POST", w)
+	})
+
+	t.Run("should return 422 on an invalid configuration", func(t *testing.T) {
+		valid = false
+		defer func() { valid = true }()
+
+		w := doReload("Bearer admin-secret")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "upstream.cep_timeout")
+		assertDocumentedResponse(t, doc, "/admin/reload", "POST", w)
+	})
+
+	t.Run("should be disabled without a token", func(t *testing.T) {
+		token = ""
+		reloads = 0
+
+		w := doReload("Bearer ")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assertDocumentedResponse(t, doc, "/admin/reload", "POST", w)
+		assert.Zero(t, reloads)
+	})
+}
+
+func TestHandler_GetBreakers(t *testing.T) {
+	router, _, _ := setupFullRouter(t, WithReload(func(ctx context.Context) (ReloadResponse, error) {
+		return ReloadResponse{}, nil
+	}, func() string { return "admin-secret" }))
+	doc := getSpec(t, router)
+
+	for authorization, status := range map[string]int{
+		"":                    http.StatusUnauthorized,
+		"Bearer wrong":        http.StatusUnauthorized,
+		"Bearer admin-secret": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/breakers", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, status, w.Code, authorization)
+		assertDocumentedResponse(t, doc, "/admin/breakers", "GET", w)
+	}
+}
@@ -1,41 +1,142 @@
 package http
 
 import (
-	"api-server/pkg/utils"
+	"api-server/domain"
+	"api-server/pkg/cep"
+	"api-server/pkg/temperature"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+const defaultTemperaturePrecision = 2
+
+type temperatureOptions struct {
+	units     []temperature.Unit
+	precision int
+	rounding  temperature.RoundingMode
+}
+
+// WithTemperatureDefaults sets the precision and rounding mode used when the request does not inform them.
+func WithTemperatureDefaults(precision int, rounding temperature.RoundingMode) Option {
+	return func(h *handler) {
+		h.temperatureDefaults = temperatureOptions{precision: precision, rounding: rounding}
+	}
+}
+
+func (h *handler) parseTemperatureOptions(c *gin.Context) (temperatureOptions, error) {
+	opts := temperatureOptions{
+		units:     temperature.DefaultUnits,
+		precision: h.temperatureDefaults.precision,
+		rounding:  h.temperatureDefaults.rounding,
+	}
+
+	if units, ok := c.GetQuery("units"); ok {
+		parsed, err := temperature.ParseUnits(units)
+		if err != nil {
+			return opts, errInvalidParameter.withDetail("units: " + err.Error())
+		}
+		opts.units = parsed
+	}
+
+	if precision, ok := c.GetQuery("precision"); ok {
+		parsed, err := strconv.Atoi(precision)
+		if err != nil || parsed < 0 || parsed > temperature.MaxPrecision {
+			return opts, errInvalidParameter.withDetail(
+				"precision: must be an integer between 0 and " + strconv.Itoa(temperature.MaxPrecision))
+		}
+		opts.precision = parsed
+	}
+
+	if rounding, ok := c.GetQuery("rounding"); ok {
+		parsed, err := temperature.ParseRoundingMode(rounding)
+		if err != nil {
+			return opts, errInvalidParameter.withDetail("rounding: must be half_up or half_even")
+		}
+		opts.rounding = parsed
+	}
+
+	return opts, nil
+}
+
+func newTemperatureResponse(temp temperature.Temperature, opts temperatureOptions) TemperatureResponse {
+	var res TemperatureResponse
+	for _, unit := range opts.units {
+		value := temp.Rounded(unit, opts.precision, opts.rounding)
+		switch unit {
+		case temperature.Celsius:
+			res.TempC = &value
+		case temperature.Fahrenheit:
+			res.TempF = &value
+		case temperature.Kelvin:
+			res.TempK = &value
+		case temperature.Rankine:
+			res.TempR = &value
+		case temperature.Reaumur:
+			res.TempRe = &value
+		case temperature.Delisle:
+			res.TempDe = &value
+		}
+	}
+	return res
+}
+
+// parseCEP rejects, before any upstream call, the malformed CEPs and those outside every UF range.
+func parseCEP(s string) (cep.CEP, error) {
+	c, err := cep.Parse(s)
+	if errors.Is(err, cep.ErrUnallocated) {
+		return "", errInvalidCEP.withDetail("zipcode range not allocated to any UF").wrap(err)
+	}
+	if err != nil {
+		return "", errInvalidCEP.wrap(err)
+	}
+	return c, nil
+}
+
+func cityError(err error) *apiError {
+	if errors.Is(err, domain.ErrNoProviderAvailable) {
+		return errUpstreamUnavailable.wrap(err)
+	}
+	return errCEPNotFound.wrap(err)
+}
+
+func temperatureError(err error, cityInfo string) *apiError {
+	if errors.Is(err, domain.ErrProviderUnavailable) {
+		return errUpstreamUnavailable.wrap(err)
+	}
+	return errTemperatureUnavailable.withDetail("can not find temperature in Celsius for City: " + cityInfo + ".").wrap(err)
+}
+
 func (h *handler) RunAnalysis(c *gin.Context) {
-	cep := c.Param("cep")
-	if !utils.IsValidCEP(cep) {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid zipcode"})
+	cep, err := parseCEP(c.Param("cep"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	opts, err := h.parseTemperatureOptions(c)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	// Call the analysis service to run
-	cityInfo, err := h.analisysService.GetCity(c.Request.Context(), cep)
+	cityInfo, err := h.analisysService.GetCity(c.Request.Context(), cep.Digits())
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "can not find zipcode"})
+		abortWithError(c, cityError(err))
 		return
 	}
 
 	celsiusTemp, err := h.analisysService.GetCelsiusTemperature(c.Request.Context(), cityInfo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError,
-			gin.H{"error": "can not find temperature in Celsius for City: " + cityInfo + ".", "details": err.Error()})
+		abortWithError(c, temperatureError(err, cityInfo))
 		return
 	}
 
-	celsiusTempFloat := float64(celsiusTemp)
-	fahrenheitTemp := utils.ConvertCelsiusToFahrenheit(celsiusTempFloat)
-	kelvinTemp := utils.ConvertCelsiusToKelvin(celsiusTempFloat)
-
-	// Arredondar para 2 casas decimais para consistência no JSON
-	fahrenheitTemp = float64(int(fahrenheitTemp*100)) / 100
-	kelvinTemp = float64(int(kelvinTemp*100)) / 100
+	temp := temperature.FromCelsius(float64(celsiusTemp))
 
-	c.JSON(http.StatusOK, gin.H{"temp_C": celsiusTemp, "temp_F": fahrenheitTemp, "temp_K": kelvinTemp})
+	h.render(c, http.StatusOK, newTemperatureResponse(temp, opts))
 	c.Next()
 }
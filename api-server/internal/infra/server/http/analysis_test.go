@@ -6,10 +6,9 @@ import (
 	"api-server/domain/mocks"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +17,7 @@ import (
 
 func setupRouter(t *testing.T) (*gin.Engine, *mocks.MockBuscaCEPAPIClient, *mocks.MockWeatherAPIClient) {
 	gin.SetMode(gin.TestMode)
-	logger := log.New(os.Stdout, "test-handler - ", log.LstdFlags)
+	logger := slog.Default()
 
 	mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{}
 	mockWeatherClient := &mocks.MockWeatherAPIClient{}
@@ -27,11 +26,13 @@ func setupRouter(t *testing.T) (*gin.Engine, *mocks.MockBuscaCEPAPIClient, *mock
 	
 	// Manually create the handler struct, similar to what NewHandler does
 	handler := &handler{
-		analisysService: analysisService,
-		log:             logger,
+		analisysService:     analysisService,
+		log:                 logger,
+		temperatureDefaults: temperatureOptions{precision: defaultTemperaturePrecision},
 	}
 
 	router := gin.Default()
+	router.Use(requestID(), handler.handleErrors())
 	// Use the correct route as defined in handler.go
 	router.GET("/tempForCep/:cep", handler.RunAnalysis)
 
@@ -75,7 +76,51 @@ func TestHandler_RunAnalysis(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
-		assert.JSONEq(t, `{"error":"invalid zipcode"}`, w.Body.String())
+		assert.Equal(t, contentTypeProblemJSON, w.Header().Get("Content-Type"))
+
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid_cep", problem.Code)
+		assert.Equal(t, "invalid zipcode", problem.Detail)
+		assert.Equal(t, "/tempForCep/123", problem.Instance)
+		assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		assert.Equal(t, w.Header().Get(headerRequestID), problem.RequestID)
+	})
+
+	t.Run("should accept a formatted cep", func(t *testing.T) {
+		router, mockBuscaCEP, mockWeather := setupRouter(t)
+
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			assert.Equal(t, "01001000", cep)
+			return "São Paulo,SP", nil
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 25, nil
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/tempForCep/01001-000", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should return 422 for an unallocated cep without calling the providers", func(t *testing.T) {
+		// Mocks sem funções: qualquer chamada aos provedores causaria panic
+		router, _, _ := setupRouter(t)
+
+		for _, input := range []string{"00012345", "12345678"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/tempForCep/"+input, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code, input)
+			var problem Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, "invalid_cep", problem.Code)
+			assert.Equal(t, "zipcode range not allocated to any UF", problem.Detail)
+		}
 	})
 
 	t.Run("should return 404 when cep is not found", func(t *testing.T) {
@@ -93,7 +138,11 @@ func TestHandler_RunAnalysis(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
-		assert.JSONEq(t, `{"error":"can not find zipcode"}`, w.Body.String())
+
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "cep_not_found", problem.Code)
+		assert.Equal(t, "can not find zipcode", problem.Detail)
 	})
 
 	t.Run("should return 500 when weather api fails", func(t *testing.T) {
@@ -115,5 +164,85 @@ func TestHandler_RunAnalysis(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "can not find temperature in Celsius for City: São Paulo,SP")
+		assert.Contains(t, w.Body.String(), `"code":"temperature_unavailable"`)
+		assert.NotContains(t, w.Body.String(), "weather api error")
 	})
-}
\ No newline at end of file
+
+	t.Run("should return only the requested units with the requested precision", func(t *testing.T) {
+		router, mockBuscaCEP, mockWeather := setupRouter(t)
+
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "Urupema,SC", nil
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return -3, nil
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/tempForCep/88625000?units=F,K,De&precision=1", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"temp_F":26.6,"temp_K":270.2,"temp_De":154.5}`, w.Body.String())
+	})
+
+	t.Run("should round instead of truncating", func(t *testing.T) {
+		router, mockBuscaCEP, mockWeather := setupRouter(t)
+
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "Urupema,SC", nil
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return -3, nil
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/tempForCep/88625000?units=K&precision=0", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"temp_K":270}`, w.Body.String())
+	})
+
+	t.Run("should round a half by the rounding mode", func(t *testing.T) {
+		router, mockBuscaCEP, mockWeather := setupRouter(t)
+
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "Urupema,SC", nil
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 1, nil
+		}
+
+		// 1°C são exatamente 148,5°De: truncar daria 148 nos dois modos
+		for query, expected := range map[string]string{
+			"units=De&precision=0":                    `{"temp_De":149}`,
+			"units=De&precision=0&rounding=half_up":   `{"temp_De":149}`,
+			"units=De&precision=0&rounding=half_even": `{"temp_De":148}`,
+			"units=De&precision=1":                    `{"temp_De":148.5}`,
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/tempForCep/88625000?"+query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code, query)
+			assert.JSONEq(t, expected, w.Body.String(), query)
+		}
+	})
+
+	t.Run("should return 400 for invalid temperature parameters", func(t *testing.T) {
+		router, _, _ := setupRouter(t)
+
+		for _, query := range []string{"units=X", "precision=-1", "precision=abc", "rounding=ceil"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/tempForCep/01001000?"+query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), `"code":"invalid_parameter"`, query)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-server/pkg/apikey"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	queryAPIKey   = "api_key"
+	contextAPIKey = "apiKey"
+)
+
+// WithAPIKeys requires an API key on the public routes and meters their daily usage.
+func WithAPIKeys(keys *apikey.Store) Option {
+	return func(h *handler) {
+		h.apiKeys = keys
+	}
+}
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(headerAPIKey); key != "" {
+		return key
+	}
+	return c.Query(queryAPIKey)
+}
+
+func apiKeyFromContext(c *gin.Context) (apikey.Key, bool) {
+	v, ok := c.Get(contextAPIKey)
+	if !ok {
+		return apikey.Key{}, false
+	}
+	key, ok := v.(apikey.Key)
+	return key, ok
+}
+
+// authenticate resolves the caller's API key and checks the route is allowed for it.
+func (h *handler) authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.apiKeys == nil {
+			c.Next()
+			return
+		}
+
+		key, err := h.apiKeys.Authenticate(apiKeyFromRequest(c))
+		if err != nil {
+			c.Header("WWW-Authenticate", `APIKey header="`+headerAPIKey+`"`)
+			abortWithError(c, errUnauthorized)
+			return
+		}
+
+		if endpoint := routePattern(c); !key.Allows(endpoint) {
+			h.logger(c).WarnContext(c.Request.Context(), "API key not allowed on endpoint", "api_key", key.Name, "endpoint", endpoint)
+			abortWithError(c, errForbidden)
+			return
+		}
+
+		c.Set(contextAPIKey, key)
+		c.Next()
+	}
+}
+
+// meter counts the request against the caller's daily quota.
+func (h *handler) meter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.meterN(c, 1) {
+			c.Next()
+		}
+	}
+}
+
+// meterN counts n lookups against the caller's daily quota, e.g. one per CEP of a batch, and sets
+// the X-Quota-* headers. When they do not fit it aborts with errQuotaExceeded and returns false.
+func (h *handler) meterN(c *gin.Context, n int) bool {
+	key, ok := apiKeyFromContext(c)
+	if !ok || n <= 0 {
+		return true
+	}
+
+	usage, err := h.apiKeys.ConsumeN(key, n)
+	if key.DailyQuota > 0 {
+		c.Header("X-Quota-Limit", strconv.Itoa(usage.Quota))
+		c.Header("X-Quota-Remaining", strconv.Itoa(usage.Remaining))
+	}
+	switch {
+	case errors.Is(err, apikey.ErrQuotaExceeded) && n > key.DailyQuota:
+		abortWithError(c, errQuotaExceeded.withDetail("more lookups than the daily quota of "+strconv.Itoa(key.DailyQuota)))
+		return false
+	case errors.Is(err, apikey.ErrQuotaExceeded):
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(usage.ResetAt.Sub(h.now()))))
+		abortWithError(c, errQuotaExceeded)
+		return false
+	}
+	return true
+}
+
+// refund gives back to the caller's daily quota n lookups counted by meterN that were not run.
+func (h *handler) refund(c *gin.Context, n int) {
+	key, ok := apiKeyFromContext(c)
+	if !ok || n <= 0 {
+		return
+	}
+	h.apiKeys.Refund(key, n)
+	if key.DailyQuota > 0 {
+		c.Header("X-Quota-Remaining", strconv.Itoa(h.apiKeys.Usage(key).Remaining))
+	}
+}
+
+func (h *handler) GetUsage(c *gin.Context) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		abortWithError(c, errUnauthorized)
+		return
+	}
+
+	c.JSON(http.StatusOK, UsageResponse{
+		Name:      key.Name,
+		Tier:      key.Tier,
+		Endpoints: key.Endpoints,
+		Usage:     h.apiKeys.Usage(key),
+	})
+}
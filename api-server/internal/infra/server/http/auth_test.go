@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-server/pkg/apikey"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthRouter(t *testing.T, keys ...apikey.Key) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	store, err := apikey.NewStore(keys)
+	assert.NoError(t, err)
+
+	handler := &handler{
+		log:     slog.Default(),
+		apiKeys: store,
+		now:     time.Now,
+	}
+
+	router := gin.New()
+	router.Use(handler.handleErrors())
+	router.GET("/tempForCep/:cep", handler.authenticate(), handler.meter(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/other", handler.authenticate(), handler.meter(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/me/usage", handler.authenticate(), handler.GetUsage)
+	return router
+}
+
+func doAuth(router *gin.Engine, path, key string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	if key != "" {
+		req.Header.Set(headerAPIKey, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_Authenticate(t *testing.T) {
+	keys := []apikey.Key{
+		{Name: "erp", Hash: apikey.Hash("erp-secret"), DailyQuota: 1, Endpoints: []string{"/tempForCep/:cep", "/me/usage"}},
+	}
+
+	t.Run("should return 401 without a valid key", func(t *testing.T) {
+		router := setupAuthRouter(t, keys...)
+
+		assert.Equal(t, http.StatusUnauthorized, doAuth(router, "/tempForCep/01001000", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, doAuth(router, "/tempForCep/01001000", "wrong").Code)
+	})
+
+	t.Run("should accept the key as query parameter", func(t *testing.T) {
+		router := setupAuthRouter(t, keys...)
+
+		assert.Equal(t, http.StatusOK, doAuth(router, "/tempForCep/01001000?api_key=erp-secret", "").Code)
+	})
+
+	t.Run("should return 403 on endpoints not allowed for the key", func(t *testing.T) {
+		router := setupAuthRouter(t, keys...)
+
+		assert.Equal(t, http.StatusForbidden, doAuth(router, "/other", "erp-secret").Code)
+	})
+
+	t.Run("should return 429 when the daily quota is used up", func(t *testing.T) {
+		router := setupAuthRouter(t, keys...)
+
+		assert.Equal(t, http.StatusOK, doAuth(router, "/tempForCep/01001000", "erp-secret").Code)
+		w := doAuth(router, "/tempForCep/01001000", "erp-secret")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("should report the key usage without consuming quota", func(t *testing.T) {
+		router := setupAuthRouter(t, keys...)
+		doAuth(router, "/tempForCep/01001000", "erp-secret")
+
+		w := doAuth(router, "/me/usage", "erp-secret")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Name  string       `json:"name"`
+			Usage apikey.Usage `json:"usage"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "erp", response.Name)
+		assert.Equal(t, 1, response.Usage.Used)
+		assert.Equal(t, 1, response.Usage.Quota)
+	})
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-server/domain"
+	"api-server/internal/infra/jobs"
+	"api-server/internal/lookup"
+	"api-server/pkg/cep"
+	"api-server/pkg/temperature"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultBatchMaxSize = 500
+	defaultBatchWorkers = 8
+	maxBatchBodyBytes   = 1 << 20
+	batchFormField      = "file"
+)
+
+type batchOptions struct {
+	maxSize int
+	workers int
+}
+
+// WithBatch bounds POST /tempForCep/batch: at most maxSize CEPs per request, looked up by workers goroutines.
+func WithBatch(maxSize, workers int) Option {
+	return func(h *handler) {
+		h.batch = batchOptions{maxSize: maxSize, workers: workers}
+	}
+}
+
+// parseBatch reads the CEPs of a JSON array of strings, a CSV body or a CSV uploaded as the "file" form field.
+func parseBatch(c *gin.Context, maxBytes int64) ([]string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "application/json", "":
+		var ceps []string
+		if err := json.NewDecoder(c.Request.Body).Decode(&ceps); err != nil {
+			return nil, errInvalidBody.withDetail("expected a JSON array of CEP strings").wrap(err)
+		}
+		return ceps, nil
+	case "text/csv":
+		return readCSVColumn(c.Request.Body)
+	case "multipart/form-data":
+		file, err := c.FormFile(batchFormField)
+		if err != nil {
+			return nil, errInvalidBody.withDetail("expected a CSV file in the " + batchFormField + " form field").wrap(err)
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, errInvalidBody.wrap(err)
+		}
+		defer f.Close()
+		return readCSVColumn(f)
+	}
+	return nil, errUnsupportedMediaType
+}
+
+// readCSVColumn returns the "cep" column of a CSV with a header, or the first column of one without.
+func readCSVColumn(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errInvalidBody.withDetail("invalid CSV").wrap(err)
+	}
+
+	column := 0
+	if len(records) > 0 {
+		for i, cell := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(cell), "cep") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+
+	var ceps []string
+	for _, record := range records {
+		if column < len(record) && strings.TrimSpace(record[column]) != "" {
+			ceps = append(ceps, record[column])
+		}
+	}
+	return ceps, nil
+}
+
+// readBatch parses the CEPs of the body, rejecting an empty batch or one with more than maxSize CEPs.
+func readBatch(c *gin.Context, maxBytes int64, maxSize int) ([]string, error) {
+	inputs, err := parseBatch(c, maxBytes)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = errBatchTooLarge.withDetail("request body larger than " + strconv.FormatInt(maxBytes, 10) + " bytes")
+		}
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, errInvalidBody.withDetail("no CEP in the batch")
+	}
+	if len(inputs) > maxSize {
+		return nil, errBatchTooLarge.withDetail("at most " + strconv.Itoa(maxSize) + " CEPs per batch")
+	}
+	return inputs, nil
+}
+
+func (h *handler) RunBatch(c *gin.Context) {
+	opts, err := h.parseTemperatureOptions(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	inputs, err := readBatch(c, maxBatchBodyBytes, h.batch.maxSize)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	// Os middlewares já cobraram uma consulta; cada outro CEP distinto custa mais uma
+	if n := distinctCEPs(inputs) - 1; !h.allowN(c, n) || !h.meterN(c, n) {
+		return
+	}
+
+	b := newBatchLookup(h.analisysService, h.batch.workers)
+	results := b.Process(c.Request.Context(), inputs)
+
+	res := BatchResponse{
+		Results: make([]BatchItemResponse, len(results)),
+		Summary: BatchSummary{Total: len(results), UniqueCEPs: len(b.Cities), UniqueCities: len(b.Temps)},
+	}
+	for i, result := range results {
+		res.Results[i] = newBatchItemResponse(result, opts)
+		if result.Error != nil {
+			res.Summary.Failed++
+		} else {
+			res.Summary.Succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// distinctCEPs is how many different valid CEPs inputs has: what a batch of them costs in rate
+// limit tokens and quota, as repeated and invalid CEPs are not looked up.
+func distinctCEPs(inputs []string) int {
+	seen := make(map[cep.CEP]bool, len(inputs))
+	for _, input := range inputs {
+		if p, err := parseCEP(input); err == nil {
+			seen[p] = true
+		}
+	}
+	return len(seen)
+}
+
+// batchLookup looks up the CEPs of a batch, or of a job one chunk at a time, each CEP and each city
+// only once.
+type batchLookup struct {
+	*lookup.Batch[string, int]
+}
+
+func newBatchLookup(service domain.AnalysisService, workers int) *batchLookup {
+	getCity := func(ctx context.Context, c cep.CEP) (string, error) {
+		return service.GetCity(ctx, c.Digits())
+	}
+	cityInfo := func(city string) string { return city }
+	return &batchLookup{lookup.NewBatch(workers, getCity, cityInfo, service.GetCelsiusTemperature)}
+}
+
+// NewBatchProcessor looks up the CEPs of a job like POST /tempForCep/batch does, with workers goroutines.
+func NewBatchProcessor(service domain.AnalysisService, workers int) jobs.Processor {
+	return newBatchLookup(service, workers)
+}
+
+func (b *batchLookup) Process(ctx context.Context, inputs []string) []jobs.Result {
+	results := make([]jobs.Result, len(inputs))
+	parsed := make([]cep.CEP, len(inputs))
+
+	var ceps []cep.CEP
+	for i, input := range inputs {
+		results[i].Input = input
+		p, err := parseCEP(input)
+		if err != nil {
+			results[i].Error = newResultError(err)
+			continue
+		}
+		parsed[i] = p
+		results[i].CEP = p.String()
+		ceps = append(ceps, p)
+	}
+	b.Lookup(ctx, ceps)
+
+	for i := range results {
+		if results[i].Error != nil {
+			continue
+		}
+		city := b.Cities[parsed[i]]
+		if city.Err != nil {
+			results[i].Error = newResultError(cityError(city.Err))
+			continue
+		}
+		results[i].City = city.Value
+		temp := b.Temps[city.Value]
+		if temp.Err != nil {
+			results[i].Error = newResultError(temperatureError(temp.Err, city.Value))
+			continue
+		}
+		celsius := float64(temp.Value)
+		results[i].Celsius = &celsius
+	}
+	return results
+}
+
+func newBatchItemResponse(result jobs.Result, opts temperatureOptions) BatchItemResponse {
+	item := BatchItemResponse{Input: result.Input, CEP: result.CEP, City: result.City}
+	if result.Celsius != nil {
+		temp := newTemperatureResponse(temperature.FromCelsius(*result.Celsius), opts)
+		item.Temperature = &temp
+	}
+	if result.Error != nil {
+		item.Error = &BatchError{Status: result.Error.Status, Code: result.Error.Code, Detail: result.Error.Detail}
+	}
+	return item
+}
+
+func newResultError(err error) *jobs.Error {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = errInternal
+	}
+	return &jobs.Error{Status: apiErr.status, Code: apiErr.code, Detail: apiErr.detail}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"api-server/pkg/apikey"
+	"api-server/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_RunBatch(t *testing.T) {
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t, WithBatch(5, 2))
+	doc := getSpec(t, router)
+
+	var mu sync.Mutex
+	cepCalls := map[string]int{}
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		mu.Lock()
+		cepCalls[cep]++
+		mu.Unlock()
+		switch cep {
+		case "01001000", "01310100":
+			return "São Paulo,SP", nil
+		case "20040020":
+			return "Rio de Janeiro,RJ", nil
+		}
+		return "", errors.New("not found")
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "", errors.New("not found")
+	}
+	var weatherCalls atomic.Int32
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		weatherCalls.Add(1)
+		if city == "Rio de Janeiro,RJ" {
+			return 0, errors.New("weather api error")
+		}
+		return 25, nil
+	}
+
+	post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/tempForCep/batch?units=C", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		assertDocumentedResponse(t, doc, "/v1/tempForCep/batch", "POST", w)
+		return w
+	}
+
+	t.Run("should deduplicate by CEP and by city", func(t *testing.T) {
+		w := post("application/json", []byte(`["01001000", "01001-000", "01310100", "20040020", "123"]`))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res BatchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Results, 5)
+
+		assert.Equal(t, "01001000", res.Results[0].Input)
+		assert.Equal(t, "01001-000", res.Results[0].CEP)
+		assert.Equal(t, "São Paulo,SP", res.Results[0].City)
+		assert.Equal(t, 25.0, *res.Results[0].Temperature.TempC)
+		assert.Equal(t, res.Results[0].Temperature, res.Results[1].Temperature)
+		assert.Equal(t, "São Paulo,SP", res.Results[2].City)
+
+		assert.Equal(t, "Rio de Janeiro,RJ", res.Results[3].City)
+		assert.Equal(t, "temperature_unavailable", res.Results[3].Error.Code)
+		assert.Equal(t, "invalid_cep", res.Results[4].Error.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Results[4].Error.Status)
+
+		assert.Equal(t, BatchSummary{Total: 5, Succeeded: 3, Failed: 2, UniqueCEPs: 3, UniqueCities: 2}, res.Summary)
+		assert.Equal(t, 1, cepCalls["01001000"])
+		assert.Equal(t, int32(2), weatherCalls.Load())
+	})
+
+	t.Run("should read a CSV body and upload", func(t *testing.T) {
+		w := post("text/csv", []byte("id,cep\n1,01001000\n2,99999999\n"))
+		require.Equal(t, http.StatusOK, w.Code)
+		var res BatchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Results, 2)
+		assert.Nil(t, res.Results[0].Error)
+		assert.Equal(t, "cep_not_found", res.Results[1].Error.Code)
+
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		file, _ := form.CreateFormFile("file", "ceps.csv")
+		_, _ = file.Write([]byte("01001000\n01310100\n"))
+		require.NoError(t, form.Close())
+
+		w = post(form.FormDataContentType(), body.Bytes())
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 2, res.Summary.Succeeded)
+	})
+
+	t.Run("should reject invalid batches", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge,
+			post("application/json", []byte(`["01001000","01001000","01001000","01001000","01001000","01001000"]`)).Code)
+		assert.Equal(t, http.StatusBadRequest, post("application/json", []byte(`[]`)).Code)
+		assert.Equal(t, http.StatusBadRequest, post("application/json", []byte(`[1001000]`)).Code)
+		assert.Equal(t, http.StatusUnsupportedMediaType, post("application/xml", []byte(`<ceps/>`)).Code)
+	})
+}
+
+func TestHandler_RunBatch_Charges(t *testing.T) {
+	keys, err := apikey.NewStore([]apikey.Key{
+		{Name: "erp", Hash: apikey.Hash("erp-secret"), DailyQuota: 4},
+		{Name: "bi", Hash: apikey.Hash("bi-secret")},
+	})
+	require.NoError(t, err)
+	limits := ratelimit.NewDynamicPolicy(ratelimit.Policy{Default: ratelimit.Limit{Rate: 0.001, Burst: 10}})
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t, WithBatch(20, 2), WithAPIKeys(keys), WithRateLimit(ratelimit.NewMemoryStore(), limits, 0))
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "São Paulo,SP", nil
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/tempForCep/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(headerAPIKey, key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should count each distinct CEP against the quota", func(t *testing.T) {
+		w := post("erp-secret", `["01001000", "01001-000", "01310100", "20040020", "123"]`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+		assert.Equal(t, "7", w.Header().Get("RateLimit-Remaining"))
+
+		w = post("erp-secret", `["01001000", "01310100"]`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"quota_exceeded"`)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("should reject more CEPs than the burst without Retry-After", func(t *testing.T) {
+		ceps := make([]string, 12)
+		for i := range ceps {
+			ceps[i] = fmt.Sprintf(`"010010%02d"`, i)
+		}
+		w := post("bi-secret", "["+strings.Join(ceps, ",")+"]")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), "rate limit burst of 10")
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+}
@@ -1,37 +1,241 @@
 package http
 
 import (
-	"log"
-	"os"
+	"context"
+	"log/slog"
+	"time"
 
 	"api-server/domain"
+	"api-server/internal/infra/health"
+	"api-server/internal/infra/metrics"
+	"api-server/pkg/apikey"
+	"api-server/pkg/circuitbreaker"
+	"api-server/pkg/openapi"
+	"api-server/pkg/temperature"
 
 	"github.com/gin-gonic/gin"
 )
 
+const apiVersionPrefix = "/v1"
+
 type handler struct {
-	analisysService domain.AnalysisService
-	log             *log.Logger
+	analisysService  domain.AnalysisService
+	log              *slog.Logger
+	breakers         *circuitbreaker.Registry
+	rateLimiter      *rateLimiter
+	apiKeys          *apikey.Store
+	now              func() time.Time
+	spec             *openapi.Document
+	readiness        *health.Readiness
+	tracker          *health.Tracker
+	version          VersionResponse
+	accessLogEnabled bool
+	debug            bool
+	metrics          *metrics.Metrics
+	reload           func(ctx context.Context) (ReloadResponse, error)
+	adminToken       func() string
+	batch            batchOptions
+	jobs             jobsOptions
+	stream           streamOptions
+
+	temperatureDefaults temperatureOptions
+}
+
+type Option func(*handler)
+
+// WithBreakers exposes the upstream circuit breakers on the admin routes.
+func WithBreakers(breakers *circuitbreaker.Registry) Option {
+	return func(h *handler) {
+		h.breakers = breakers
+	}
+}
+
+// WithDebug runs gin in debug mode with its request logger, for local development.
+func WithDebug() Option {
+	return func(h *handler) {
+		h.debug = true
+	}
 }
 
-func NewHandler(analisysService domain.AnalysisService, log *log.Logger,
+func NewHandler(analisysService domain.AnalysisService, log *slog.Logger, opts ...Option,
 ) *gin.Engine {
 	handler := &handler{
 		analisysService: analisysService,
 		log:             log,
+		now:             time.Now,
+		temperatureDefaults: temperatureOptions{
+			precision: defaultTemperaturePrecision,
+			rounding:  temperature.HalfUp,
+		},
+		batch: batchOptions{maxSize: defaultBatchMaxSize, workers: defaultBatchWorkers},
+	}
+	for _, opt := range opts {
+		opt(handler)
 	}
 
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
-	router.Use(gin.Recovery())
+	router.HandleMethodNotAllowed = true
+	router.Use(requestID(), handler.trace(), handler.requestLogger())
+	if handler.metrics != nil {
+		router.Use(handler.instrument())
+	}
+	if handler.accessLogEnabled {
+		router.Use(handler.accessLog())
+	}
+	router.Use(handler.handleErrors(), handler.recovery())
+	router.NoRoute(noRoute)
+	router.NoMethod(noMethod)
 
-	if os.Getenv("ENV") == "local" {
+	if handler.debug {
 		gin.SetMode(gin.DebugMode)
 		router.Use(gin.Logger())
 	}
 
-	router.GET("/tempForCep/:cep", handler.RunAnalysis)
+	routes := handler.routes()
+	for _, r := range routes {
+		router.Handle(r.method, r.fullPath(), r.handlers...)
+		if r.legacy {
+			router.Handle(r.method, r.path, r.handlers...)
+		}
+	}
+
+	handler.spec = buildSpec(routes)
+	router.GET("/openapi.json", handler.GetOpenAPI)
+	if handler.metrics != nil {
+		router.GET("/metrics", gin.WrapH(handler.metrics.Handler()))
+	}
 
 	return router
 }
+
+// route is a single endpoint: its handler chain and its OpenAPI documentation.
+type route struct {
+	method   string
+	path     string
+	handlers []gin.HandlerFunc
+	doc      operationDoc
+	// unversioned routes are served as is, outside the /v1 group (operational endpoints).
+	unversioned bool
+	// legacy routes are also served without the /v1 prefix, as a deprecated alias.
+	legacy bool
+}
+
+func (r route) fullPath() string {
+	if r.unversioned {
+		return r.path
+	}
+	return apiVersionPrefix + r.path
+}
+
+func (h *handler) routes() []route {
+	routes := []route{
+		{
+			method:   "GET",
+			path:     "/tempForCep/:cep",
+			handlers: []gin.HandlerFunc{negotiateFormat(), h.authenticate(), h.rateLimit(), h.meter(), h.RunAnalysis},
+			doc:      temperatureDoc(h.apiKeys != nil),
+			legacy:   true,
+		},
+		{
+			method:   "POST",
+			path:     "/tempForCep/batch",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.meter(), h.RunBatch},
+			doc:      batchDoc(h.apiKeys != nil, h.batch.maxSize),
+		},
+		{
+			method:   "GET",
+			path:     "/cep/search",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.meter(), h.SearchCEP},
+			doc:      searchDoc(h.apiKeys != nil),
+		},
+	}
+
+	if h.stream.hub != nil {
+		routes = append(routes, route{
+			method:   "GET",
+			path:     "/tempForCep/:cep/stream",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.meter(), h.StreamTemperature},
+			doc:      streamDoc(h.apiKeys != nil),
+		})
+	}
+
+	if h.jobs.runner != nil {
+		routes = append(routes, route{
+			method:   "POST",
+			path:     "/jobs",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.meter(), h.CreateJob},
+			doc:      createJobDoc(h.apiKeys != nil, h.jobs.maxSize),
+		}, route{
+			method:   "GET",
+			path:     "/jobs/:id",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.GetJob},
+			doc:      jobDoc(h.apiKeys != nil),
+		}, route{
+			method:   "DELETE",
+			path:     "/jobs/:id",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.CancelJob},
+			doc:      cancelJobDoc(h.apiKeys != nil),
+		}, route{
+			method:   "GET",
+			path:     "/jobs/:id/result",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.rateLimit(), h.GetJobResult},
+			doc:      jobResultDoc(h.apiKeys != nil),
+		})
+	}
+
+	if h.apiKeys != nil {
+		routes = append(routes, route{
+			method:   "GET",
+			path:     "/me/usage",
+			handlers: []gin.HandlerFunc{h.authenticate(), h.GetUsage},
+			doc:      usageDoc(),
+			legacy:   true,
+		})
+	}
+
+	routes = append(routes, route{
+		method:      "GET",
+		path:        "/healthz",
+		handlers:    []gin.HandlerFunc{h.Healthz},
+		doc:         healthzDoc(),
+		unversioned: true,
+	}, route{
+		method:      "GET",
+		path:        "/readyz",
+		handlers:    []gin.HandlerFunc{h.Readyz},
+		doc:         readyzDoc(),
+		unversioned: true,
+	}, route{
+		method:      "GET",
+		path:        "/status",
+		handlers:    []gin.HandlerFunc{h.GetStatus},
+		doc:         statusDoc(),
+		unversioned: true,
+	}, route{
+		method:      "GET",
+		path:        "/version",
+		handlers:    []gin.HandlerFunc{h.GetVersion},
+		doc:         versionDoc(),
+		unversioned: true,
+	}, route{
+		method:      "GET",
+		path:        "/admin/breakers",
+		handlers:    []gin.HandlerFunc{h.authenticateAdmin(), h.GetBreakers},
+		doc:         breakersDoc(),
+		unversioned: true,
+	})
+
+	if h.reload != nil {
+		routes = append(routes, route{
+			method:      "POST",
+			path:        "/admin/reload",
+			handlers:    []gin.HandlerFunc{h.authenticateAdmin(), h.Reload},
+			doc:         reloadDoc(),
+			unversioned: true,
+		})
+	}
+
+	return routes
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"net/http"
+
+	"api-server/internal/infra/health"
+	"api-server/pkg/circuitbreaker"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+)
+
+// WithHealth enables readiness and upstream status reporting on the health routes.
+func WithHealth(readiness *health.Readiness, tracker *health.Tracker) Option {
+	return func(h *handler) {
+		h.readiness = readiness
+		h.tracker = tracker
+	}
+}
+
+func (h *handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+func (h *handler) Readyz(c *gin.Context) {
+	if h.readiness != nil && !h.readiness.Ready() {
+		abortWithError(c, errNotReady)
+		return
+	}
+	c.JSON(http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+func (h *handler) GetStatus(c *gin.Context) {
+	breakerStates := make(map[string]string)
+	if h.breakers != nil {
+		for _, b := range h.breakers.Snapshots() {
+			breakerStates[b.Name] = b.State
+		}
+	}
+
+	res := StatusResponse{
+		Status:    statusOK,
+		Ready:     h.readiness == nil || h.readiness.Ready(),
+		Providers: []ProviderStatus{},
+	}
+
+	var statuses []health.ProviderStatus
+	if h.tracker != nil {
+		statuses = h.tracker.Statuses()
+	}
+	for _, s := range statuses {
+		provider := ProviderStatus{ProviderStatus: s, BreakerState: breakerStates[s.Name]}
+		if !s.Healthy() || provider.BreakerState == circuitbreaker.StateOpen.String() ||
+			(s.APIKeyValid != nil && !*s.APIKeyValid) {
+			res.Status = statusDegraded
+		}
+		res.Providers = append(res.Providers, provider)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
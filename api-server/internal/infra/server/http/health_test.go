@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-server/domain"
+	"api-server/internal/infra/health"
+	"api-server/pkg/circuitbreaker"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Health(t *testing.T) {
+	readiness := &health.Readiness{}
+	tracker := health.NewTracker(domain.ProviderBrasilAPI, domain.ProviderViaCEP)
+	breakers := circuitbreaker.NewRegistry()
+	breakers.Add(circuitbreaker.Settings{Name: domain.ProviderViaCEP, MinRequests: 1, CoolDown: time.Minute})
+
+	router, _, _ := setupFullRouter(t, WithHealth(readiness, tracker), WithBreakers(breakers))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should always be alive", func(t *testing.T) {
+		w := get("/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	})
+
+	t.Run("should only be ready after startup and before shutdown", func(t *testing.T) {
+		w := get("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"not_ready"`)
+
+		readiness.SetReady(true)
+		assert.Equal(t, http.StatusOK, get("/readyz").Code)
+
+		readiness.SetReady(false)
+		assert.Equal(t, http.StatusServiceUnavailable, get("/readyz").Code)
+	})
+
+	t.Run("should report each provider with its breaker state", func(t *testing.T) {
+		ctx := context.Background()
+		tracker.ObserveProviderCall(ctx, domain.ProviderBrasilAPI, 80*time.Millisecond, nil)
+		tracker.ObserveProviderCall(ctx, domain.ProviderViaCEP, 1000*time.Millisecond, errors.New("timeout"))
+		_ = breakers.Get(domain.ProviderViaCEP).Execute(func() error { return errors.New("timeout") })
+
+		w := get("/status")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res StatusResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, statusDegraded, res.Status)
+		require.Len(t, res.Providers, 2)
+		assert.Equal(t, domain.ProviderBrasilAPI, res.Providers[0].Name)
+		assert.Equal(t, int64(80), res.Providers[0].LastLatencyMS)
+		assert.NotNil(t, res.Providers[0].LastSuccess)
+		assert.Equal(t, domain.ProviderViaCEP, res.Providers[1].Name)
+		assert.Equal(t, "open", res.Providers[1].BreakerState)
+		assert.Equal(t, "timeout", res.Providers[1].LastError)
+
+		doc := getSpec(t, router)
+		assertDocumentedResponse(t, doc, "/status", "GET", w)
+	})
+}
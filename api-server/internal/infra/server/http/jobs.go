@@ -0,0 +1,264 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/internal/infra/jobs"
+	"api-server/pkg/logger"
+	"api-server/pkg/temperature"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultJobMaxSize  = 50000
+	maxJobBodyBytes    = 8 << 20
+	maxIdempotencyKey  = 255
+	headerIdempotency  = "Idempotency-Key"
+	headerJobStatus    = "X-Job-Status"
+	contentTypeNDJSON  = "application/x-ndjson"
+	jobResultFlushSize = 100
+
+	formatNDJSON format = "ndjson"
+)
+
+// jobResultOffers are the formats of the job results, NDJSON by default.
+var jobResultOffers = []offer{
+	{contentTypeNDJSON, formatNDJSON},
+	{"text/csv", formatCSV},
+}
+
+type jobsOptions struct {
+	runner  *jobs.Runner
+	maxSize int
+}
+
+// WithJobs serves the asynchronous batch jobs run by runner, of at most maxSize CEPs each.
+func WithJobs(runner *jobs.Runner, maxSize int) Option {
+	return func(h *handler) {
+		h.jobs = jobsOptions{runner: runner, maxSize: maxSize}
+	}
+}
+
+// jobOwner scopes the jobs to the API key that created them; without API keys every job is shared.
+func jobOwner(c *gin.Context) string {
+	key, _ := apiKeyFromContext(c)
+	return key.Name
+}
+
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return errJobNotFound
+	case errors.Is(err, jobs.ErrIdempotencyConflict):
+		return errIdempotencyConflict
+	case errors.Is(err, jobs.ErrQueueFull):
+		return errJobQueueFull
+	case errors.Is(err, jobs.ErrFinished):
+		return errJobFinished
+	}
+	return err
+}
+
+func newJobResponse(job jobs.Job) JobResponse {
+	return JobResponse{
+		ID:         job.ID,
+		Status:     string(job.Status),
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Succeeded:  job.Processed - job.Failed,
+		Failed:     job.Failed,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+		ResultURL:  jobURL(job.ID) + "/result",
+	}
+}
+
+func jobURL(id string) string {
+	return apiVersionPrefix + "/jobs/" + id
+}
+
+func (h *handler) CreateJob(c *gin.Context) {
+	key := c.GetHeader(headerIdempotency)
+	if len(key) > maxIdempotencyKey {
+		abortWithError(c, errInvalidParameter.withDetail(headerIdempotency+": at most "+strconv.Itoa(maxIdempotencyKey)+" characters"))
+		return
+	}
+
+	inputs, err := readBatch(c, maxJobBodyBytes, h.jobs.maxSize)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	// Como num batch, cada CEP distinto além do já cobrado pelos middlewares custa uma consulta
+	n := distinctCEPs(inputs) - 1
+	if !h.allowN(c, n) || !h.meterN(c, n) {
+		return
+	}
+
+	job, created, err := h.jobs.runner.Submit(c.Request.Context(), jobOwner(c), key, inputs)
+	if !created {
+		// Nada foi enfileirado (repetição ou erro): a quota cobrada pelos CEPs volta
+		h.refund(c, n)
+	}
+	if err != nil {
+		abortWithError(c, jobError(err))
+		return
+	}
+
+	c.Header("Location", jobURL(job.ID))
+	if !created {
+		// Repetição com a mesma Idempotency-Key: devolve o job já criado
+		c.JSON(http.StatusOK, newJobResponse(job))
+		return
+	}
+	h.logger(c).InfoContext(c.Request.Context(), "Job created", "job_id", job.ID, "total", job.Total)
+	c.JSON(http.StatusAccepted, newJobResponse(job))
+}
+
+func (h *handler) GetJob(c *gin.Context) {
+	job, err := h.jobs.runner.Get(c.Request.Context(), jobOwner(c), c.Param("id"))
+	if err != nil {
+		abortWithError(c, jobError(err))
+		return
+	}
+	c.JSON(http.StatusOK, newJobResponse(job))
+}
+
+func (h *handler) CancelJob(c *gin.Context) {
+	job, err := h.jobs.runner.Cancel(c.Request.Context(), jobOwner(c), c.Param("id"))
+	if err != nil {
+		abortWithError(c, jobError(err))
+		return
+	}
+	h.logger(c).InfoContext(c.Request.Context(), "Job canceled", "job_id", job.ID)
+	c.JSON(http.StatusOK, newJobResponse(job))
+}
+
+// GetJobResult streams the results stored so far, as NDJSON or CSV. X-Job-Status tells whether
+// the job is still running, in which case the results are partial.
+func (h *handler) GetJobResult(c *gin.Context) {
+	opts, err := h.parseTemperatureOptions(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	asCSV, err := jobResultFormat(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	owner, id := jobOwner(c), c.Param("id")
+	job, err := h.jobs.runner.Get(ctx, owner, id)
+	if err != nil {
+		abortWithError(c, jobError(err))
+		return
+	}
+
+	// O WriteTimeout do servidor cortaria o resultado de um job grande: sem prazo para esta resposta
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger(c).WarnContext(ctx, "Could not lift the write deadline of the job result", logger.Err(err))
+	}
+
+	c.Header("Vary", "Accept")
+	c.Header(headerJobStatus, string(job.Status))
+
+	var write func(JobResultResponse) error
+	var flush func() error
+	if asCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write(jobResultCSVHeader(opts))
+		write = func(res JobResultResponse) error {
+			return w.Write(res.csvRecord(opts))
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		c.Header("Content-Type", contentTypeNDJSON)
+		enc := json.NewEncoder(c.Writer)
+		write = func(res JobResultResponse) error {
+			return enc.Encode(res)
+		}
+		flush = func() error {
+			return nil
+		}
+	}
+
+	c.Status(http.StatusOK)
+
+	written := 0
+	err = h.jobs.runner.Results(ctx, owner, id, func(result jobs.Result) error {
+		if err := write(JobResultResponse{Index: result.Index, BatchItemResponse: newBatchItemResponse(result, opts)}); err != nil {
+			return err
+		}
+		if written++; written%jobResultFlushSize == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// O status já foi enviado: só resta interromper o stream
+		h.logger(c).WarnContext(ctx, "Job result stream interrupted", "job_id", id, "written", written, logger.Err(err))
+		return
+	}
+	c.Writer.Flush()
+}
+
+// jobResultFormat picks CSV by ?format=csv or an Accept preferring text/csv, and NDJSON otherwise.
+func jobResultFormat(c *gin.Context) (bool, error) {
+	switch strings.ToLower(c.Query(queryFormat)) {
+	case "csv":
+		return true, nil
+	case "ndjson":
+		return false, nil
+	case "":
+		c.Header("Vary", "Accept")
+		f, ok := acceptedFormat(c.GetHeader("Accept"), jobResultOffers)
+		if !ok {
+			return false, errNotAcceptable.withDetail("supported formats: " + contentTypeNDJSON + ", text/csv")
+		}
+		return f == formatCSV, nil
+	}
+	return false, errInvalidParameter.withDetail("format: must be ndjson or csv")
+}
+
+// jobResultCSVHeader has one temperature column per requested unit, in the order of TemperatureResponse.
+func jobResultCSVHeader(opts temperatureOptions) []string {
+	header := []string{"index", "input", "cep", "city"}
+	header = append(header, newTemperatureResponse(temperature.FromCelsius(0), opts).CSVHeader()...)
+	return append(header, "error_code", "error_detail")
+}
+
+func (r JobResultResponse) csvRecord(opts temperatureOptions) []string {
+	record := []string{strconv.Itoa(r.Index), r.Input, r.CEP, r.City}
+	temps := make([]string, len(opts.units))
+	if r.Temperature != nil {
+		for i, f := range r.Temperature.fields() {
+			temps[i] = formatFloat(*f.value)
+		}
+	}
+	record = append(record, temps...)
+	if r.Error != nil {
+		return append(record, r.Error.Code, r.Error.Detail)
+	}
+	return append(record, "", "")
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-server/domain/analysis"
+	"api-server/domain/mocks"
+	"api-server/internal/infra/jobs"
+	"api-server/pkg/apikey"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupJobsRouter(t *testing.T, opts ...Option) *gin.Engine {
+	t.Helper()
+	mockBuscaCEP := &mocks.MockBuscaCEPAPIClient{
+		GetBrasilAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+			if cep == "01001000" {
+				return "São Paulo,SP", nil
+			}
+			return "", errors.New("not found")
+		},
+		GetViaAPICEPFunc: func(ctx context.Context, cep string) (string, error) {
+			return "", errors.New("not found")
+		},
+	}
+	mockWeather := &mocks.MockWeatherAPIClient{
+		GetHGWeatherAPIFunc: func(ctx context.Context, city string) (int, error) {
+			return 25, nil
+		},
+	}
+	service := analysis.NewAnalysisService(mockBuscaCEP, mockWeather, slog.Default())
+
+	runner := jobs.NewRunner(jobs.NewMemoryStore(), func() jobs.Processor {
+		return NewBatchProcessor(service, 2)
+	}, slog.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = runner.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	router := NewHandler(service, slog.Default(), append([]Option{WithJobs(runner, 3)}, opts...)...)
+	gin.SetMode(gin.TestMode)
+	return router
+}
+
+func TestHandler_Jobs(t *testing.T) {
+	router := setupJobsRouter(t)
+	doc := getSpec(t, router)
+
+	do := func(method, path, idempotencyKey string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if idempotencyKey != "" {
+			req.Header.Set(headerIdempotency, idempotencyKey)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(idempotencyKey, body string) (*httptest.ResponseRecorder, JobResponse) {
+		w := do("POST", "/v1/jobs", idempotencyKey, []byte(body))
+		assertDocumentedResponse(t, doc, "/v1/jobs", "POST", w)
+		var res JobResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w, res
+	}
+	waitSucceeded := func(id string) JobResponse {
+		var res JobResponse
+		require.Eventually(t, func() bool {
+			w := do("GET", "/v1/jobs/"+id, "", nil)
+			assertDocumentedResponse(t, doc, "/v1/jobs/{id}", "GET", w)
+			_ = json.Unmarshal(w.Body.Bytes(), &res)
+			return res.Status == string(jobs.StatusSucceeded)
+		}, 2*time.Second, 10*time.Millisecond)
+		return res
+	}
+
+	t.Run("should run the job and stream its results", func(t *testing.T) {
+		w, job := create("", `["01001000", "99999999", "123"]`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/v1/jobs/"+job.ID, w.Header().Get("Location"))
+		assert.Equal(t, 3, job.Total)
+
+		job = waitSucceeded(job.ID)
+		assert.Equal(t, 3, job.Processed)
+		assert.Equal(t, 1, job.Succeeded)
+		assert.Equal(t, 2, job.Failed)
+
+		w = do("GET", job.ResultURL+"?units=C", "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, contentTypeNDJSON, w.Header().Get("Content-Type"))
+		assert.Equal(t, "succeeded", w.Header().Get(headerJobStatus))
+
+		var lines []JobResultResponse
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var line JobResultResponse
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		require.Len(t, lines, 3)
+		assert.Equal(t, "São Paulo,SP", lines[0].City)
+		assert.Equal(t, 25.0, *lines[0].Temperature.TempC)
+		assert.Nil(t, lines[0].Temperature.TempF)
+		assert.Equal(t, "cep_not_found", lines[1].Error.Code)
+		assert.Equal(t, 2, lines[2].Index)
+		assert.Equal(t, "invalid_cep", lines[2].Error.Code)
+
+		accept := func(accept string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", job.ResultURL, nil)
+			req.Header.Set("Accept", accept)
+			router.ServeHTTP(w, req)
+			return w
+		}
+		assert.Equal(t, "text/csv; charset=utf-8", accept("text/csv, application/x-ndjson").Header().Get("Content-Type"))
+		assert.Equal(t, contentTypeNDJSON, accept("text/csv;q=0.5, application/x-ndjson").Header().Get("Content-Type"))
+		assert.Equal(t, "text/csv; charset=utf-8", accept("application/json, text/*;q=0.8").Header().Get("Content-Type"))
+		w = accept("application/xml")
+		assertDocumentedResponse(t, doc, "/v1/jobs/{id}/result", "GET", w)
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+		w = do("GET", job.ResultURL+"?format=csv&units=C,F", "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"index", "input", "cep", "city", "temp_C", "temp_F", "error_code", "error_detail"}, records[0])
+		assert.Equal(t, []string{"0", "01001000", "01001-000", "São Paulo,SP", "25", "77", "", ""}, records[1])
+		assert.Equal(t, "cep_not_found", records[2][6])
+
+		w = do("DELETE", "/v1/jobs/"+job.ID, "", nil)
+		assertDocumentedResponse(t, doc, "/v1/jobs/{id}", "DELETE", w)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("should replay a request with the same Idempotency-Key", func(t *testing.T) {
+		w, first := create("order-42", `["01001000"]`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		w, again := create("order-42", `["01001000"]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, first.ID, again.ID)
+
+		w, _ = create("order-42", `["99999999"]`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("should reject invalid requests", func(t *testing.T) {
+		w, _ := create("", `["01001000","01001000","01001000","01001000"]`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		w = do("GET", "/v1/jobs/unknown", "", nil)
+		assertDocumentedResponse(t, doc, "/v1/jobs/{id}", "GET", w)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do("GET", "/v1/jobs/unknown/result?format=xml", "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_CreateJob_Charges(t *testing.T) {
+	keys, err := apikey.NewStore([]apikey.Key{{Name: "erp", Hash: apikey.Hash("erp-secret"), DailyQuota: 5}})
+	require.NoError(t, err)
+	router := setupJobsRouter(t, WithAPIKeys(keys))
+
+	create := func(idempotencyKey, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/jobs", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(headerAPIKey, "erp-secret")
+		req.Header.Set(headerIdempotency, idempotencyKey)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create("order-1", `["01001000", "01001-000", "99999999"]`)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "3", w.Header().Get("X-Quota-Remaining"), "one lookup per distinct valid CEP")
+
+	w = create("order-1", `["01001000", "01001-000", "99999999"]`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Remaining"), "a replay costs only the request")
+
+	w = create("order-2", `["01001000", "01310100", "20040020"]`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"quota_exceeded"`)
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"api-server/internal/infra/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+const unmatchedRoute = "unmatched"
+
+// WithMetrics instruments every request and serves the metrics on /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(h *handler) {
+		h.metrics = m
+	}
+}
+
+func (h *handler) instrument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := h.now()
+		done := h.metrics.RequestStarted()
+
+		c.Next()
+
+		// Rotas inexistentes ficam num único label para não explodir a cardinalidade
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		done(route, c.Request.Method, c.Writer.Status(), h.now().Sub(start))
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-server/internal/infra/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Metrics(t *testing.T) {
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t, WithMetrics(metrics.New()))
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "São Paulo,SP", nil
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}
+
+	for _, path := range []string{"/v1/tempForCep/01001000", "/v1/tempForCep/123", "/v1/tempForCep/456", "/nope"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	body := w.Body.String()
+	assert.Contains(t, body, `api_server_http_requests_total{method="GET",route="/v1/tempForCep/:cep",status="200"} 1`)
+	assert.Contains(t, body, `api_server_http_requests_total{method="GET",route="/v1/tempForCep/:cep",status="422"} 2`)
+	assert.Contains(t, body, `api_server_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, "api_server_http_requests_in_flight 1", "the scrape itself is in flight")
+}
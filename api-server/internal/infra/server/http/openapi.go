@@ -0,0 +1,551 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-server/pkg/openapi"
+	"api-server/pkg/temperature"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	securitySchemeAPIKey     = "apiKey"
+	securitySchemeAdminToken = "adminToken"
+)
+
+type operationDoc struct {
+	id        string
+	summary   string
+	tag       string
+	params    []openapi.Parameter
+	body      *openapi.RequestBody
+	secured   bool
+	admin     bool
+	responses []responseDoc
+}
+
+type responseDoc struct {
+	status      int
+	description string
+	body        any
+	headers     []string
+	// negotiated responses are also available as XML, CSV and plain text.
+	negotiated bool
+	// contentType replaces application/json, e.g. for NDJSON streams, whose body documents one line.
+	contentType string
+	// alternatives are other media types of the body, documented as plain strings.
+	alternatives []string
+}
+
+var headerDocs = map[string]string{
+	"RateLimit-Limit":     "Bucket size of the caller's rate limit tier",
+	"RateLimit-Remaining": "Requests left in the bucket",
+	"RateLimit-Reset":     "Seconds until the bucket is full again",
+	"Retry-After":         "Seconds to wait before retrying",
+	"X-Quota-Limit":       "Daily quota of the API key",
+	"X-Quota-Remaining":   "Requests left in today's quota",
+	"Location":            "URL of the job",
+	headerJobStatus:       "Status of the job; the results are partial unless it is succeeded",
+	headerRequestID:       "ID of the request, echoed from the request header or generated",
+}
+
+var rateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}
+
+func cepParam() openapi.Parameter {
+	return openapi.Parameter{
+		Name:        "cep",
+		In:          "path",
+		Description: "Brazilian postal code, 8 digits, optionally as 00000-000",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string", Pattern: "^[0-9]{5}-?[0-9]{3}$"},
+	}
+}
+
+func temperatureDoc(secured bool) operationDoc {
+	doc := operationDoc{
+		id:      "getTemperatureForCEP",
+		summary: "Current temperature of the city of a CEP",
+		tag:     "temperature",
+		params: append(append([]openapi.Parameter{cepParam()}, temperatureParams()...), openapi.Parameter{
+			Name:        "format",
+			In:          "query",
+			Description: "Response format, overrides the Accept header",
+			Schema:      &openapi.Schema{Type: "string", Enum: []string{"json", "xml", "csv", "text"}},
+		}),
+		secured: secured,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Temperature found", body: TemperatureResponse{}, negotiated: true,
+				headers: append(rateLimitHeaders, "X-Quota-Limit", "X-Quota-Remaining")},
+			{status: http.StatusBadRequest, description: "Invalid units, precision or rounding", body: Problem{}},
+			{status: http.StatusNotFound, description: "CEP not found", body: Problem{}},
+			{status: http.StatusNotAcceptable, description: "None of the accepted media types can be produced", body: Problem{}},
+			{status: http.StatusUnprocessableEntity, description: "Invalid CEP", body: Problem{}},
+			{status: http.StatusTooManyRequests, description: "Rate limit or daily quota exceeded", body: Problem{},
+				headers: append(rateLimitHeaders, "Retry-After")},
+			{status: http.StatusInternalServerError, description: "Temperature could not be retrieved", body: Problem{}},
+			{status: http.StatusServiceUnavailable, description: "Upstream providers unavailable (circuit breakers open)", body: Problem{}},
+		},
+	}
+	if secured {
+		doc.responses = append(doc.responses,
+			responseDoc{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			responseDoc{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		)
+	}
+	return doc
+}
+
+// temperatureParams are the query parameters shaping the temperatures of a response.
+func temperatureParams() []openapi.Parameter {
+	return []openapi.Parameter{{
+		Name:        "units",
+		In:          "query",
+		Description: "Comma separated units: C, F, K, R (Rankine), Re (Réaumur), De (Delisle). Defaults to C,F,K",
+		Schema:      &openapi.Schema{Type: "string"},
+	}, {
+		Name:        "precision",
+		In:          "query",
+		Description: "Decimal places, 0 to " + strconv.Itoa(temperature.MaxPrecision) + ". Defaults to " + strconv.Itoa(defaultTemperaturePrecision),
+		Schema:      &openapi.Schema{Type: "integer"},
+	}, {
+		Name:        "rounding",
+		In:          "query",
+		Description: "Rounding mode for halves. Defaults to half_up",
+		Schema:      &openapi.Schema{Type: "string", Enum: []string{"half_up", "half_even"}},
+	}}
+}
+
+func batchDoc(secured bool, maxSize int) operationDoc {
+	doc := operationDoc{
+		id:      "getTemperatureForCEPBatch",
+		summary: "Current temperature of up to " + strconv.Itoa(maxSize) + " CEPs at once",
+		tag:     "temperature",
+		params:  temperatureParams(),
+		body:    batchBody(),
+		secured: secured,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "One result or error per CEP", body: BatchResponse{},
+				headers: append(rateLimitHeaders, "X-Quota-Limit", "X-Quota-Remaining")},
+			{status: http.StatusBadRequest, description: "Invalid body, units, precision or rounding", body: Problem{}},
+			{status: http.StatusRequestEntityTooLarge, description: "Too many CEPs", body: Problem{}},
+			{status: http.StatusUnsupportedMediaType, description: "Body is not JSON, CSV or multipart", body: Problem{}},
+			{status: http.StatusTooManyRequests, description: "Rate limit or daily quota exceeded, each distinct CEP counting as one lookup", body: Problem{},
+				headers: append(rateLimitHeaders, "Retry-After")},
+		},
+	}
+	if secured {
+		doc.responses = append(doc.responses,
+			responseDoc{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			responseDoc{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		)
+	}
+	return doc
+}
+
+func streamDoc(secured bool) operationDoc {
+	doc := operationDoc{
+		id:      "streamTemperatureForCEP",
+		summary: "Server-Sent Events with the temperature of the city of a CEP, sent whenever it changes",
+		tag:     "temperature",
+		params: append([]openapi.Parameter{cepParam(), {
+			Name:        "Last-Event-ID",
+			In:          "header",
+			Description: "ID of the last event received; the current temperature is skipped when it is the same",
+			Schema:      &openapi.Schema{Type: "string"},
+		}}, temperatureParams()...),
+		secured: secured,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Stream of temperature events, whose data is a TemperatureEvent, and heartbeat comments",
+				body: TemperatureEvent{}, contentType: contentTypeEventStream,
+				headers: append(rateLimitHeaders, "X-Quota-Limit", "X-Quota-Remaining")},
+			{status: http.StatusBadRequest, description: "Invalid units, precision or rounding", body: Problem{}},
+			{status: http.StatusNotFound, description: "CEP not found", body: Problem{}},
+			{status: http.StatusUnprocessableEntity, description: "Invalid CEP", body: Problem{}},
+			{status: http.StatusTooManyRequests, description: "Rate limit or daily quota exceeded", body: Problem{},
+				headers: append(rateLimitHeaders, "Retry-After")},
+			{status: http.StatusServiceUnavailable, description: "Upstream providers unavailable, or the instance is shutting down", body: Problem{}},
+		},
+	}
+	if secured {
+		doc.responses = append(doc.responses,
+			responseDoc{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			responseDoc{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		)
+	}
+	return doc
+}
+
+// batchBody documents the CEPs of a batch: a JSON array, a CSV or a CSV upload.
+func batchBody() *openapi.RequestBody {
+	csvSchema := &openapi.Schema{Type: "string", Description: "CSV with a cep column, or the CEPs in the first column"}
+	return &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+		"application/json": {Schema: &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}},
+		"text/csv":         {Schema: csvSchema},
+		"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+			batchFormField: {Type: "string", Format: "binary", Description: csvSchema.Description},
+		}}},
+	}}
+}
+
+var jobIDParam = openapi.Parameter{
+	Name:        "id",
+	In:          "path",
+	Description: "Job ID, as returned on creation",
+	Required:    true,
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+// jobAuthResponses are the failures common to every job route.
+func jobAuthResponses(secured bool) []responseDoc {
+	responses := []responseDoc{
+		{status: http.StatusTooManyRequests, description: "Rate limit exceeded", body: Problem{},
+			headers: append(rateLimitHeaders, "Retry-After")},
+	}
+	if secured {
+		responses = append(responses,
+			responseDoc{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			responseDoc{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		)
+	}
+	return responses
+}
+
+func createJobDoc(secured bool, maxSize int) operationDoc {
+	return operationDoc{
+		id:      "createJob",
+		summary: "Look up the temperature of up to " + strconv.Itoa(maxSize) + " CEPs in background",
+		tag:     "jobs",
+		params: []openapi.Parameter{{
+			Name:        headerIdempotency,
+			In:          "header",
+			Description: "Retrying with the same key returns the job already created instead of a new one",
+			Schema:      &openapi.Schema{Type: "string"},
+		}},
+		body:    batchBody(),
+		secured: secured,
+		responses: append([]responseDoc{
+			{status: http.StatusAccepted, description: "Job queued", body: JobResponse{},
+				headers: []string{"Location", "X-Quota-Limit", "X-Quota-Remaining"}},
+			{status: http.StatusOK, description: "Job already created with this Idempotency-Key", body: JobResponse{},
+				headers: []string{"Location"}},
+			{status: http.StatusBadRequest, description: "Invalid body or Idempotency-Key", body: Problem{}},
+			{status: http.StatusConflict, description: "Idempotency-Key already used with a different body", body: Problem{}},
+			{status: http.StatusRequestEntityTooLarge, description: "Too many CEPs", body: Problem{}},
+			{status: http.StatusUnsupportedMediaType, description: "Body is not JSON, CSV or multipart", body: Problem{}},
+			{status: http.StatusServiceUnavailable, description: "Too many jobs waiting", body: Problem{}},
+		}, jobAuthResponses(secured)...),
+	}
+}
+
+func jobDoc(secured bool) operationDoc {
+	return operationDoc{
+		id:      "getJob",
+		summary: "Status and progress of a job",
+		tag:     "jobs",
+		params:  []openapi.Parameter{jobIDParam},
+		secured: secured,
+		responses: append([]responseDoc{
+			{status: http.StatusOK, description: "The job", body: JobResponse{}},
+			{status: http.StatusNotFound, description: "No such job for the API key", body: Problem{}},
+		}, jobAuthResponses(secured)...),
+	}
+}
+
+func cancelJobDoc(secured bool) operationDoc {
+	return operationDoc{
+		id:      "cancelJob",
+		summary: "Cancel a queued or running job, keeping the results so far",
+		tag:     "jobs",
+		params:  []openapi.Parameter{jobIDParam},
+		secured: secured,
+		responses: append([]responseDoc{
+			{status: http.StatusOK, description: "Job canceled", body: JobResponse{}},
+			{status: http.StatusNotFound, description: "No such job for the API key", body: Problem{}},
+			{status: http.StatusConflict, description: "Job already finished", body: Problem{}},
+		}, jobAuthResponses(secured)...),
+	}
+}
+
+func jobResultDoc(secured bool) operationDoc {
+	return operationDoc{
+		id:      "getJobResult",
+		summary: "Results of a job, one per CEP, as NDJSON or CSV",
+		tag:     "jobs",
+		params: append([]openapi.Parameter{jobIDParam, {
+			Name:        queryFormat,
+			In:          "query",
+			Description: "ndjson or csv; overrides the Accept header. Defaults to ndjson",
+			Schema:      &openapi.Schema{Type: "string", Enum: []string{"ndjson", "csv"}},
+		}}, temperatureParams()...),
+		secured: secured,
+		responses: append([]responseDoc{
+			{status: http.StatusOK, description: "Results so far, in the order of the request", body: JobResultResponse{},
+				headers: []string{headerJobStatus}, contentType: contentTypeNDJSON, alternatives: []string{"text/csv"}},
+			{status: http.StatusBadRequest, description: "Invalid format, units, precision or rounding", body: Problem{}},
+			{status: http.StatusNotFound, description: "No such job for the API key", body: Problem{}},
+			{status: http.StatusNotAcceptable, description: "Accept allows neither NDJSON nor CSV", body: Problem{}},
+		}, jobAuthResponses(secured)...),
+	}
+}
+
+func searchDoc(secured bool) operationDoc {
+	doc := operationDoc{
+		id:      "searchCEP",
+		summary: "CEPs of the streets of a city, optionally with the current temperature of the city",
+		tag:     "cep",
+		params: append([]openapi.Parameter{{
+			Name:        "uf",
+			In:          "query",
+			Description: "State code, e.g. SP",
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string", Pattern: "^[A-Za-z]{2}$"},
+		}, {
+			Name:        "city",
+			In:          "query",
+			Description: "City name, at least 3 characters",
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string"},
+		}, {
+			Name:        "street",
+			In:          "query",
+			Description: "Street name or part of it, at least 3 characters",
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string"},
+		}, {
+			Name:        "temperature",
+			In:          "query",
+			Description: "Also return the current temperature of the city. Defaults to false",
+			Schema:      &openapi.Schema{Type: "boolean"},
+		}}, temperatureParams()...),
+		secured: secured,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Matching addresses, with temperature_error instead of the temperature when it could not be found", body: CEPSearchResponse{},
+				headers: append(rateLimitHeaders, "X-Quota-Limit", "X-Quota-Remaining")},
+			{status: http.StatusBadRequest, description: "Invalid uf, city, street or temperature parameters", body: Problem{}},
+			{status: http.StatusTooManyRequests, description: "Rate limit or daily quota exceeded", body: Problem{},
+				headers: append(rateLimitHeaders, "Retry-After")},
+			{status: http.StatusBadGateway, description: "Search failed upstream", body: Problem{}},
+			{status: http.StatusServiceUnavailable, description: "ViaCEP disabled or its circuit breaker open", body: Problem{}},
+		},
+	}
+	if secured {
+		doc.responses = append(doc.responses,
+			responseDoc{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			responseDoc{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		)
+	}
+	return doc
+}
+
+func usageDoc() operationDoc {
+	return operationDoc{
+		id:      "getAPIKeyUsage",
+		summary: "Daily usage of the calling API key",
+		tag:     "api-keys",
+		secured: true,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Usage of the key", body: UsageResponse{}},
+			{status: http.StatusUnauthorized, description: "Invalid or missing API key", body: Problem{}},
+			{status: http.StatusForbidden, description: "Endpoint not allowed for the API key", body: Problem{}},
+		},
+	}
+}
+
+func healthzDoc() operationDoc {
+	return operationDoc{
+		id:      "getLiveness",
+		summary: "Liveness probe",
+		tag:     "health",
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Process is alive", body: HealthResponse{}},
+		},
+	}
+}
+
+func readyzDoc() operationDoc {
+	return operationDoc{
+		id:      "getReadiness",
+		summary: "Readiness probe: ready after startup and until shutdown starts",
+		tag:     "health",
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Ready to receive traffic", body: HealthResponse{}},
+			{status: http.StatusServiceUnavailable, description: "Starting or draining", body: Problem{}},
+		},
+	}
+}
+
+func statusDoc() operationDoc {
+	return operationDoc{
+		id:      "getStatus",
+		summary: "Status of each upstream provider",
+		tag:     "health",
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Upstream status", body: StatusResponse{}},
+		},
+	}
+}
+
+func versionDoc() operationDoc {
+	return operationDoc{
+		id:      "getVersion",
+		summary: "Build and Cloud Run revision of the instance",
+		tag:     "health",
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Version of the instance", body: VersionResponse{}},
+		},
+	}
+}
+
+func breakersDoc() operationDoc {
+	return operationDoc{
+		id:      "getCircuitBreakers",
+		summary: "State of the upstream circuit breakers",
+		tag:     "admin",
+		admin:   true,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Breakers state", body: BreakersResponse{}},
+			{status: http.StatusUnauthorized, description: "Invalid or missing admin token", body: Problem{}},
+			{status: http.StatusForbidden, description: "No admin token configured", body: Problem{}},
+		},
+	}
+}
+
+func reloadDoc() operationDoc {
+	return operationDoc{
+		id:      "reloadConfiguration",
+		summary: "Reload the configuration file and apply the reloadable settings",
+		tag:     "admin",
+		admin:   true,
+		responses: []responseDoc{
+			{status: http.StatusOK, description: "Configuration reloaded", body: ReloadResponse{}},
+			{status: http.StatusUnauthorized, description: "Invalid or missing admin token", body: Problem{}},
+			{status: http.StatusForbidden, description: "No admin token configured", body: Problem{}},
+			{status: http.StatusUnprocessableEntity, description: "Invalid configuration, nothing applied", body: Problem{}},
+		},
+	}
+}
+
+// buildSpec generates the OpenAPI document from the registered routes.
+func buildSpec(routes []route) *openapi.Document {
+	gen := openapi.NewGenerator()
+	doc := &openapi.Document{
+		OpenAPI: "3.1.0",
+		Info: openapi.Info{
+			Title:       "Temperature from CEP API",
+			Description: "Current temperature in Celsius, Fahrenheit and Kelvin for a Brazilian CEP.",
+			Version:     "1.0.0",
+		},
+		Paths: make(map[string]*openapi.PathItem),
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				securitySchemeAPIKey:     {Type: "apiKey", In: "header", Name: headerAPIKey},
+				securitySchemeAdminToken: {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+
+	for _, r := range routes {
+		addOperation(doc, gen, r.fullPath(), r, false)
+		if r.legacy {
+			addOperation(doc, gen, r.path, r, true)
+		}
+	}
+
+	doc.Components.Schemas = gen.Schemas
+	return doc
+}
+
+func addOperation(doc *openapi.Document, gen *openapi.Generator, path string, r route, deprecated bool) {
+	op := &openapi.Operation{
+		OperationID: r.doc.id,
+		Summary:     r.doc.summary,
+		Tags:        []string{r.doc.tag},
+		Deprecated:  deprecated,
+		Parameters:  r.doc.params,
+		RequestBody: r.doc.body,
+		Responses:   make(map[string]openapi.Response),
+	}
+	if deprecated {
+		op.OperationID += "Legacy"
+	}
+	if r.doc.secured {
+		op.Security = []map[string][]string{{securitySchemeAPIKey: {}}}
+	}
+	if r.doc.admin {
+		op.Security = []map[string][]string{{securitySchemeAdminToken: {}}}
+	}
+
+	for _, res := range r.doc.responses {
+		response := openapi.Response{Description: res.description}
+		if res.body != nil {
+			contentType := "application/json"
+			if _, ok := res.body.(Problem); ok {
+				contentType = contentTypeProblemJSON
+			}
+			if res.contentType != "" {
+				contentType = res.contentType
+			}
+			response.Content = map[string]openapi.MediaType{
+				contentType: {Schema: gen.SchemaFor(res.body)},
+			}
+			for _, mediaType := range res.alternatives {
+				response.Content[mediaType] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+			}
+			if res.negotiated {
+				for _, mediaType := range mediaTypes() {
+					if _, ok := response.Content[mediaType]; !ok {
+						response.Content[mediaType] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+					}
+				}
+			}
+		}
+		res.headers = append([]string{headerRequestID}, res.headers...)
+		for _, name := range res.headers {
+			if response.Headers == nil {
+				response.Headers = make(map[string]openapi.Header)
+			}
+			schema := &openapi.Schema{Type: "integer"}
+			if name == headerRequestID || name == "Location" || name == headerJobStatus {
+				schema = &openapi.Schema{Type: "string"}
+			}
+			response.Headers[name] = openapi.Header{Description: headerDocs[name], Schema: schema}
+		}
+		op.Responses[strconv.Itoa(res.status)] = response
+	}
+
+	specPath := openAPIPath(path)
+	item, ok := doc.Paths[specPath]
+	if !ok {
+		item = &openapi.PathItem{}
+		doc.Paths[specPath] = item
+	}
+	slot := item.Operation(r.method)
+	if slot == nil {
+		// As rotas são fixas: um método sem campo no PathItem é erro de programação
+		panic("openapi: can not document method " + r.method + " of " + r.path)
+	}
+	*slot = op
+}
+
+// openAPIPath converts gin path parameters (":cep") to OpenAPI templates ("{cep}").
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// routePattern is the gin route pattern without the version prefix, as used in API key allow lists.
+func routePattern(c *gin.Context) string {
+	return strings.TrimPrefix(c.FullPath(), apiVersionPrefix)
+}
+
+func (h *handler) GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.spec)
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"api-server/domain/analysis"
+	"api-server/domain/mocks"
+	"api-server/internal/infra/jobs"
+	"api-server/internal/infra/stream"
+	"api-server/pkg/apikey"
+	"api-server/pkg/openapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupFullRouter(t *testing.T, opts ...Option) (*gin.Engine, *mocks.MockBuscaCEPAPIClient, *mocks.MockWeatherAPIClient) {
+	logger := slog.Default()
+
+	mockBuscaCEPClient := &mocks.MockBuscaCEPAPIClient{}
+	mockWeatherClient := &mocks.MockWeatherAPIClient{}
+	analysisService := analysis.NewAnalysisService(mockBuscaCEPClient, mockWeatherClient, logger)
+
+	router := NewHandler(analysisService, logger, opts...)
+	gin.SetMode(gin.TestMode)
+
+	return router, mockBuscaCEPClient, mockWeatherClient
+}
+
+func getSpec(t *testing.T, router *gin.Engine) *openapi.Document {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return &doc
+}
+
+// assertMatchesSchema checks a decoded JSON value against a schema of the document.
+func assertMatchesSchema(t *testing.T, doc *openapi.Document, schema *openapi.Schema, value any, path string) {
+	t.Helper()
+	schema = doc.Resolve(schema)
+	require.NotNil(t, schema, "unresolved schema at %s", path)
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		require.True(t, ok, "%s: expected object, got %T", path, value)
+		for _, name := range schema.Required {
+			assert.Contains(t, obj, name, "%s: missing required property", path)
+		}
+		for name, v := range obj {
+			if schema.AdditionalProperties != nil {
+				assertMatchesSchema(t, doc, schema.AdditionalProperties, v, path+"."+name)
+				continue
+			}
+			prop, ok := schema.Properties[name]
+			if assert.True(t, ok, "%s: undocumented property %q", path, name) {
+				assertMatchesSchema(t, doc, prop, v, path+"."+name)
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		require.True(t, ok, "%s: expected array, got %T", path, value)
+		for i, v := range arr {
+			assertMatchesSchema(t, doc, schema.Items, v, path+"["+strconv.Itoa(i)+"]")
+		}
+	case "string":
+		assert.IsType(t, "", value, path)
+	case "number", "integer":
+		assert.IsType(t, float64(0), value, path)
+	case "boolean":
+		assert.IsType(t, true, value, path)
+	}
+}
+
+func assertDocumentedResponse(t *testing.T, doc *openapi.Document, specPath, method string, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	item, ok := doc.Paths[specPath]
+	require.True(t, ok, "path %s not documented", specPath)
+	slot := item.Operation(method)
+	require.True(t, slot != nil && *slot != nil, "%s %s not documented", method, specPath)
+	op := *slot
+
+	res, ok := op.Responses[strconv.Itoa(w.Code)]
+	require.True(t, ok, "status %d of %s %s not documented", w.Code, method, specPath)
+
+	contentType, _, _ := strings.Cut(w.Header().Get("Content-Type"), ";")
+	media, ok := res.Content[contentType]
+	require.True(t, ok, "content type %s of %s %s %d not documented", contentType, method, specPath, w.Code)
+
+	var body any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assertMatchesSchema(t, doc, media.Schema, body, "body")
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	store, err := apikey.NewStore([]apikey.Key{{Name: "erp", Hash: apikey.Hash("secret")}})
+	require.NoError(t, err)
+
+	runner := jobs.NewRunner(jobs.NewMemoryStore(), nil, slog.Default())
+	hub := stream.NewHub(nil, slog.Default())
+	router, _, _ := setupFullRouter(t, WithAPIKeys(store), WithJobs(runner, 10), WithStream(hub, 0))
+	doc := getSpec(t, router)
+
+	for _, r := range router.Routes() {
+		if r.Path == "/openapi.json" {
+			continue
+		}
+
+		item, ok := doc.Paths[openAPIPath(r.Path)]
+		if assert.True(t, ok, "route %s %s is not documented", r.Method, r.Path) {
+			slot := item.Operation(r.Method)
+			assert.True(t, slot != nil && *slot != nil, "route %s %s is not documented", r.Method, r.Path)
+		}
+	}
+
+	assert.True(t, doc.Paths["/tempForCep/{cep}"].Get.Deprecated)
+	assert.False(t, doc.Paths["/v1/tempForCep/{cep}"].Get.Deprecated)
+}
+
+func TestOpenAPI_ResponsesMatchSchema(t *testing.T) {
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t)
+	doc := getSpec(t, router)
+
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		if cep == "99999999" {
+			return "", errors.New("not found")
+		}
+		return "São Paulo,SP", nil
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}
+
+	for _, tc := range []struct {
+		path   string
+		status int
+	}{
+		{"/v1/tempForCep/01001000", http.StatusOK},
+		{"/v1/tempForCep/123", http.StatusUnprocessableEntity},
+		{"/v1/tempForCep/99999999", http.StatusNotFound},
+		{"/tempForCep/01001000", http.StatusOK},
+		{"/admin/breakers", http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		route := "/v1/tempForCep/{cep}"
+		switch {
+		case tc.path == "/admin/breakers":
+			route = "/admin/breakers"
+		case tc.path == "/tempForCep/01001000":
+			route = "/tempForCep/{cep}"
+		}
+		assertDocumentedResponse(t, doc, route, "GET", w)
+	}
+
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 0, errors.New("weather api error")
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tempForCep/01001000", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertDocumentedResponse(t, doc, "/v1/tempForCep/{cep}", "GET", w)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"api-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contentTypeProblemJSON = "application/problem+json"
+	problemTypePrefix      = "urn:api-server:problem:"
+)
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type      string `json:"type" description:"URI identifying the problem type"`
+	Title     string `json:"title" description:"Short summary of the problem type"`
+	Status    int    `json:"status" description:"HTTP status code"`
+	Detail    string `json:"detail,omitempty" description:"Explanation specific to this occurrence"`
+	Instance  string `json:"instance" description:"Request path of this occurrence"`
+	Code      string `json:"code" description:"Stable machine-readable error code"`
+	RequestID string `json:"request_id" description:"ID of the request, also sent in the X-Request-ID header"`
+}
+
+// apiError is an error that maps to a problem response.
+type apiError struct {
+	status int
+	code   string
+	title  string
+	detail string
+	err    error
+}
+
+func (e *apiError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %s: %s", e.code, e.detail, e.err.Error())
+	}
+	return e.code + ": " + e.detail
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+func newAPIError(status int, code, title, detail string) *apiError {
+	return &apiError{status: status, code: code, title: title, detail: detail}
+}
+
+func (e *apiError) wrap(err error) *apiError {
+	wrapped := *e
+	wrapped.err = err
+	return &wrapped
+}
+
+func (e *apiError) withDetail(detail string) *apiError {
+	copied := *e
+	copied.detail = detail
+	return &copied
+}
+
+var (
+	errInvalidParameter       = newAPIError(http.StatusBadRequest, "invalid_parameter", "Invalid parameter", "invalid query parameter")
+	errInvalidBody            = newAPIError(http.StatusBadRequest, "invalid_body", "Invalid body", "invalid request body")
+	errBatchTooLarge          = newAPIError(http.StatusRequestEntityTooLarge, "batch_too_large", "Batch too large", "too many CEPs in the batch")
+	errUnsupportedMediaType   = newAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported media type", "supported types: application/json, text/csv, multipart/form-data")
+	errJobNotFound            = newAPIError(http.StatusNotFound, "job_not_found", "Job not found", "job not found")
+	errJobFinished            = newAPIError(http.StatusConflict, "job_finished", "Job finished", "job already finished")
+	errIdempotencyConflict    = newAPIError(http.StatusConflict, "idempotency_conflict", "Idempotency conflict", "Idempotency-Key already used with a different request")
+	errJobQueueFull           = newAPIError(http.StatusServiceUnavailable, "job_queue_full", "Job queue full", "too many jobs waiting, retry later")
+	errInvalidCEP             = newAPIError(http.StatusUnprocessableEntity, "invalid_cep", "Invalid CEP", "invalid zipcode")
+	errCEPNotFound            = newAPIError(http.StatusNotFound, "cep_not_found", "CEP not found", "can not find zipcode")
+	errSearchFailed           = newAPIError(http.StatusBadGateway, "search_failed", "Search failed", "can not search addresses")
+	errTemperatureUnavailable = newAPIError(http.StatusInternalServerError, "temperature_unavailable", "Temperature unavailable", "can not find temperature in Celsius")
+	errUpstreamUnavailable    = newAPIError(http.StatusServiceUnavailable, "upstream_unavailable", "Upstream unavailable", "upstream providers are temporarily unavailable")
+	errUnauthorized           = newAPIError(http.StatusUnauthorized, "unauthorized", "Unauthorized", "invalid or missing api key")
+	errForbidden              = newAPIError(http.StatusForbidden, "forbidden", "Forbidden", "endpoint not allowed for api key")
+	errAdminDisabled          = newAPIError(http.StatusForbidden, "admin_disabled", "Forbidden", "no admin token configured")
+	errInvalidConfig          = newAPIError(http.StatusUnprocessableEntity, "invalid_config", "Invalid configuration", "configuration not applied")
+	errRateLimited            = newAPIError(http.StatusTooManyRequests, "rate_limited", "Too many requests", "rate limit exceeded")
+	errQuotaExceeded          = newAPIError(http.StatusTooManyRequests, "quota_exceeded", "Daily quota exceeded", "daily quota exceeded")
+	errNotAcceptable          = newAPIError(http.StatusNotAcceptable, "not_acceptable", "Not acceptable", "supported formats: application/json, application/xml, text/csv, text/plain")
+	errNotReady               = newAPIError(http.StatusServiceUnavailable, "not_ready", "Not ready", "service is starting or shutting down")
+	errRouteNotFound          = newAPIError(http.StatusNotFound, "route_not_found", "Not found", "route not found")
+	errMethodNotAllowed       = newAPIError(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed", "method not allowed")
+	errInternal               = newAPIError(http.StatusInternalServerError, "internal_error", "Internal server error", "unexpected error")
+)
+
+// abortWithError stops the chain and leaves the error to handleErrors.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// handleErrors writes every error recorded in the chain as a problem response.
+func (h *handler) handleErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) {
+			apiErr = errInternal.wrap(err)
+		}
+
+		if apiErr.status >= http.StatusInternalServerError {
+			h.logger(c).ErrorContext(c.Request.Context(), "Request failed", "method", c.Request.Method, "path", c.Request.URL.Path,
+				logger.KeyStatus, apiErr.status, logger.Err(err))
+		}
+
+		writeProblem(c, apiErr)
+	}
+}
+
+func writeProblem(c *gin.Context, e *apiError) {
+	c.Header("Content-Type", contentTypeProblemJSON)
+	c.JSON(e.status, Problem{
+		Type:      problemTypePrefix + e.code,
+		Title:     e.title,
+		Status:    e.status,
+		Detail:    e.detail,
+		Instance:  c.Request.URL.Path,
+		Code:      e.code,
+		RequestID: requestIDFromContext(c),
+	})
+}
+
+func (h *handler) recovery() gin.HandlerFunc {
+	// O stack trace não vai para o stderr do gin: o log próprio já registra o panic
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		h.logger(c).ErrorContext(c.Request.Context(), "Panic recovered", "path", c.Request.URL.Path, "panic", fmt.Sprint(recovered))
+		abortWithError(c, errInternal)
+	})
+}
+
+func noRoute(c *gin.Context) {
+	abortWithError(c, errRouteNotFound)
+}
+
+func noMethod(c *gin.Context) {
+	abortWithError(c, errMethodNotAllowed)
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupProblemRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := &handler{log: slog.Default()}
+
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(requestID(), handler.handleErrors(), handler.recovery())
+	router.NoRoute(noRoute)
+	router.NoMethod(noMethod)
+	router.GET("/unexpected", func(c *gin.Context) { abortWithError(c, errors.New("boom")) })
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+	return router
+}
+
+func doProblem(t *testing.T, router *gin.Engine, method, path string, header http.Header) (*httptest.ResponseRecorder, Problem) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v[0])
+	}
+	router.ServeHTTP(w, req)
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	return w, problem
+}
+
+func TestHandler_HandleErrors(t *testing.T) {
+	router := setupProblemRouter()
+
+	t.Run("should map unknown errors to internal_error without leaking them", func(t *testing.T) {
+		w, problem := doProblem(t, router, "GET", "/unexpected", nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, contentTypeProblemJSON, w.Header().Get("Content-Type"))
+		assert.Equal(t, "internal_error", problem.Code)
+		assert.Equal(t, problemTypePrefix+"internal_error", problem.Type)
+		assert.NotContains(t, w.Body.String(), "boom")
+	})
+
+	t.Run("should map panics to internal_error", func(t *testing.T) {
+		w, problem := doProblem(t, router, "GET", "/panic", nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "internal_error", problem.Code)
+	})
+
+	t.Run("should answer unknown routes and methods with problems", func(t *testing.T) {
+		w, problem := doProblem(t, router, "GET", "/nowhere", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "route_not_found", problem.Code)
+
+		w, problem = doProblem(t, router, "POST", "/unexpected", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "method_not_allowed", problem.Code)
+	})
+
+	t.Run("should echo a valid caller request ID and replace an invalid one", func(t *testing.T) {
+		w, problem := doProblem(t, router, "GET", "/nowhere", http.Header{headerRequestID: {"abc-123"}})
+		assert.Equal(t, "abc-123", w.Header().Get(headerRequestID))
+		assert.Equal(t, "abc-123", problem.RequestID)
+
+		w, problem = doProblem(t, router, "GET", "/nowhere", http.Header{headerRequestID: {"bad id\n"}})
+		assert.Len(t, problem.RequestID, 32)
+		assert.Equal(t, problem.RequestID, w.Header().Get(headerRequestID))
+	})
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/pkg/logger"
+	"api-server/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+const headerAPIKey = "X-API-Key"
+
+type rateLimiter struct {
+	store       ratelimit.Store
+	policy      *ratelimit.DynamicPolicy
+	trustedHops int
+}
+
+// WithRateLimit limits the public routes per API key (or client IP for anonymous callers).
+// trustedHops is the number of proxies in front of the service appending to X-Forwarded-For
+// (1 on Cloud Run). The policy can be replaced at runtime.
+func WithRateLimit(store ratelimit.Store, policy *ratelimit.DynamicPolicy, trustedHops int) Option {
+	return func(h *handler) {
+		h.rateLimiter = &rateLimiter{
+			store:       store,
+			policy:      policy,
+			trustedHops: trustedHops,
+		}
+	}
+}
+
+func (h *handler) rateLimit() gin.HandlerFunc {
+	if h.rateLimiter == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		if h.allowN(c, 1) {
+			c.Next()
+		}
+	}
+}
+
+// allowN takes n tokens from the caller's bucket, e.g. one per CEP of a batch, and sets the
+// RateLimit-* headers. When they do not fit it aborts with errRateLimited and returns false.
+func (h *handler) allowN(c *gin.Context, n int) bool {
+	rl := h.rateLimiter
+	if rl == nil || n <= 0 {
+		return true
+	}
+
+	var tier, key string
+	var limit ratelimit.Limit
+	policy := rl.policy.Load()
+	if apiKey, ok := apiKeyFromContext(c); ok {
+		tier, limit = policy.LimitForTier(apiKey.Tier)
+		key = "key:" + apiKey.Name
+	} else {
+		apiKey := c.GetHeader(headerAPIKey)
+		tier, limit = policy.LimitFor(apiKey)
+		key = "ip:" + clientIP(c.Request, rl.trustedHops)
+		if tier != "default" {
+			key = "key:" + hashKey(apiKey)
+		}
+	}
+	if !limit.Enabled() {
+		return true
+	}
+
+	res, err := rl.store.TakeN(c.Request.Context(), key, limit, n)
+	if err != nil {
+		// Falha do store não deve derrubar a API: deixa passar
+		h.logger(c).ErrorContext(c.Request.Context(), "Rate limit store failed", "tier", tier, logger.Err(err))
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+	switch {
+	case !res.Allowed && res.RetryAfter > 0:
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		abortWithError(c, errRateLimited)
+		return false
+	case !res.Allowed:
+		// Mais do que o burst nunca cabe: esperar não adianta
+		abortWithError(c, errRateLimited.withDetail("more lookups than the rate limit burst of "+strconv.Itoa(limit.Burst)))
+		return false
+	}
+	return true
+}
+
+// clientIP returns the caller address. Behind trustedHops proxies the client is the entry
+// the outermost trusted proxy appended to X-Forwarded-For; entries before it can be forged.
+func clientIP(r *http.Request, trustedHops int) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" && trustedHops > 0 {
+		parts := strings.Split(xff, ",")
+		idx := len(parts) - trustedHops
+		if idx < 0 {
+			idx = 0
+		}
+		if ip := strings.TrimSpace(parts[idx]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
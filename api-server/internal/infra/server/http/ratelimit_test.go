@@ -0,0 +1,78 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-server/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRateLimitRouter(policy ratelimit.Policy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	handler := &handler{log: slog.Default()}
+	WithRateLimit(ratelimit.NewMemoryStore(), ratelimit.NewDynamicPolicy(policy), 1)(handler)
+
+	router := gin.New()
+	router.Use(handler.handleErrors())
+	router.GET("/limited", handler.rateLimit(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func doLimited(router *gin.Engine, remoteAddr, xff, apiKey string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/limited", nil)
+	req.RemoteAddr = remoteAddr
+	if xff != "" {
+		req.Header.Set("X-Forwarded-For", xff)
+	}
+	if apiKey != "" {
+		req.Header.Set(headerAPIKey, apiKey)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	policy := ratelimit.Policy{
+		Default:     ratelimit.Limit{Rate: 1, Burst: 1},
+		Tiers:       map[string]ratelimit.Limit{"partner": {Rate: 1, Burst: 3}},
+		APIKeyTiers: map[string]string{"partner-key": "partner"},
+	}
+
+	t.Run("should return 429 with Retry-After once the bucket is empty", func(t *testing.T) {
+		router := setupRateLimitRouter(policy)
+
+		w := doLimited(router, "10.0.0.1:1234", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+		w = doLimited(router, "10.0.0.1:1234", "", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("should key anonymous callers by the client IP appended by the trusted proxy", func(t *testing.T) {
+		router := setupRateLimitRouter(policy)
+
+		assert.Equal(t, http.StatusOK, doLimited(router, "10.0.0.1:1", "1.1.1.1, 203.0.113.7", "").Code)
+		// Forged leftmost entry does not give the same client a new bucket
+		assert.Equal(t, http.StatusTooManyRequests, doLimited(router, "10.0.0.1:1", "9.9.9.9, 203.0.113.7", "").Code)
+		assert.Equal(t, http.StatusOK, doLimited(router, "10.0.0.1:1", "203.0.113.8", "").Code)
+	})
+
+	t.Run("should apply the API key tier", func(t *testing.T) {
+		router := setupRateLimitRouter(policy)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, doLimited(router, "10.0.0.1:1", "", "partner-key").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, doLimited(router, "10.0.0.1:1", "", "partner-key").Code)
+	})
+}
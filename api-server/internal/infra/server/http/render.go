@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type format string
+
+const (
+	formatJSON format = "json"
+	formatXML  format = "xml"
+	formatCSV  format = "csv"
+	formatText format = "text"
+
+	contextFormat = "responseFormat"
+	queryFormat   = "format"
+)
+
+// offer is a media type the renderer can produce, in server preference order.
+type offer struct {
+	mediaType string
+	format    format
+}
+
+var offers = []offer{
+	{"application/json", formatJSON},
+	{"application/xml", formatXML},
+	{"text/plain", formatText},
+	{"text/csv", formatCSV},
+	{"text/xml", formatXML},
+}
+
+var formatAliases = map[string]format{
+	"json": formatJSON,
+	"xml":  formatXML,
+	"csv":  formatCSV,
+	"text": formatText,
+	"txt":  formatText,
+}
+
+// tabular payloads can be rendered as CSV.
+type tabular interface {
+	CSVHeader() []string
+	CSVRecords() [][]string
+}
+
+// texter payloads can be rendered as a human-readable line.
+type texter interface {
+	Text() string
+}
+
+// negotiateFormat picks the response format from ?format= or the Accept header,
+// failing with 406 before the handler runs.
+func negotiateFormat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+
+		f, ok := selectFormat(c.Query(queryFormat), c.GetHeader("Accept"))
+		if !ok {
+			abortWithError(c, errNotAcceptable)
+			return
+		}
+
+		c.Set(contextFormat, f)
+		c.Next()
+	}
+}
+
+func selectFormat(override, accept string) (format, bool) {
+	if override != "" {
+		f, ok := formatAliases[strings.ToLower(override)]
+		return f, ok
+	}
+	return acceptedFormat(accept, offers)
+}
+
+// acceptedFormat picks the offer matching the accept media range of the highest q-value, or the first
+// offer when accept is empty.
+func acceptedFormat(accept string, offers []offer) (format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0].format, true
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(mediaType)), q: 1}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.mediaType != "" && r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		for _, o := range offers {
+			if mediaTypeMatches(r.mediaType, o.mediaType) {
+				return o.format, true
+			}
+		}
+	}
+	return "", false
+}
+
+func mediaTypeMatches(accepted, offered string) bool {
+	if accepted == "*/*" || accepted == offered {
+		return true
+	}
+	prefix, sub, _ := strings.Cut(accepted, "/")
+	return sub == "*" && strings.HasPrefix(offered, prefix+"/")
+}
+
+// render writes payload in the negotiated format (JSON when the route does not negotiate).
+func (h *handler) render(c *gin.Context, status int, payload any) {
+	f := formatJSON
+	if v, ok := c.Get(contextFormat); ok {
+		f = v.(format)
+	}
+
+	switch f {
+	case formatXML:
+		c.XML(status, payload)
+	case formatCSV:
+		t, ok := payload.(tabular)
+		if !ok {
+			abortWithError(c, errNotAcceptable)
+			return
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write(t.CSVHeader())
+		_ = w.WriteAll(t.CSVRecords())
+		c.Data(status, "text/csv; charset=utf-8", buf.Bytes())
+	case formatText:
+		t, ok := payload.(texter)
+		if !ok {
+			abortWithError(c, errNotAcceptable)
+			return
+		}
+		c.String(status, "%s\n", t.Text())
+	default:
+		c.JSON(status, payload)
+	}
+}
+
+// mediaTypes lists the content types a negotiated route can produce, for the OpenAPI document.
+func mediaTypes() []string {
+	types := make([]string, 0, len(offers))
+	for _, o := range offers {
+		types = append(types, o.mediaType)
+	}
+	return types
+}
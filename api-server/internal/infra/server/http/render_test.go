@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		override, accept string
+		expected         format
+		ok               bool
+	}{
+		{"", "", formatJSON, true},
+		{"", "*/*", formatJSON, true},
+		{"", "application/xml", formatXML, true},
+		{"", "text/xml", formatXML, true},
+		{"", "text/csv", formatCSV, true},
+		{"", "text/*", formatText, true},
+		{"", "text/csv;q=0.5, application/xml;q=0.9", formatXML, true},
+		{"", "application/json;q=0, text/plain", formatText, true},
+		{"", "image/png", "", false},
+		{"csv", "application/json", formatCSV, true},
+		{"TXT", "", formatText, true},
+		{"yaml", "", "", false},
+	} {
+		f, ok := selectFormat(tc.override, tc.accept)
+		assert.Equal(t, tc.ok, ok, "%q %q", tc.override, tc.accept)
+		assert.Equal(t, tc.expected, f, "%q %q", tc.override, tc.accept)
+	}
+}
+
+func TestHandler_RunAnalysis_ContentNegotiation(t *testing.T) {
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t)
+	var calls atomic.Int32
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		calls.Add(1)
+		return "São Paulo,SP", nil
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = mockBuscaCEP.GetBrasilAPICEPFunc
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}
+
+	do := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should render XML", func(t *testing.T) {
+		w := do("/v1/tempForCep/01001000?units=C,F", "application/xml")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+		assert.Equal(t, "<temperature><temp_C>25</temp_C><temp_F>77</temp_F></temperature>", w.Body.String())
+	})
+
+	t.Run("should render CSV", func(t *testing.T) {
+		w := do("/v1/tempForCep/01001000", "text/csv")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		assert.Equal(t, "temp_C,temp_F,temp_K\n25,77,298.15\n", w.Body.String())
+	})
+
+	t.Run("should render plain text through the format override", func(t *testing.T) {
+		w := do("/v1/tempForCep/01001000?format=text", "application/json")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, "25 °C | 77 °F | 298.15 K\n", w.Body.String())
+	})
+
+	t.Run("should return 406 before calling the upstream APIs", func(t *testing.T) {
+		calls.Store(0)
+		w := do("/v1/tempForCep/01001000", "image/png")
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, contentTypeProblemJSON, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"code":"not_acceptable"`)
+		assert.Equal(t, int32(0), calls.Load())
+	})
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-server/internal/infra/health"
+	"api-server/pkg/apikey"
+	"api-server/pkg/circuitbreaker"
+	"api-server/pkg/temperature"
+)
+
+// TemperatureResponse has one field per requested unit (Celsius, Fahrenheit and Kelvin by default).
+type TemperatureResponse struct {
+	XMLName xml.Name `json:"-" xml:"temperature"`
+	TempC   *float64 `json:"temp_C,omitempty" xml:"temp_C,omitempty" description:"Temperature in Celsius"`
+	TempF   *float64 `json:"temp_F,omitempty" xml:"temp_F,omitempty" description:"Temperature in Fahrenheit"`
+	TempK   *float64 `json:"temp_K,omitempty" xml:"temp_K,omitempty" description:"Temperature in Kelvin"`
+	TempR   *float64 `json:"temp_R,omitempty" xml:"temp_R,omitempty" description:"Temperature in Rankine"`
+	TempRe  *float64 `json:"temp_Re,omitempty" xml:"temp_Re,omitempty" description:"Temperature in Réaumur"`
+	TempDe  *float64 `json:"temp_De,omitempty" xml:"temp_De,omitempty" description:"Temperature in Delisle"`
+}
+
+type temperatureField struct {
+	unit   temperature.Unit
+	symbol string
+	value  *float64
+}
+
+func (r TemperatureResponse) fields() []temperatureField {
+	all := []temperatureField{
+		{temperature.Celsius, "°C", r.TempC},
+		{temperature.Fahrenheit, "°F", r.TempF},
+		{temperature.Kelvin, "K", r.TempK},
+		{temperature.Rankine, "°R", r.TempR},
+		{temperature.Reaumur, "°Ré", r.TempRe},
+		{temperature.Delisle, "°De", r.TempDe},
+	}
+
+	fields := make([]temperatureField, 0, len(all))
+	for _, f := range all {
+		if f.value != nil {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r TemperatureResponse) CSVHeader() []string {
+	var header []string
+	for _, f := range r.fields() {
+		header = append(header, "temp_"+string(f.unit))
+	}
+	return header
+}
+
+func (r TemperatureResponse) CSVRecords() [][]string {
+	var record []string
+	for _, f := range r.fields() {
+		record = append(record, formatFloat(*f.value))
+	}
+	return [][]string{record}
+}
+
+// Text renders the temperatures as a single line, e.g. "25 °C | 77 °F | 298.15 K".
+func (r TemperatureResponse) Text() string {
+	var parts []string
+	for _, f := range r.fields() {
+		parts = append(parts, formatFloat(*f.value)+" "+f.symbol)
+	}
+	return strings.Join(parts, " | ")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+type BatchResponse struct {
+	Results []BatchItemResponse `json:"results" description:"One result per CEP of the request, in the same order"`
+	Summary BatchSummary        `json:"summary"`
+}
+
+type BatchItemResponse struct {
+	Input       string               `json:"input" description:"CEP as sent"`
+	CEP         string               `json:"cep,omitempty" description:"CEP formatted as 00000-000, when valid"`
+	City        string               `json:"city,omitempty" description:"City and state the CEP resolved to"`
+	Temperature *TemperatureResponse `json:"temperature,omitempty"`
+	Error       *BatchError          `json:"error,omitempty" description:"Why this CEP failed; the other results are not affected"`
+}
+
+// BatchError is the problem a single CEP of a batch would have got from GET /tempForCep/{cep}.
+type BatchError struct {
+	Status int    `json:"status" description:"HTTP status code of the equivalent single lookup"`
+	Code   string `json:"code" description:"Stable machine-readable error code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type BatchSummary struct {
+	Total        int `json:"total"`
+	Succeeded    int `json:"succeeded"`
+	Failed       int `json:"failed"`
+	UniqueCEPs   int `json:"unique_ceps" description:"CEP lookups made, after deduplication"`
+	UniqueCities int `json:"unique_cities" description:"Temperature lookups made, after deduplication"`
+}
+
+// TemperatureEvent is the data of a temperature event of GET /tempForCep/{cep}/stream.
+type TemperatureEvent struct {
+	CEP         string              `json:"cep" description:"CEP formatted as 00000-000"`
+	City        string              `json:"city" description:"City and state the CEP resolved to"`
+	Temperature TemperatureResponse `json:"temperature"`
+	ObservedAt  time.Time           `json:"observed_at" description:"When the new temperature was first seen"`
+}
+
+type JobResponse struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status" description:"queued, running, succeeded, failed or canceled"`
+	Total      int        `json:"total" description:"CEPs in the job"`
+	Processed  int        `json:"processed" description:"CEPs looked up so far"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed" description:"CEPs whose result is an error"`
+	Error      string     `json:"error,omitempty" description:"Why the job failed as a whole"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ResultURL  string     `json:"result_url" description:"Where to download the results, partial while the job runs"`
+}
+
+// JobResultResponse is one line of the NDJSON results of a job.
+type JobResultResponse struct {
+	Index int `json:"index" description:"Position of the CEP in the job request"`
+	BatchItemResponse
+}
+
+type CEPSearchResponse struct {
+	Addresses   []AddressResponse    `json:"addresses" description:"Matching addresses, empty when none is found"`
+	Temperature *TemperatureResponse `json:"temperature,omitempty" description:"Current temperature of the city, when requested with temperature=true"`
+	// A falha da temperatura não derruba a busca: os endereços vêm mesmo assim
+	TemperatureError *BatchError `json:"temperature_error,omitempty" description:"Why the temperature is missing when it was requested and could not be found; the addresses are not affected"`
+}
+
+type AddressResponse struct {
+	CEP          string `json:"cep" description:"CEP formatted as 00000-000"`
+	Street       string `json:"street" description:"Street (logradouro)"`
+	Complement   string `json:"complement,omitempty" description:"Part of the street covered by the CEP, e.g. lado ímpar"`
+	Neighborhood string `json:"neighborhood" description:"Neighborhood (bairro)"`
+	City         string `json:"city"`
+	UF           string `json:"uf" description:"State code"`
+}
+
+type UsageResponse struct {
+	Name      string       `json:"name" description:"API key name"`
+	Tier      string       `json:"tier,omitempty" description:"Rate limit tier of the key"`
+	Endpoints []string     `json:"endpoints,omitempty" description:"Route patterns the key may call (all when empty)"`
+	Usage     apikey.Usage `json:"usage"`
+}
+
+type BreakersResponse struct {
+	Breakers []circuitbreaker.Snapshot `json:"breakers"`
+}
+
+type ReloadResponse struct {
+	Changed         []string `json:"changed" description:"Keys applied by the reload"`
+	RestartRequired []string `json:"restart_required" description:"Keys that changed but only take effect after a restart"`
+}
+
+type HealthResponse struct {
+	Status string `json:"status" description:"Always ok when the endpoint answers 200"`
+}
+
+type VersionResponse struct {
+	Version       string `json:"version" description:"Release version"`
+	Build         string `json:"build" description:"Commit the binary was built from"`
+	Date          string `json:"date" description:"Build date"`
+	Service       string `json:"service,omitempty" description:"Cloud Run service (K_SERVICE)"`
+	Revision      string `json:"revision,omitempty" description:"Cloud Run revision (K_REVISION)"`
+	Configuration string `json:"configuration,omitempty" description:"Cloud Run configuration (K_CONFIGURATION)"`
+}
+
+type StatusResponse struct {
+	Status    string           `json:"status" description:"ok, or degraded when a provider is failing, its breaker is open or its API key is invalid"`
+	Ready     bool             `json:"ready" description:"Whether the instance accepts traffic"`
+	Providers []ProviderStatus `json:"providers"`
+}
+
+type ProviderStatus struct {
+	health.ProviderStatus
+	BreakerState string `json:"breaker_state,omitempty" description:"closed, half-open or open"`
+}
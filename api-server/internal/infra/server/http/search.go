@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"api-server/domain"
+	"api-server/pkg/cep"
+	"api-server/pkg/temperature"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViaCEP só aceita buscas com cidade e logradouro de pelo menos 3 caracteres
+const minSearchLength = 3
+
+type searchQuery struct {
+	uf          string
+	city        string
+	street      string
+	temperature bool
+}
+
+func parseSearchQuery(c *gin.Context) (searchQuery, error) {
+	q := searchQuery{
+		uf:     strings.ToUpper(strings.TrimSpace(c.Query("uf"))),
+		city:   strings.TrimSpace(c.Query("city")),
+		street: strings.TrimSpace(c.Query("street")),
+	}
+
+	if !cep.IsUF(q.uf) {
+		return q, errInvalidParameter.withDetail("uf: must be the code of a state, e.g. SP")
+	}
+	if utf8.RuneCountInString(q.city) < minSearchLength {
+		return q, errInvalidParameter.withDetail("city: must have at least " + strconv.Itoa(minSearchLength) + " characters")
+	}
+	if utf8.RuneCountInString(q.street) < minSearchLength {
+		return q, errInvalidParameter.withDetail("street: must have at least " + strconv.Itoa(minSearchLength) + " characters")
+	}
+
+	if value, ok := c.GetQuery("temperature"); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return q, errInvalidParameter.withDetail("temperature: must be true or false")
+		}
+		q.temperature = parsed
+	}
+	return q, nil
+}
+
+func (h *handler) SearchCEP(c *gin.Context) {
+	q, err := parseSearchQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	opts, err := h.parseTemperatureOptions(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	addresses, err := h.analisysService.SearchAddresses(c.Request.Context(), q.uf, q.city, q.street)
+	if errors.Is(err, domain.ErrNoProviderAvailable) {
+		abortWithError(c, errUpstreamUnavailable.wrap(err))
+		return
+	}
+	if err != nil {
+		abortWithError(c, errSearchFailed.wrap(err))
+		return
+	}
+
+	res := CEPSearchResponse{Addresses: make([]AddressResponse, 0, len(addresses))}
+	for _, a := range addresses {
+		res.Addresses = append(res.Addresses, newAddressResponse(a))
+	}
+
+	if q.temperature && len(addresses) > 0 {
+		// Todos os endereços são da mesma cidade: uma única consulta de temperatura
+		cityInfo := addresses[0].City + "," + addresses[0].UF
+		celsiusTemp, err := h.analisysService.GetCelsiusTemperature(c.Request.Context(), cityInfo)
+		if err != nil {
+			apiErr := temperatureError(err, cityInfo)
+			res.TemperatureError = &BatchError{Status: apiErr.status, Code: apiErr.code, Detail: apiErr.detail}
+		} else {
+			temp := newTemperatureResponse(temperature.FromCelsius(float64(celsiusTemp)), opts)
+			res.Temperature = &temp
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func newAddressResponse(a domain.Address) AddressResponse {
+	res := AddressResponse{
+		CEP:          a.CEP,
+		Street:       a.Street,
+		Complement:   a.Complement,
+		Neighborhood: a.Neighborhood,
+		City:         a.City,
+		UF:           a.UF,
+	}
+	if parsed, err := cep.Parse(a.CEP); err == nil {
+		res.CEP = parsed.String()
+	}
+	return res
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-server/domain"
+	"api-server/internal/infra/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_SearchCEP(t *testing.T) {
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t)
+	doc := getSpec(t, router)
+
+	mockBuscaCEP.SearchViaAPICEPFunc = func(ctx context.Context, uf, city, street string) ([]domain.Address, error) {
+		assert.Equal(t, "SP", uf)
+		assert.Equal(t, "São Paulo", city)
+		if street == "Rua Inexistente" {
+			return nil, nil
+		}
+		if street == "Rua Quebrada" {
+			return nil, &client.StatusError{Provider: domain.ProviderViaCEP, StatusCode: http.StatusBadRequest}
+		}
+		return []domain.Address{
+			{CEP: "01001-000", Street: "Praça da Sé", Complement: "lado ímpar", Neighborhood: "Sé", City: "São Paulo", UF: "SP"},
+			{CEP: "01001-001", Street: "Praça da Sé", Complement: "lado par", Neighborhood: "Sé", City: "São Paulo", UF: "SP"},
+		}, nil
+	}
+	weatherCalls := 0
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		weatherCalls++
+		assert.Equal(t, "São Paulo,SP", city)
+		return 25, nil
+	}
+
+	search := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/cep/search?"+query, nil)
+		router.ServeHTTP(w, req)
+		assertDocumentedResponse(t, doc, "/v1/cep/search", "GET", w)
+		return w
+	}
+
+	t.Run("should return the addresses", func(t *testing.T) {
+		w := search("uf=sp&city=S%C3%A3o+Paulo&street=Pra%C3%A7a+da+S%C3%A9")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res CEPSearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Addresses, 2)
+		assert.Equal(t, "01001-000", res.Addresses[0].CEP)
+		assert.Equal(t, "lado ímpar", res.Addresses[0].Complement)
+		assert.Nil(t, res.Temperature)
+		assert.Zero(t, weatherCalls)
+	})
+
+	t.Run("should add the temperature of the city once", func(t *testing.T) {
+		w := search("uf=SP&city=S%C3%A3o+Paulo&street=Pra%C3%A7a+da+S%C3%A9&temperature=true&units=C")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res CEPSearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.NotNil(t, res.Temperature)
+		assert.Equal(t, 25.0, *res.Temperature.TempC)
+		assert.Nil(t, res.Temperature.TempF)
+		assert.Equal(t, 1, weatherCalls)
+	})
+
+	t.Run("should keep the addresses when the temperature fails", func(t *testing.T) {
+		lookup := mockWeather.GetHGWeatherAPIFunc
+		defer func() { mockWeather.GetHGWeatherAPIFunc = lookup }()
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 0, errors.New("weather api error")
+		}
+
+		w := search("uf=SP&city=S%C3%A3o+Paulo&street=Pra%C3%A7a+da+S%C3%A9&temperature=true")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res CEPSearchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Addresses, 2)
+		assert.Nil(t, res.Temperature)
+		require.NotNil(t, res.TemperatureError)
+		assert.Equal(t, "temperature_unavailable", res.TemperatureError.Code)
+	})
+
+	t.Run("should return an empty list when nothing matches", func(t *testing.T) {
+		w := search("uf=SP&city=S%C3%A3o+Paulo&street=Rua+Inexistente&temperature=true")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"addresses":[]}`, w.Body.String())
+	})
+
+	t.Run("should validate the query", func(t *testing.T) {
+		for _, query := range []string{
+			"city=S%C3%A3o+Paulo&street=Rua+Augusta",
+			"uf=XX&city=S%C3%A3o+Paulo&street=Rua+Augusta",
+			"uf=SP&city=SP&street=Rua+Augusta",
+			"uf=SP&city=S%C3%A3o+Paulo&street=Ru",
+			"uf=SP&city=S%C3%A3o+Paulo&street=Rua+Augusta&temperature=maybe",
+		} {
+			assert.Equal(t, http.StatusBadRequest, search(query).Code, query)
+		}
+	})
+
+	t.Run("should return 502 when the search fails", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadGateway, search("uf=SP&city=S%C3%A3o+Paulo&street=Rua+Quebrada").Code)
+	})
+}
@@ -2,17 +2,22 @@ package http
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
+
+	"api-server/pkg/logger"
 )
 
 type Server struct {
 	server *http.Server
-	log    *log.Logger
+	lis    net.Listener
+	log    *slog.Logger
 }
 
-func New(port string, handler http.Handler, log *log.Logger) *Server {
+func New(port string, handler http.Handler, log *slog.Logger) *Server {
 	return &Server{
 		server: &http.Server{
 			Addr:         ":" + port,
@@ -24,22 +29,47 @@ func New(port string, handler http.Handler, log *log.Logger) *Server {
 	}
 }
 
-func (s *Server) ListenAndServe() {
-	go func() {
-		s.log.Printf("Server API running on %s!", s.server.Addr)
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.log.Printf("Error on ListenAndServe: %q", err)
-		}
-	}()
+// Listen binds the port, so a busy port fails the startup instead of a background goroutine.
+func (s *Server) Listen() error {
+	lis, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.lis = lis
+	return nil
+}
+
+// Serve blocks serving the bound port until Shutdown.
+func (s *Server) Serve() error {
+	s.log.Info("HTTP server running", "addr", s.lis.Addr().String())
+	if err := s.server.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// RegisterOnShutdown runs f as soon as Shutdown starts, to end the streams the drain would wait for.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.server.RegisterOnShutdown(f)
+}
+
+// Shutdown drains in-flight requests until ctx is done, then closes the remaining connections.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.log.Info("Shutting down HTTP server")
+	// Fecha o listener mesmo se o Serve nunca chegou a rodar
+	defer s.closeListener()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.log.Warn("Could not shutdown HTTP server gracefully", logger.Err(err))
+		_ = s.server.Close()
+		return err
+	}
+	s.log.Info("HTTP server gracefully stopped")
+	return nil
 }
 
-func (s *Server) Shutdown() {
-	s.log.Printf("Shutting down server")
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-	if err := s.server.Shutdown(ctx); err != nil && err != http.ErrServerClosed {
-		s.log.Printf("Could not shutdown in 60s: %q", err)
-		return
+func (s *Server) closeListener() {
+	if s.lis != nil {
+		_ = s.lis.Close()
 	}
-	s.log.Printf("Server gracefully stopped")
 }
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"api-server/internal/infra/stream"
+	"api-server/pkg/logger"
+	"api-server/pkg/temperature"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStreamHeartbeat = 15 * time.Second
+	contentTypeEventStream = "text/event-stream"
+	streamRetryMillis      = 5000
+)
+
+type streamOptions struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+// WithStream serves GET /tempForCep/:cep/stream from hub, with a comment line every heartbeat
+// so proxies keep the idle connections open.
+func WithStream(hub *stream.Hub, heartbeat time.Duration) Option {
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+	return func(h *handler) {
+		h.stream = streamOptions{hub: hub, heartbeat: heartbeat}
+	}
+}
+
+// StreamTemperature sends a temperature event whenever the temperature of the city of the CEP
+// changes. Resuming with Last-Event-ID skips the current temperature if the client already has it.
+func (h *handler) StreamTemperature(c *gin.Context) {
+	cep, err := parseCEP(c.Param("cep"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	opts, err := h.parseTemperatureOptions(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	cityInfo, err := h.analisysService.GetCity(ctx, cep.Digits())
+	if err != nil {
+		abortWithError(c, cityError(err))
+		return
+	}
+
+	sub, err := h.stream.hub.Subscribe(cityInfo)
+	if errors.Is(err, stream.ErrClosed) {
+		abortWithError(c, errNotReady)
+		return
+	}
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	defer sub.Close()
+
+	// O WriteTimeout do servidor encerraria o stream: sem prazo para esta resposta
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger(c).WarnContext(ctx, "Could not lift the write deadline of the stream", logger.Err(err))
+	}
+
+	c.Header("Content-Type", contentTypeEventStream)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMillis)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.stream.heartbeat)
+	defer heartbeat.Stop()
+
+	lastID := c.GetHeader("Last-Event-ID")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case obs, ok := <-sub.C:
+			if !ok {
+				// Shutdown: o cliente reconecta em outra instância com o Last-Event-ID
+				return
+			}
+			if obs.ID == lastID {
+				continue
+			}
+			lastID = obs.ID
+
+			data, err := json.Marshal(TemperatureEvent{
+				CEP:         cep.String(),
+				City:        obs.City,
+				Temperature: newTemperatureResponse(temperature.FromCelsius(float64(obs.Celsius)), opts),
+				ObservedAt:  obs.ObservedAt,
+			})
+			if err != nil {
+				h.logger(c).ErrorContext(ctx, "Could not encode stream event", logger.Err(err))
+				return
+			}
+			fmt.Fprintf(c.Writer, "event: temperature\nid: %s\ndata: %s\n\n", obs.ID, data)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
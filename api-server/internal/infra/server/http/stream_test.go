@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-server/internal/infra/stream"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is an event or a comment read from a stream.
+type sseEvent struct {
+	event, id, data, comment string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return e
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			e.event = value
+		case "id":
+			e.id = value
+		case "data":
+			e.data = value
+		case "":
+			e.comment = value
+		}
+	}
+}
+
+func TestHandler_StreamTemperature(t *testing.T) {
+	hub := stream.NewHub(func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}, slog.Default(), stream.WithInterval(time.Hour))
+	router, mockBuscaCEP, _ := setupFullRouter(t, WithStream(hub, 20*time.Millisecond))
+	doc := getSpec(t, router)
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "São Paulo,SP", nil
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "", errors.New("not found")
+	}
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	open := func(t *testing.T, ctx context.Context, lastEventID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/tempForCep/01001000/stream?units=C", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, contentTypeEventStream, res.Header.Get("Content-Type"))
+
+		r := bufio.NewReader(res.Body)
+		readEvent(t, r) // retry
+		return res, r
+	}
+
+	var id string
+	t.Run("should send the temperature and then heartbeats", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, r := open(t, ctx, "")
+
+		e := readEvent(t, r)
+		assert.Equal(t, "temperature", e.event)
+		assert.NotEmpty(t, e.id)
+		var data TemperatureEvent
+		require.NoError(t, json.Unmarshal([]byte(e.data), &data))
+		assert.Equal(t, "01001-000", data.CEP)
+		assert.Equal(t, "São Paulo,SP", data.City)
+		assert.Equal(t, 25.0, *data.Temperature.TempC)
+		assert.Nil(t, data.Temperature.TempF)
+		id = e.id
+
+		assert.Equal(t, "heartbeat", readEvent(t, r).comment)
+		cancel()
+		assert.Eventually(t, func() bool { return hub.Subscribers("São Paulo,SP") == 0 }, time.Second, 5*time.Millisecond,
+			"the subscription ends with the client")
+	})
+
+	t.Run("should skip the temperature the client already has", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, r := open(t, ctx, id)
+
+		assert.Equal(t, "heartbeat", readEvent(t, r).comment)
+	})
+
+	t.Run("should reject an invalid CEP", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/tempForCep/123/stream", nil)
+		router.ServeHTTP(w, req)
+		assertDocumentedResponse(t, doc, "/v1/tempForCep/{cep}/stream", "GET", w)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("should end the streams on shutdown", func(t *testing.T) {
+		_, r := open(t, context.Background(), "")
+		readEvent(t, r)
+
+		hub.Close()
+		for {
+			if _, err := r.ReadString('\n'); err != nil {
+				break
+			}
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/tempForCep/01001000/stream", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
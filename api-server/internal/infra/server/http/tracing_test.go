@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	router, mockBuscaCEP, mockWeather := setupFullRouter(t)
+	mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	mockBuscaCEP.GetViaAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+		return "São Paulo,SP", nil
+	}
+	mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+		return 25, nil
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tempForCep/01001000", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	server := spans["GET /v1/tempForCep/:cep"]
+	require.NotNil(t, server, "server span")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String(), "continues the caller's trace")
+
+	getCity := spans["GetCity"]
+	require.NotNil(t, getCity)
+	assert.Equal(t, server.SpanContext().SpanID(), getCity.Parent().SpanID())
+	assert.Contains(t, getCity.Attributes(), attribute.String("cep.race.winner", "ViaCEP"))
+
+	require.NotNil(t, spans["lookup ViaCEP"])
+	assert.Equal(t, getCity.SpanContext().SpanID(), spans["lookup ViaCEP"].Parent().SpanID())
+
+	temperature := spans["GetCelsiusTemperature"]
+	require.NotNil(t, temperature)
+	assert.Equal(t, server.SpanContext().SpanID(), temperature.Parent().SpanID())
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithVersion publishes the build and Cloud Run revision on /version.
+func WithVersion(info VersionResponse) Option {
+	return func(h *handler) {
+		h.version = info
+	}
+}
+
+func (h *handler) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, h.version)
+}
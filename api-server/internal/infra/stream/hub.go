@@ -0,0 +1,212 @@
+// Package stream pushes temperature changes to many subscribers with a single upstream poll per city.
+package stream
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"api-server/pkg/logger"
+)
+
+const defaultInterval = 60 * time.Second
+
+var ErrClosed = errors.New("stream hub closed")
+
+// Observation is the temperature of a city, sent whenever it changes.
+type Observation struct {
+	// ID derives from the city and the temperature only, so every instance, even after a restart,
+	// recognizes the Last-Event-ID of an observation it sent.
+	ID         string
+	City       string
+	Celsius    int
+	ObservedAt time.Time
+}
+
+func newObservation(city string, celsius int, at time.Time) Observation {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(city + "\x00" + strconv.Itoa(celsius)))
+	return Observation{ID: strconv.FormatUint(h.Sum64(), 36), City: city, Celsius: celsius, ObservedAt: at}
+}
+
+// Hub runs one poller per city with subscribers, started by the first one and stopped with the last.
+type Hub struct {
+	fetch    func(ctx context.Context, city string) (int, error)
+	interval time.Duration
+	log      *slog.Logger
+	now      func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pollers map[string]*poller
+	wg      sync.WaitGroup
+}
+
+type Option func(*Hub)
+
+// WithInterval sets how often each city is polled.
+func WithInterval(d time.Duration) Option {
+	return func(h *Hub) {
+		h.interval = d
+	}
+}
+
+func NewHub(fetch func(ctx context.Context, city string) (int, error), log *slog.Logger, opts ...Option) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		fetch:    fetch,
+		interval: defaultInterval,
+		log:      log,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+		pollers:  make(map[string]*poller),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type poller struct {
+	city   string
+	cancel context.CancelFunc
+	subs   map[*Subscription]struct{}
+	latest *Observation
+}
+
+// Subscription receives the observations of a city. C holds only the latest one, so a slow
+// subscriber skips the intermediate values, and is closed when the hub is.
+type Subscription struct {
+	C <-chan Observation
+
+	ch     chan Observation
+	hub    *Hub
+	poller *poller
+	once   sync.Once
+}
+
+// Subscribe starts receiving the observations of city, beginning with the current one if known.
+func (h *Hub) Subscribe(city string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+
+	p, ok := h.pollers[city]
+	if !ok {
+		ctx, cancel := context.WithCancel(h.ctx)
+		p = &poller{city: city, cancel: cancel, subs: make(map[*Subscription]struct{})}
+		h.pollers[city] = p
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.run(ctx, p)
+		}()
+	}
+
+	ch := make(chan Observation, 1)
+	sub := &Subscription{C: ch, ch: ch, hub: h, poller: p}
+	p.subs[sub] = struct{}{}
+	if p.latest != nil {
+		ch <- *p.latest
+	}
+	return sub, nil
+}
+
+// Close unsubscribes; the poller of the city stops with its last subscriber.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(s.poller.subs, s)
+		if len(s.poller.subs) == 0 && h.pollers[s.poller.city] == s.poller {
+			s.poller.cancel()
+			delete(h.pollers, s.poller.city)
+		}
+	})
+}
+
+// Subscribers counts the subscriptions of city.
+func (h *Hub) Subscribers(city string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if p, ok := h.pollers[city]; ok {
+		return len(p.subs)
+	}
+	return 0
+}
+
+// Close stops every poller and closes every subscription, ending the streams on shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.cancel()
+	for city, p := range h.pollers {
+		for sub := range p.subs {
+			close(sub.ch)
+		}
+		p.subs = nil
+		delete(h.pollers, city)
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+}
+
+func (h *Hub) run(ctx context.Context, p *poller) {
+	log := h.log.With("city", p.city)
+	log.Debug("Stream poller started")
+	defer log.Debug("Stream poller stopped")
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		celsius, err := h.fetch(ctx, p.city)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			// Falha pontual no polling mantém a última observação
+			log.Warn("Stream poll failed", logger.Err(err))
+		default:
+			h.publish(p, newObservation(p.city, celsius, h.now().UTC()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends obs to every subscriber when the temperature changed.
+func (h *Hub) publish(p *poller, obs Observation) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if p.latest != nil && p.latest.ID == obs.ID {
+		return
+	}
+	p.latest = &obs
+	for sub := range p.subs {
+		// Só a mais recente importa: descarta a que o assinante ainda não leu
+		select {
+		case <-sub.ch:
+		default:
+		}
+		sub.ch <- obs
+	}
+}
@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWeather returns the temperature set by the test and counts the polls per city.
+type fakeWeather struct {
+	mu      sync.Mutex
+	celsius int
+	err     error
+	polls   map[string]int
+}
+
+func (f *fakeWeather) fetch(_ context.Context, city string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.polls == nil {
+		f.polls = make(map[string]int)
+	}
+	f.polls[city]++
+	return f.celsius, f.err
+}
+
+func (f *fakeWeather) set(celsius int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.celsius, f.err = celsius, err
+}
+
+func (f *fakeWeather) pollsOf(city string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.polls[city]
+}
+
+func receive(t *testing.T, sub *Subscription) Observation {
+	t.Helper()
+	select {
+	case obs, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return obs
+	case <-time.After(2 * time.Second):
+		t.Fatal("no observation")
+		return Observation{}
+	}
+}
+
+func TestHub(t *testing.T) {
+	t.Run("should share one poller per city and send only the changes", func(t *testing.T) {
+		weather := &fakeWeather{celsius: 25}
+		hub := NewHub(weather.fetch, slog.Default(), WithInterval(10*time.Millisecond))
+		defer hub.Close()
+
+		first, err := hub.Subscribe("São Paulo,SP")
+		require.NoError(t, err)
+		obs := receive(t, first)
+		assert.Equal(t, 25, obs.Celsius)
+
+		second, err := hub.Subscribe("São Paulo,SP")
+		require.NoError(t, err)
+		assert.Equal(t, obs, receive(t, second), "a new subscriber starts with the current observation")
+		assert.Equal(t, 2, hub.Subscribers("São Paulo,SP"))
+
+		// Sem mudança, nada é enviado
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, first.C)
+
+		weather.set(26, nil)
+		changed := receive(t, first)
+		assert.Equal(t, 26, changed.Celsius)
+		assert.NotEqual(t, obs.ID, changed.ID)
+		assert.Equal(t, changed, receive(t, second))
+
+		// Falhas mantêm a última observação
+		weather.set(0, errors.New("weather api error"))
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, first.C)
+
+		first.Close()
+		second.Close()
+		assert.Equal(t, 0, hub.Subscribers("São Paulo,SP"))
+		polls := weather.pollsOf("São Paulo,SP")
+		time.Sleep(50 * time.Millisecond)
+		assert.LessOrEqual(t, weather.pollsOf("São Paulo,SP"), polls+1, "the poller stops with its last subscriber")
+	})
+
+	t.Run("should derive the ID from the observation", func(t *testing.T) {
+		at := time.Now()
+		assert.Equal(t, newObservation("Rio de Janeiro,RJ", 30, at).ID, newObservation("Rio de Janeiro,RJ", 30, at.Add(time.Hour)).ID)
+		assert.NotEqual(t, newObservation("Rio de Janeiro,RJ", 30, at).ID, newObservation("Rio de Janeiro,RJ", 31, at).ID)
+		assert.NotEqual(t, newObservation("Rio de Janeiro,RJ", 30, at).ID, newObservation("São Paulo,SP", 30, at).ID)
+	})
+
+	t.Run("should close the subscriptions on close", func(t *testing.T) {
+		var polls atomic.Int32
+		hub := NewHub(func(ctx context.Context, city string) (int, error) {
+			polls.Add(1)
+			return 25, nil
+		}, slog.Default())
+
+		sub, err := hub.Subscribe("São Paulo,SP")
+		require.NoError(t, err)
+		receive(t, sub)
+
+		hub.Close()
+		_, ok := <-sub.C
+		assert.False(t, ok)
+		sub.Close()
+
+		_, err = hub.Subscribe("São Paulo,SP")
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
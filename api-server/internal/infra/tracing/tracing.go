@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	httpclient "api-server/pkg/http_client"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	envEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	envServiceName    = "OTEL_SERVICE_NAME"
+
+	defaultServiceName = "api-server"
+)
+
+// Enabled reports whether an OTLP endpoint is configured.
+func Enabled() bool {
+	return os.Getenv(envEndpoint) != "" || os.Getenv(envTracesEndpoint) != ""
+}
+
+// Setup installs the W3C trace context propagator and, when an OTLP endpoint is configured,
+// a tracer provider exporting over OTLP/HTTP. The exporter reads the standard OTEL_EXPORTER_OTLP_*
+// and OTEL_TRACES_SAMPLER* variables. The returned func flushes and stops the exporter.
+func Setup(ctx context.Context, version string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := os.Getenv(envServiceName)
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(redactURLs{SpanExporter: exporter, redact: httpclient.DefaultRedactor.URL}),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Transport traces every outbound HTTP call and propagates the trace context to the upstream.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Host
+	}))
+}
+
+// urlKeys are the attributes otelhttp records the full request URL in, query string included.
+var urlKeys = map[attribute.Key]bool{"http.url": true, "url.full": true}
+
+// redactURLs masks secrets (e.g. the HG Weather API key) in the URL attributes of every exported span.
+// otelhttp sets them after the span starts, so they can only be rewritten on export.
+type redactURLs struct {
+	sdktrace.SpanExporter
+	redact func(string) string
+}
+
+func (e redactURLs) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	redacted := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, s := range spans {
+		redacted[i] = redactedSpan{ReadOnlySpan: s, redact: e.redact}
+	}
+	return e.SpanExporter.ExportSpans(ctx, redacted)
+}
+
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	redact func(string) string
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	attrs := s.ReadOnlySpan.Attributes()
+	redacted := make([]attribute.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		if urlKeys[kv.Key] && kv.Value.Type() == attribute.STRING {
+			kv = kv.Key.String(s.redact(kv.Value.AsString()))
+		}
+		redacted[i] = kv
+	}
+	return redacted
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	httpclient "api-server/pkg/http_client"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectorStub is an in-process OTLP/HTTP collector keeping the received spans.
+type collectorStub struct {
+	mu       sync.Mutex
+	services []string
+	spans    []string
+}
+
+func (c *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	var req coltracepb.ExportTraceServiceRequest
+	if r.URL.Path != "/v1/traces" || proto.Unmarshal(body, &req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, attr := range rs.Resource.Attributes {
+			if attr.Key == "service.name" {
+				c.services = append(c.services, attr.Value.GetStringValue())
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.spans = append(c.spans, span.Name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(out)
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("should only propagate when no endpoint is configured", func(t *testing.T) {
+		t.Setenv(envEndpoint, "")
+		t.Setenv(envTracesEndpoint, "")
+
+		shutdown, err := Setup(context.Background(), "test")
+		require.NoError(t, err)
+		assert.False(t, Enabled())
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("should export spans over OTLP", func(t *testing.T) {
+		collector := &collectorStub{}
+		server := httptest.NewServer(collector)
+		defer server.Close()
+
+		t.Setenv(envEndpoint, server.URL)
+		t.Setenv(envServiceName, "api-server-test")
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		shutdown, err := Setup(context.Background(), "1.0.0")
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "GetCity")
+		span.End()
+		require.NoError(t, shutdown(context.Background()))
+
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		assert.Equal(t, []string{"GetCity"}, collector.spans)
+		assert.Equal(t, []string{"api-server-test"}, collector.services)
+	})
+}
+
+func TestTransport(t *testing.T) {
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	t.Setenv(envEndpoint, "")
+	t.Setenv(envTracesEndpoint, "")
+	_, err := Setup(context.Background(), "test")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+	req, _ := http.NewRequestWithContext(ctx, "GET", upstream.URL, nil)
+
+	res, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	assert.Contains(t, traceparent, "4bf92f3577b34da6a3ce929d0e0e4736", "trace context is propagated upstream")
+}
+
+func TestRedactURLs(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(redactURLs{SpanExporter: exporter, redact: httpclient.DefaultRedactor.URL}))
+	transport := otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithTracerProvider(provider))
+
+	req, _ := http.NewRequest("GET", upstream.URL+"/weather?city_name=Recife&key=s3cr3t", nil)
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	var urls []string
+	for _, kv := range spans[0].Attributes {
+		if urlKeys[kv.Key] {
+			urls = append(urls, kv.Value.AsString())
+		}
+	}
+	require.NotEmpty(t, urls)
+	for _, u := range urls {
+		assert.NotContains(t, u, "s3cr3t")
+		assert.Contains(t, u, "key=REDACTED")
+	}
+}
@@ -0,0 +1,103 @@
+package lookup
+
+import (
+	"context"
+	"sync"
+
+	"api-server/pkg/cep"
+)
+
+// Result is the outcome of a lookup of a Batch.
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// Batch looks up the cities of many CEPs and their temperatures, as the batch endpoint of the server
+// and the enrich command do. Each CEP is looked up only once, even repeated or in another format, and
+// so is each city: different CEPs of the same city share the temperature. The lookups are kept across
+// calls, so a large input can be looked up a chunk at a time.
+type Batch[C, T any] struct {
+	Cities map[cep.CEP]Result[C]
+	Temps  map[string]Result[T]
+
+	workers     int
+	city        func(ctx context.Context, c cep.CEP) (C, error)
+	cityInfo    func(city C) string
+	temperature func(ctx context.Context, cityInfo string) (T, error)
+}
+
+// NewBatch looks up with city, and then with temperature by the cityInfo of each city, from workers
+// goroutines. A nil temperature only resolves the cities.
+func NewBatch[C, T any](workers int, city func(ctx context.Context, c cep.CEP) (C, error), cityInfo func(city C) string,
+	temperature func(ctx context.Context, cityInfo string) (T, error),
+) *Batch[C, T] {
+	return &Batch[C, T]{
+		Cities:      make(map[cep.CEP]Result[C]),
+		Temps:       make(map[string]Result[T]),
+		workers:     workers,
+		city:        city,
+		cityInfo:    cityInfo,
+		temperature: temperature,
+	}
+}
+
+// Lookup looks up the CEPs, and the temperatures of their cities, not looked up yet. The outcomes are
+// left in Cities and Temps.
+func (b *Batch[C, T]) Lookup(ctx context.Context, ceps []cep.CEP) {
+	var pending []cep.CEP
+	seen := make(map[cep.CEP]bool)
+	for _, c := range ceps {
+		if _, ok := b.Cities[c]; !ok && !seen[c] {
+			seen[c] = true
+			pending = append(pending, c)
+		}
+	}
+
+	found := make([]Result[C], len(pending))
+	FanOut(len(pending), b.workers, func(i int) {
+		found[i].Value, found[i].Err = b.city(ctx, pending[i])
+	})
+
+	var cities []string
+	queued := make(map[string]bool)
+	for i, c := range pending {
+		b.Cities[c] = found[i]
+		if found[i].Err != nil || b.temperature == nil {
+			continue
+		}
+		info := b.cityInfo(found[i].Value)
+		if _, ok := b.Temps[info]; !ok && !queued[info] {
+			queued[info] = true
+			cities = append(cities, info)
+		}
+	}
+
+	temps := make([]Result[T], len(cities))
+	FanOut(len(cities), b.workers, func(i int) {
+		temps[i].Value, temps[i].Err = b.temperature(ctx, cities[i])
+	})
+	for i, info := range cities {
+		b.Temps[info] = temps[i]
+	}
+}
+
+// FanOut calls fn for every index in [0, n) from at most workers goroutines, and at least one.
+func FanOut(n, workers int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-server/pkg/cep"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	count := func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[key]++
+	}
+	city := func(ctx context.Context, c cep.CEP) (string, error) {
+		count(c.Digits())
+		switch c.Digits() {
+		case "01001000", "01310100":
+			return "São Paulo,SP", nil
+		case "20040020":
+			return "Rio de Janeiro,RJ", nil
+		}
+		return "", errors.New("not found")
+	}
+	temperature := func(ctx context.Context, cityInfo string) (int, error) {
+		count(cityInfo)
+		return 25, nil
+	}
+	parse := func(inputs ...string) []cep.CEP {
+		ceps := make([]cep.CEP, len(inputs))
+		for i, input := range inputs {
+			var err error
+			ceps[i], err = cep.Parse(input)
+			require.NoError(t, err)
+		}
+		return ceps
+	}
+
+	t.Run("should look up each CEP and each city once, across calls", func(t *testing.T) {
+		calls = map[string]int{}
+		b := NewBatch(2, city, func(city string) string { return city }, temperature)
+
+		b.Lookup(context.Background(), parse("01001000", "01001-000", "01310100", "99999999"))
+		b.Lookup(context.Background(), parse("01001000", "20040020"))
+
+		assert.Len(t, b.Cities, 4)
+		assert.Equal(t, "São Paulo,SP", b.Cities[parse("01001-000")[0]].Value)
+		assert.Error(t, b.Cities[parse("99999999")[0]].Err)
+		assert.Equal(t, Result[int]{Value: 25}, b.Temps["Rio de Janeiro,RJ"])
+		assert.Equal(t, map[string]int{
+			"01001000": 1, "01310100": 1, "99999999": 1, "20040020": 1,
+			"São Paulo,SP": 1, "Rio de Janeiro,RJ": 1,
+		}, calls)
+	})
+
+	t.Run("should only resolve the cities without temperature", func(t *testing.T) {
+		b := NewBatch[string, int](0, city, func(city string) string { return city }, nil)
+		b.Lookup(context.Background(), parse("01001000"))
+		assert.Len(t, b.Cities, 1)
+		assert.Empty(t, b.Temps)
+	})
+}
+
+func TestFanOut(t *testing.T) {
+	var running, peak atomic.Int32
+	done := make([]bool, 20)
+	FanOut(len(done), 3, func(i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		done[i] = true
+		running.Add(-1)
+	})
+
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.NotContains(t, done, false)
+	FanOut(0, 3, func(int) { t.Error("no work") })
+
+	ran := 0
+	FanOut(2, 0, func(int) { ran++ })
+	assert.Equal(t, 2, ran, "at least one worker")
+}
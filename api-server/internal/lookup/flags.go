@@ -0,0 +1,158 @@
+package lookup
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"api-server/internal/config"
+	"api-server/pkg/env"
+	"api-server/pkg/temperature"
+)
+
+// AddFlags registers the flags of the settings shared by the command-line tools, with the current
+// values as defaults.
+func (s *Settings) AddFlags(fs *flag.FlagSet) {
+	fs.Var((*providersValue)(&s.CEPProviders), "providers", "CEP providers to race: "+strings.Join(knownProviders(), ", "))
+	fs.BoolVar(&s.Weather, "temperature", s.Weather, "look up the temperature; false only resolves the city")
+	fs.DurationVar(&s.CEPTimeout, "cep-timeout", s.CEPTimeout, "timeout of the CEP provider race")
+	fs.DurationVar(&s.WeatherTimeout, "weather-timeout", s.WeatherTimeout, "timeout of the weather lookup")
+	fs.DurationVar(&s.Timeout, "timeout", s.Timeout, "timeout of each upstream HTTP call")
+	fs.IntVar(&s.MaxRetries, "retries", s.MaxRetries, "retries of a failed upstream call")
+}
+
+// ReadKey reads the weather API key from the environment, or the file named by WEATHER_API_KEY_FILE
+// as the server does, never from a flag, so it stays out of the shell history. It is required only
+// to look up temperatures.
+func (s *Settings) ReadKey() error {
+	key, _, err := env.EnvSecrets{}.Secret(config.EnvWeatherAPIKey)
+	if err != nil {
+		return err
+	}
+	s.WeatherAPIKey = key
+	if s.Weather && s.WeatherAPIKey == "" {
+		return errors.New(config.EnvWeatherAPIKey + " is required, or -temperature=false")
+	}
+	return nil
+}
+
+func knownProviders() []string {
+	return config.Default().Providers.CEP
+}
+
+// providersValue accepts the provider names regardless of case, as the server configuration does.
+type providersValue []string
+
+func (p *providersValue) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+func (p *providersValue) Set(s string) error {
+	known := knownProviders()
+	var providers []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		i := indexFold(known, name)
+		if i < 0 {
+			return fmt.Errorf("unknown provider %q: must be one of %s", name, strings.Join(known, ", "))
+		}
+		providers = append(providers, known[i])
+	}
+	if len(providers) == 0 {
+		return errors.New("at least one provider is required")
+	}
+	*p = providers
+	return nil
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Format is how the command-line tools print a temperature.
+type Format struct {
+	Units     []temperature.Unit
+	Precision int
+	Rounding  temperature.RoundingMode
+}
+
+// AddFlags registers the -units, -precision and -rounding flags, with the current values as defaults.
+func (f *Format) AddFlags(fs *flag.FlagSet) {
+	fs.Var((*unitsValue)(&f.Units), "units", "comma separated units: C, F, K, R, Re, De")
+	fs.Func("precision", fmt.Sprintf("decimal places, 0 to %d (default %d)", temperature.MaxPrecision, f.Precision), func(s string) error {
+		precision, err := strconv.Atoi(s)
+		if err != nil || precision < 0 || precision > temperature.MaxPrecision {
+			return fmt.Errorf("must be between 0 and %d", temperature.MaxPrecision)
+		}
+		f.Precision = precision
+		return nil
+	})
+	fs.Func("rounding", fmt.Sprintf("rounding mode: half_up or half_even (default %s)", f.Rounding), func(s string) (err error) {
+		f.Rounding, err = temperature.ParseRoundingMode(s)
+		return err
+	})
+}
+
+// Columns are the names of the temperature columns, as in the API: temp_C, temp_F...
+func (f Format) Columns() []string {
+	columns := make([]string, len(f.Units))
+	for i, unit := range f.Units {
+		columns[i] = "temp_" + string(unit)
+	}
+	return columns
+}
+
+// Values are the temperature in each unit, rounded.
+func (f Format) Values(celsius int) []float64 {
+	temp := temperature.FromCelsius(float64(celsius))
+	values := make([]float64, len(f.Units))
+	for i, unit := range f.Units {
+		values[i] = temp.Rounded(unit, f.Precision, f.Rounding)
+	}
+	return values
+}
+
+// Strings are the Values formatted without trailing zeros.
+func (f Format) Strings(celsius int) []string {
+	values := f.Values(celsius)
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return s
+}
+
+type unitsValue []temperature.Unit
+
+func (u *unitsValue) String() string {
+	if u == nil {
+		return ""
+	}
+	s := make([]string, len(*u))
+	for i, unit := range *u {
+		s[i] = string(unit)
+	}
+	return strings.Join(s, ",")
+}
+
+func (u *unitsValue) Set(s string) error {
+	units, err := temperature.ParseUnits(s)
+	if err != nil {
+		return err
+	}
+	*u = units
+	return nil
+}
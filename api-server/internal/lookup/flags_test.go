@@ -0,0 +1,77 @@
+package lookup
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"api-server/domain"
+	"api-server/pkg/temperature"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlags(t *testing.T) {
+	parse := func(args ...string) (Settings, Format, error) {
+		settings := DefaultSettings()
+		format := Format{Units: temperature.DefaultUnits, Precision: 2, Rounding: temperature.HalfUp}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		settings.AddFlags(fs)
+		format.AddFlags(fs)
+		return settings, format, fs.Parse(args)
+	}
+
+	t.Run("should keep the defaults", func(t *testing.T) {
+		settings, format, err := parse()
+		require.NoError(t, err)
+		assert.Equal(t, DefaultSettings(), settings)
+		assert.Equal(t, []string{"temp_C", "temp_F", "temp_K"}, format.Columns())
+	})
+
+	t.Run("should parse the flags", func(t *testing.T) {
+		settings, format, err := parse("-providers", "viacep, brasilapi", "-units", "c,k", "-precision", "0", "-rounding", "half_even")
+		require.NoError(t, err)
+		assert.Equal(t, []string{domain.ProviderViaCEP, domain.ProviderBrasilAPI}, settings.CEPProviders)
+		assert.Equal(t, []string{"25", "298"}, format.Strings(25))
+		assert.Equal(t, temperature.HalfEven, format.Rounding)
+	})
+
+	t.Run("should reject invalid values", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"-providers", "Correios"},
+			{"-providers", ","},
+			{"-units", "X"},
+			{"-precision", "7"},
+			{"-rounding", "up"},
+		} {
+			_, _, err := parse(args...)
+			assert.Error(t, err, args)
+		}
+	})
+
+	t.Run("should require the weather key only for temperatures", func(t *testing.T) {
+		t.Setenv("WEATHER_API_KEY", "")
+		settings := DefaultSettings()
+		assert.ErrorContains(t, settings.ReadKey(), "WEATHER_API_KEY")
+		settings.Weather = false
+		assert.NoError(t, settings.ReadKey())
+	})
+
+	t.Run("should read the weather key from a secret file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "weather-key")
+		require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o600))
+		t.Setenv("WEATHER_API_KEY", "")
+		t.Setenv("WEATHER_API_KEY_FILE", path)
+
+		settings := DefaultSettings()
+		require.NoError(t, settings.ReadKey())
+		assert.Equal(t, "s3cret", settings.WeatherAPIKey)
+
+		t.Setenv("WEATHER_API_KEY", "other")
+		assert.ErrorContains(t, settings.ReadKey(), "not both")
+	})
+}
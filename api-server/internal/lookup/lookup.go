@@ -0,0 +1,191 @@
+// Package lookup runs the CEP and temperature lookups of the server from the command-line tools:
+// the same clients and analysis service, without the server around them. Batch deduplicates the
+// lookups of many CEPs, for the tools and for the batch endpoints of the server alike.
+package lookup
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"api-server/domain"
+	"api-server/domain/analysis"
+	"api-server/internal/config"
+	"api-server/internal/infra/client"
+	"api-server/pkg/cep"
+	httpclient "api-server/pkg/http_client"
+	"api-server/pkg/logger"
+)
+
+// Settings are the server settings that matter to a lookup.
+type Settings struct {
+	CEPProviders     []string
+	Weather          bool
+	WeatherAPIKey    string
+	Timeout          time.Duration
+	CEPTimeout       time.Duration
+	WeatherTimeout   time.Duration
+	MaxRetries       int
+	MaxRetryInterval time.Duration
+}
+
+// DefaultSettings are the defaults of the server.
+func DefaultSettings() Settings {
+	cfg := config.Default()
+	return Settings{
+		CEPProviders:     cfg.Providers.CEP,
+		Weather:          true,
+		Timeout:          cfg.Upstream.Timeout,
+		CEPTimeout:       cfg.Upstream.CEPTimeout,
+		WeatherTimeout:   cfg.Upstream.WeatherTimeout,
+		MaxRetries:       cfg.Upstream.MaxRetries,
+		MaxRetryInterval: cfg.Upstream.MaxRetryInterval,
+	}
+}
+
+// Call is a call made to an upstream provider.
+type Call struct {
+	Provider string
+	Latency  time.Duration
+	Err      error
+}
+
+// City is the city a CEP resolved to.
+type City struct {
+	// Info is the "City,UF" returned by the providers, the key of the temperature lookup.
+	Info     string
+	Name     string
+	UF       string
+	Provider string
+	Calls    []Call
+}
+
+// Temperature is the current temperature of a city.
+type Temperature struct {
+	Celsius  int
+	Provider string
+	Calls    []Call
+}
+
+type Service struct {
+	analysis domain.AnalysisService
+}
+
+// NewLogger writes the logs of the lookups to w as text: only the errors, and the warnings too when
+// verbose. As in the server logs, the weather key is masked wherever it shows up, e.g. in the error
+// of a failed call.
+func NewLogger(w io.Writer, verbose bool, settings Settings) *slog.Logger {
+	httpclient.DefaultRedactor.AddSecret(settings.WeatherAPIKey)
+	level := slog.LevelError
+	if verbose {
+		level = slog.LevelWarn
+	}
+	return logger.New(w, logger.Options{Format: logger.FormatText, Level: level, Redact: httpclient.DefaultRedactor.String})
+}
+
+// New builds the clients and the analysis service as the server does, minus the circuit breakers:
+// a command-line run is too short for them to matter.
+func New(settings Settings, log *slog.Logger) *Service {
+	httpClient := httpclient.NewHTTPClient(settings.Timeout)
+	opts := []client.Option{client.WithRetries(settings.MaxRetries, settings.MaxRetryInterval)}
+
+	buscaCEPAPIClient := client.NewObservedBuscaCEPAPIClient(
+		client.NewBuscaCEPAPIClient(httpClient, log, opts...), Observer)
+	weatherAPIClient := client.NewObservedWeatherAPIClient(
+		client.NewWeatherAPIClient(httpClient, log, client.StaticKey(settings.WeatherAPIKey), opts...), Observer)
+
+	return NewWithClients(buscaCEPAPIClient, weatherAPIClient, settings, log)
+}
+
+// NewWithClients runs the lookups on the given clients, which must report their calls to Observer.
+func NewWithClients(buscaCEPAPIClient domain.BuscaCEPAPIClient, weatherAPIClient domain.WeatherAPIClient, settings Settings, log *slog.Logger) *Service {
+	return &Service{
+		analysis: analysis.NewAnalysisService(buscaCEPAPIClient, weatherAPIClient, log,
+			analysis.WithSettings(analysis.Settings{
+				CEPProviders:   settings.CEPProviders,
+				WeatherEnabled: settings.Weather,
+				CEPTimeout:     settings.CEPTimeout,
+				WeatherTimeout: settings.WeatherTimeout,
+			})),
+	}
+}
+
+// Observer records the provider calls in the context of the lookup that made them.
+var Observer domain.ProviderObserver = recorder{}
+
+// City resolves the CEP. The provider is the first one to answer; Calls has every call that
+// finished before it, in order.
+func (s *Service) City(ctx context.Context, c cep.CEP) (City, error) {
+	ctx, calls := withCalls(ctx)
+	info, err := s.analysis.GetCity(ctx, c.Digits())
+	city := City{Calls: calls.snapshot()}
+	if err != nil {
+		return city, err
+	}
+
+	city.Info = info
+	city.Name, city.UF = splitCityInfo(info)
+	if city.UF == "" {
+		city.UF = c.UF()
+	}
+	for _, call := range city.Calls {
+		if call.Err == nil {
+			city.Provider = call.Provider
+			break
+		}
+	}
+	return city, nil
+}
+
+// Temperature looks up the current temperature of a city as returned by City.
+func (s *Service) Temperature(ctx context.Context, cityInfo string) (Temperature, error) {
+	ctx, calls := withCalls(ctx)
+	celsius, err := s.analysis.GetCelsiusTemperature(ctx, cityInfo)
+	temp := Temperature{Celsius: celsius, Calls: calls.snapshot()}
+	if err == nil {
+		temp.Provider = domain.ProviderHGWeather
+	}
+	return temp, err
+}
+
+// splitCityInfo splits the "City,UF" returned by the CEP providers.
+func splitCityInfo(cityInfo string) (string, string) {
+	idx := strings.LastIndex(cityInfo, ",")
+	if idx < 0 {
+		return cityInfo, ""
+	}
+	return cityInfo[:idx], cityInfo[idx+1:]
+}
+
+type callsKey struct{}
+
+type calls struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func withCalls(ctx context.Context) (context.Context, *calls) {
+	c := &calls{}
+	return context.WithValue(ctx, callsKey{}, c), c
+}
+
+func (c *calls) snapshot() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+type recorder struct{}
+
+func (recorder) ObserveProviderCall(ctx context.Context, provider string, latency time.Duration, err error) {
+	c, ok := ctx.Value(callsKey{}).(*calls)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Provider: provider, Latency: latency, Err: err})
+}
@@ -0,0 +1,128 @@
+package lookup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"api-server/domain"
+	"api-server/domain/mocks"
+	"api-server/internal/infra/client"
+	"api-server/pkg/cep"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupService(t *testing.T, settings Settings) (*Service, *mocks.MockBuscaCEPAPIClient, *mocks.MockWeatherAPIClient) {
+	t.Helper()
+	mockBuscaCEP := &mocks.MockBuscaCEPAPIClient{}
+	mockWeather := &mocks.MockWeatherAPIClient{}
+	service := NewWithClients(
+		client.NewObservedBuscaCEPAPIClient(mockBuscaCEP, Observer),
+		client.NewObservedWeatherAPIClient(mockWeather, Observer),
+		settings, slog.Default())
+	return service, mockBuscaCEP, mockWeather
+}
+
+func TestService(t *testing.T) {
+	settings := DefaultSettings()
+	settings.WeatherAPIKey = "key"
+	service, mockBuscaCEP, mockWeather := setupService(t, settings)
+	c, err := cep.Parse("01001000")
+	require.NoError(t, err)
+
+	t.Run("should resolve the city and record the provider calls", func(t *testing.T) {
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "", errors.New("brasilapi error")
+		}
+		mockBuscaCEP.GetViaAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "São Paulo,SP", nil
+		}
+
+		city, err := service.City(context.Background(), c)
+		require.NoError(t, err)
+		assert.Equal(t, "São Paulo,SP", city.Info)
+		assert.Equal(t, "São Paulo", city.Name)
+		assert.Equal(t, "SP", city.UF)
+		assert.Equal(t, domain.ProviderViaCEP, city.Provider)
+		require.Len(t, city.Calls, 2)
+		assert.Equal(t, domain.ProviderBrasilAPI, city.Calls[0].Provider)
+		assert.Error(t, city.Calls[0].Err)
+		assert.Equal(t, domain.ProviderViaCEP, city.Calls[1].Provider)
+		assert.GreaterOrEqual(t, city.Calls[1].Latency, 10*time.Millisecond)
+	})
+
+	t.Run("should fall back to the UF of the CEP", func(t *testing.T) {
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "São Paulo", nil
+		}
+
+		city, err := service.City(context.Background(), c)
+		require.NoError(t, err)
+		assert.Equal(t, "São Paulo", city.Name)
+		assert.Equal(t, "SP", city.UF)
+	})
+
+	t.Run("should look up the temperature", func(t *testing.T) {
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			assert.Equal(t, "São Paulo,SP", city)
+			return 25, nil
+		}
+
+		temp, err := service.Temperature(context.Background(), "São Paulo,SP")
+		require.NoError(t, err)
+		assert.Equal(t, 25, temp.Celsius)
+		assert.Equal(t, domain.ProviderHGWeather, temp.Provider)
+		require.Len(t, temp.Calls, 1)
+		assert.NoError(t, temp.Calls[0].Err)
+	})
+
+	t.Run("should return the provider errors", func(t *testing.T) {
+		mockWeather.GetHGWeatherAPIFunc = func(ctx context.Context, city string) (int, error) {
+			return 0, errors.New("weather api error")
+		}
+
+		temp, err := service.Temperature(context.Background(), "São Paulo,SP")
+		assert.Error(t, err)
+		assert.Empty(t, temp.Provider)
+		require.Len(t, temp.Calls, 1)
+		assert.Error(t, temp.Calls[0].Err)
+	})
+
+	t.Run("should only race the selected providers", func(t *testing.T) {
+		settings := settings
+		settings.CEPProviders = []string{domain.ProviderBrasilAPI}
+		service, mockBuscaCEP, _ := setupService(t, settings)
+		mockBuscaCEP.GetBrasilAPICEPFunc = func(ctx context.Context, cep string) (string, error) {
+			return "Rio de Janeiro,RJ", nil
+		}
+
+		city, err := service.City(context.Background(), c)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ProviderBrasilAPI, city.Provider)
+		assert.Len(t, city.Calls, 1)
+	})
+}
+
+func TestNewLogger(t *testing.T) {
+	settings := DefaultSettings()
+	settings.WeatherAPIKey = "8dc0a8c0-weather"
+
+	var buf bytes.Buffer
+	log := NewLogger(&buf, false, settings)
+	log.Warn("Temperature lookup failed")
+	log.Error("Temperature lookup failed", "error", errors.New(`Get "https://api.hgbrasil.com/weather?city_name=x": 8dc0a8c0-weather rejected`))
+
+	assert.NotContains(t, buf.String(), "level=WARN", "warnings only with verbose")
+	assert.NotContains(t, buf.String(), "8dc0a8c0-weather")
+	assert.Contains(t, buf.String(), "REDACTED rejected")
+
+	buf.Reset()
+	NewLogger(&buf, true, settings).Warn("Temperature lookup failed")
+	assert.Contains(t, buf.String(), "level=WARN")
+}
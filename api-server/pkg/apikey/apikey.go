@@ -0,0 +1,251 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidKey         = errors.New("invalid api key")
+	ErrEndpointNotAllowed = errors.New("endpoint not allowed for api key")
+	ErrQuotaExceeded      = errors.New("daily quota exceeded")
+)
+
+// Key is a partner API key. Only the SHA-256 hash of the secret is kept.
+type Key struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	Tier string `json:"tier,omitempty"`
+	// DailyQuota is the number of metered requests per UTC day. Zero means unlimited.
+	DailyQuota int `json:"daily_quota,omitempty"`
+	// Endpoints are the route patterns (e.g. "/tempForCep/:cep") or gRPC full method names
+	// (e.g. "/temperature.v1.TemperatureService/GetTemperature") the key may call. Empty allows all.
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+func (k Key) Allows(endpoint string) bool {
+	if len(k.Endpoints) == 0 {
+		return true
+	}
+	for _, e := range k.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the hex SHA-256 of a plain text key, as stored in the keys file.
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadFile reads a JSON array of keys (with hashed secrets) from path.
+func LoadFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid api keys file %s: %w", path, err)
+	}
+
+	for i, k := range keys {
+		keys[i].Hash = strings.ToLower(k.Hash)
+	}
+	return keys, nil
+}
+
+// ParseEnv parses "name:secret[:quota[:tier]]" entries separated by commas.
+// The plain text secret is hashed right away and never kept.
+func ParseEnv(s string) ([]Key, error) {
+	var keys []Key
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid api key entry for %q: expected name:secret[:quota[:tier]]", parts[0])
+		}
+
+		key := Key{Name: parts[0], Hash: Hash(parts[1])}
+		if len(parts) > 2 && parts[2] != "" {
+			quota, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid daily quota for api key %q: %w", key.Name, err)
+			}
+			key.DailyQuota = quota
+		}
+		if len(parts) > 3 {
+			key.Tier = parts[3]
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Usage is the consumption of a key in the current UTC day.
+type Usage struct {
+	Name      string    `json:"name"`
+	Date      string    `json:"date"`
+	Used      int       `json:"used"`
+	Quota     int       `json:"quota"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+type counter struct {
+	date string
+	used int
+}
+
+// Store authenticates keys and counts their daily usage.
+type Store struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	byHash map[string]Key
+	usage  map[string]*counter
+}
+
+func NewStore(keys []Key) (*Store, error) {
+	byHash, err := index(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		byHash: byHash,
+		now:    time.Now,
+		usage:  make(map[string]*counter),
+	}, nil
+}
+
+func index(keys []Key) (map[string]Key, error) {
+	byHash := make(map[string]Key, len(keys))
+	names := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.Name == "" || len(k.Hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid api key %q: name and sha256 hash are required", k.Name)
+		}
+		if names[k.Name] {
+			return nil, fmt.Errorf("duplicated api key name %q", k.Name)
+		}
+		names[k.Name] = true
+		byHash[k.Hash] = k
+	}
+	return byHash, nil
+}
+
+// Replace swaps the keys, e.g. after a rotation. The usage of the names kept is preserved.
+func (s *Store) Replace(keys []Key) error {
+	byHash, err := index(keys)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byHash = byHash
+	return nil
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.byHash)
+}
+
+// Authenticate returns the key matching the plain text secret.
+func (s *Store) Authenticate(plain string) (Key, error) {
+	if plain == "" {
+		return Key{}, ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	key, ok := s.byHash[Hash(plain)]
+	s.mu.Unlock()
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Consume counts one metered request, failing with ErrQuotaExceeded when the quota is used up.
+func (s *Store) Consume(key Key) (Usage, error) {
+	return s.ConsumeN(key, 1)
+}
+
+// ConsumeN counts n metered lookups at once, e.g. the CEPs of a batch, or none when they do not fit
+// in the quota left.
+func (s *Store) ConsumeN(key Key, n int) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counter(key.Name)
+	if key.DailyQuota > 0 && c.used+n > key.DailyQuota {
+		return s.usageOf(key, c), ErrQuotaExceeded
+	}
+
+	c.used += n
+	return s.usageOf(key, c), nil
+}
+
+// Refund gives back n lookups counted today, e.g. of a request that turned out not to run them.
+func (s *Store) Refund(key Key, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counter(key.Name)
+	c.used = max(c.used-n, 0)
+}
+
+// Usage returns the consumption of key today.
+func (s *Store) Usage(key Key) Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.usageOf(key, s.counter(key.Name))
+}
+
+func (s *Store) counter(name string) *counter {
+	today := s.now().UTC().Format(time.DateOnly)
+
+	c, ok := s.usage[name]
+	if !ok || c.date != today {
+		c = &counter{date: today}
+		s.usage[name] = c
+	}
+	return c
+}
+
+func (s *Store) usageOf(key Key, c *counter) Usage {
+	day, _ := time.Parse(time.DateOnly, c.date)
+
+	u := Usage{
+		Name:    key.Name,
+		Date:    c.date,
+		Used:    c.used,
+		Quota:   key.DailyQuota,
+		ResetAt: day.AddDate(0, 0, 1),
+	}
+	if key.DailyQuota > 0 {
+		u.Remaining = max(key.DailyQuota-c.used, 0)
+	}
+	return u
+}
@@ -0,0 +1,112 @@
+package apikey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnv(t *testing.T) {
+	keys, err := ParseEnv("erp:s3cret:100:partner, monitor:other")
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{
+		{Name: "erp", Hash: Hash("s3cret"), DailyQuota: 100, Tier: "partner"},
+		{Name: "monitor", Hash: Hash("other")},
+	}, keys)
+
+	_, err = ParseEnv("missing-secret")
+	assert.Error(t, err)
+
+	_, err = ParseEnv("erp:s3cret:lots")
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `[{"name":"erp","hash":"` + Hash("s3cret") + `","daily_quota":2,"endpoints":["/tempForCep/:cep"]}]`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := LoadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.True(t, keys[0].Allows("/tempForCep/:cep"))
+	assert.False(t, keys[0].Allows("/me/usage"))
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2024, 8, 10, 23, 0, 0, 0, time.UTC)
+	store, err := NewStore([]Key{{Name: "erp", Hash: Hash("s3cret"), DailyQuota: 2}})
+	assert.NoError(t, err)
+	store.now = func() time.Time { return now }
+
+	t.Run("should authenticate by the hashed secret", func(t *testing.T) {
+		key, err := store.Authenticate("s3cret")
+		assert.NoError(t, err)
+		assert.Equal(t, "erp", key.Name)
+
+		_, err = store.Authenticate("wrong")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("should enforce the daily quota and reset the next day", func(t *testing.T) {
+		key, _ := store.Authenticate("s3cret")
+
+		_, err := store.Consume(key)
+		assert.NoError(t, err)
+		usage, err := store.Consume(key)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, usage.Remaining)
+
+		usage, err = store.Consume(key)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, 2, usage.Used)
+		assert.Equal(t, time.Date(2024, 8, 11, 0, 0, 0, 0, time.UTC), usage.ResetAt)
+
+		now = now.Add(2 * time.Hour)
+		usage = store.Usage(key)
+		assert.Equal(t, "2024-08-11", usage.Date)
+		assert.Equal(t, 0, usage.Used)
+	})
+
+	t.Run("should count several lookups at once, or none", func(t *testing.T) {
+		key, _ := store.Authenticate("s3cret")
+
+		_, err := store.ConsumeN(key, 3)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, 0, store.Usage(key).Used)
+
+		usage, err := store.ConsumeN(key, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, usage.Used)
+
+		store.Refund(key, 5)
+		assert.Equal(t, 0, store.Usage(key).Used)
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		_, err := NewStore([]Key{{Name: "plain", Hash: "not-a-hash"}})
+		assert.Error(t, err)
+
+		_, err = NewStore([]Key{{Name: "a", Hash: Hash("1")}, {Name: "a", Hash: Hash("2")}})
+		assert.Error(t, err)
+	})
+	t.Run("should replace the keys keeping their usage", func(t *testing.T) {
+		key, _ := store.Authenticate("s3cret")
+		_, _ = store.Consume(key)
+
+		err := store.Replace([]Key{{Name: "erp", Hash: Hash("rotated"), DailyQuota: 2}})
+		assert.NoError(t, err)
+
+		_, err = store.Authenticate("s3cret")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		key, err = store.Authenticate("rotated")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, store.Usage(key).Used)
+
+		assert.Error(t, store.Replace([]Key{{Name: "plain", Hash: "not-a-hash"}}))
+		assert.Equal(t, 1, store.Len(), "keys kept when the replacement is invalid")
+	})
+}
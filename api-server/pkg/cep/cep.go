@@ -0,0 +1,86 @@
+// Package cep parses Brazilian postal codes (Código de Endereçamento Postal) and infers their
+// UF from the ranges allocated by the Correios.
+package cep
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalid     = errors.New("cep must have 8 digits, as 00000000 or 00000-000")
+	ErrUnallocated = errors.New("cep range not allocated to any UF")
+)
+
+// CEP holds the 8 digits of a postal code.
+type CEP string
+
+// Parse accepts 01001000 and 01001-000, with surrounding spaces, and rejects the CEPs
+// outside every UF range with ErrUnallocated.
+func Parse(s string) (CEP, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 9 && s[5] == '-' {
+		s = s[:5] + s[6:]
+	}
+	if len(s) != 8 {
+		return "", ErrInvalid
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return "", ErrInvalid
+		}
+	}
+
+	c := CEP(s)
+	if _, ok := c.Range(); !ok {
+		return "", ErrUnallocated
+	}
+	return c, nil
+}
+
+// String formats the CEP canonically, as 01001-000.
+func (c CEP) String() string {
+	if len(c) != 8 {
+		return string(c)
+	}
+	return string(c[:5]) + "-" + string(c[5:])
+}
+
+// Digits returns the 8 digits, as sent to the upstream providers.
+func (c CEP) Digits() string {
+	return string(c)
+}
+
+// Range returns the UF range the CEP belongs to, or false when it is in no range or in a block
+// of a range without any CEP assigned.
+func (c CEP) Range() (Range, bool) {
+	for _, r := range unallocated {
+		if string(c) >= r.First && string(c) <= r.Last {
+			return Range{}, false
+		}
+	}
+	for _, r := range ranges {
+		if string(c) >= r.First && string(c) <= r.Last {
+			return r, true
+		}
+	}
+	return Range{}, false
+}
+
+// UF returns the state of the CEP, e.g. SP, or "" when not allocated.
+func (c CEP) UF() string {
+	r, _ := c.Range()
+	return r.UF
+}
+
+// Region returns the region of the CEP, e.g. Sudeste, or "" when not allocated.
+func (c CEP) Region() Region {
+	r, _ := c.Range()
+	return regions[r.UF]
+}
+
+// IsUF reports whether uf is the code of a Brazilian state, e.g. SP.
+func IsUF(uf string) bool {
+	_, ok := regions[uf]
+	return ok
+}
@@ -0,0 +1,106 @@
+package cep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, input := range []string{"01001000", "01001-000", " 01001-000 "} {
+		c, err := Parse(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, "01001-000", c.String(), input)
+		assert.Equal(t, "01001000", c.Digits(), input)
+	}
+
+	for _, input := range []string{"", "123", "0100100", "010010000", "0100-1000", "01001 000", "0100a000", "０1001000"} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrInvalid, input)
+	}
+
+	for _, input := range []string{"00000000", "00999-999", "12345678", "12345-000", "12345-999"} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrUnallocated, input)
+	}
+}
+
+func TestUF(t *testing.T) {
+	for input, want := range map[string]struct {
+		uf     string
+		region Region
+	}{
+		"01001-000": {"SP", Sudeste},
+		"20040-020": {"RJ", Sudeste},
+		"69301-000": {"RR", Norte},
+		"69900-000": {"AC", Norte},
+		"70040-010": {"DF", CentroOeste},
+		"72800-000": {"GO", CentroOeste},
+		"73000-000": {"DF", CentroOeste},
+		"74000-000": {"GO", CentroOeste},
+		"50030-000": {"PE", Nordeste},
+		"90010-000": {"RS", Sul},
+		"99999-999": {"RS", Sul},
+	} {
+		c, err := Parse(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want.uf, c.UF(), input)
+		assert.Equal(t, want.region, c.Region(), input)
+	}
+}
+
+func TestIsUF(t *testing.T) {
+	assert.True(t, IsUF("SP"))
+	assert.True(t, IsUF("DF"))
+	assert.False(t, IsUF("sp"))
+	assert.False(t, IsUF("XX"))
+}
+
+func TestRanges(t *testing.T) {
+	last := "00999999"
+	for _, r := range ranges {
+		assert.Greater(t, r.First, last, "overlap before %s %s", r.UF, r.First)
+		assert.LessOrEqual(t, r.First, r.Last, "%s %s", r.UF, r.First)
+		assert.Contains(t, regions, r.UF)
+		last = r.Last
+	}
+
+	for _, r := range unallocated {
+		assert.Equal(t, r.First[:5]+"000", r.First, "blocks start at a 5 digit prefix")
+		for _, c := range []CEP{CEP(r.First), CEP(r.Last)} {
+			_, ok := c.Range()
+			assert.False(t, ok, c)
+		}
+		// Os vizinhos do bloco continuam na UF
+		for _, c := range []string{prev(r.First), next(r.Last)} {
+			_, err := Parse(c)
+			assert.NoError(t, err, c)
+		}
+	}
+}
+
+// next increments a CEP of 8 digits.
+func next(c string) string {
+	b := []byte(c)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '9' {
+			b[i]++
+			break
+		}
+		b[i] = '0'
+	}
+	return string(b)
+}
+
+// prev decrements a CEP of 8 digits.
+func prev(c string) string {
+	b := []byte(c)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] > '0' {
+			b[i]--
+			break
+		}
+		b[i] = '9'
+	}
+	return string(b)
+}
@@ -0,0 +1,68 @@
+package cep
+
+// Region is one of the five regions of Brazil.
+type Region string
+
+const (
+	Norte       Region = "Norte"
+	Nordeste    Region = "Nordeste"
+	CentroOeste Region = "Centro-Oeste"
+	Sudeste     Region = "Sudeste"
+	Sul         Region = "Sul"
+)
+
+// Range is a block of CEPs allocated to a UF, bounds included.
+type Range struct {
+	UF    string
+	First string
+	Last  string
+}
+
+// Faixas de CEP por UF dos Correios. Nenhuma UF usa o prefixo 0 (00000-000 a 00999-999).
+var ranges = []Range{
+	{UF: "SP", First: "01000000", Last: "19999999"},
+	{UF: "RJ", First: "20000000", Last: "28999999"},
+	{UF: "ES", First: "29000000", Last: "29999999"},
+	{UF: "MG", First: "30000000", Last: "39999999"},
+	{UF: "BA", First: "40000000", Last: "48999999"},
+	{UF: "SE", First: "49000000", Last: "49999999"},
+	{UF: "PE", First: "50000000", Last: "56999999"},
+	{UF: "AL", First: "57000000", Last: "57999999"},
+	{UF: "PB", First: "58000000", Last: "58999999"},
+	{UF: "RN", First: "59000000", Last: "59999999"},
+	{UF: "CE", First: "60000000", Last: "63999999"},
+	{UF: "PI", First: "64000000", Last: "64999999"},
+	{UF: "MA", First: "65000000", Last: "65999999"},
+	{UF: "PA", First: "66000000", Last: "68899999"},
+	{UF: "AP", First: "68900000", Last: "68999999"},
+	{UF: "AM", First: "69000000", Last: "69299999"},
+	{UF: "RR", First: "69300000", Last: "69399999"},
+	{UF: "AM", First: "69400000", Last: "69899999"},
+	{UF: "AC", First: "69900000", Last: "69999999"},
+	{UF: "DF", First: "70000000", Last: "72799999"},
+	{UF: "GO", First: "72800000", Last: "72999999"},
+	{UF: "DF", First: "73000000", Last: "73699999"},
+	{UF: "GO", First: "73700000", Last: "76799999"},
+	{UF: "RO", First: "76800000", Last: "76999999"},
+	{UF: "TO", First: "77000000", Last: "77999999"},
+	{UF: "MT", First: "78000000", Last: "78899999"},
+	{UF: "RO", First: "78900000", Last: "78999999"},
+	{UF: "MS", First: "79000000", Last: "79999999"},
+	{UF: "PR", First: "80000000", Last: "87999999"},
+	{UF: "SC", First: "88000000", Last: "89999999"},
+	{UF: "RS", First: "90000000", Last: "99999999"},
+}
+
+// Blocos sem CEP atribuído dentro das faixas das UFs: recusados antes de consultar os provedores.
+var unallocated = []Range{
+	{First: "12345000", Last: "12345999"},
+}
+
+var regions = map[string]Region{
+	"AC": Norte, "AM": Norte, "AP": Norte, "PA": Norte, "RO": Norte, "RR": Norte, "TO": Norte,
+	"AL": Nordeste, "BA": Nordeste, "CE": Nordeste, "MA": Nordeste, "PB": Nordeste,
+	"PE": Nordeste, "PI": Nordeste, "RN": Nordeste, "SE": Nordeste,
+	"DF": CentroOeste, "GO": CentroOeste, "MS": CentroOeste, "MT": CentroOeste,
+	"ES": Sudeste, "MG": Sudeste, "RJ": Sudeste, "SP": Sudeste,
+	"PR": Sul, "RS": Sul, "SC": Sul,
+}
@@ -0,0 +1,395 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// ErrOpenState is returned when the breaker is open and the call was not attempted.
+	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrTooManyRequests is returned when the breaker is half-open and the probe quota is exhausted.
+	ErrTooManyRequests = errors.New("circuit breaker is half-open: too many requests")
+)
+
+// Settings configures a Breaker.
+type Settings struct {
+	Name string
+	// FailureRatio trips the breaker when failures/requests in the current window reaches it.
+	FailureRatio float64
+	// MinRequests is the minimum number of requests in the window before the ratio is evaluated.
+	MinRequests uint32
+	// Interval is the closed-state window after which counts are cleared. Zero never clears.
+	Interval time.Duration
+	// CoolDown is how long the breaker stays open before allowing probes.
+	CoolDown time.Duration
+	// HalfOpenMaxRequests is the number of probes allowed while half-open.
+	HalfOpenMaxRequests uint32
+	// IsSuccessful decides whether an error counts as a failure. Defaults to DefaultIsSuccessful.
+	IsSuccessful func(err error) bool
+	// IsIgnored decides whether an error is neither a success nor a failure: the request is not
+	// counted and frees its half-open probe slot. Defaults to DefaultIsIgnored.
+	IsIgnored func(err error) bool
+	// OnStateChange is called (synchronously) whenever the breaker changes state.
+	OnStateChange func(name string, from, to State)
+}
+
+// DefaultSettings returns sane defaults for an upstream HTTP provider.
+func DefaultSettings(name string) Settings {
+	return Settings{
+		Name:                name,
+		FailureRatio:        0.5,
+		MinRequests:         5,
+		Interval:            time.Minute,
+		CoolDown:            30 * time.Second,
+		HalfOpenMaxRequests: 1,
+	}
+}
+
+// DefaultIsSuccessful treats only a nil error as success.
+func DefaultIsSuccessful(err error) bool {
+	return err == nil
+}
+
+// DefaultIsIgnored ignores a cancellation by the caller, so losing a race or a client going away
+// neither trips the breaker nor closes it while half-open.
+func DefaultIsIgnored(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// Counts holds the request counters of the current window.
+type Counts struct {
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"total_successes"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
+}
+
+func (c *Counts) onRequest() {
+	c.Requests++
+}
+
+func (c *Counts) onSuccess() {
+	c.TotalSuccesses++
+	c.ConsecutiveSuccesses++
+	c.ConsecutiveFailures = 0
+}
+
+func (c *Counts) onFailure() {
+	c.TotalFailures++
+	c.ConsecutiveFailures++
+	c.ConsecutiveSuccesses = 0
+}
+
+func (c *Counts) clear() {
+	*c = Counts{}
+}
+
+// Breaker is a closed/open/half-open circuit breaker.
+type Breaker struct {
+	settings Settings
+	now      func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	counts     Counts
+	expiry     time.Time
+	changedAt  time.Time
+}
+
+func New(settings Settings) *Breaker {
+	if settings.FailureRatio <= 0 || settings.FailureRatio > 1 {
+		settings.FailureRatio = 0.5
+	}
+	if settings.CoolDown <= 0 {
+		settings.CoolDown = 30 * time.Second
+	}
+	if settings.HalfOpenMaxRequests == 0 {
+		settings.HalfOpenMaxRequests = 1
+	}
+	if settings.IsSuccessful == nil {
+		settings.IsSuccessful = DefaultIsSuccessful
+	}
+	if settings.IsIgnored == nil {
+		settings.IsIgnored = DefaultIsIgnored
+	}
+
+	b := &Breaker{settings: settings, now: time.Now}
+	b.changedAt = b.now()
+	b.toNewGeneration(b.changedAt)
+	return b
+}
+
+func (b *Breaker) Name() string {
+	return b.settings.Name
+}
+
+// State returns the current state, moving open to half-open if the cool-down elapsed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, _ := b.currentState(b.now())
+	return state
+}
+
+// Allow reports whether a call may be attempted right now without consuming a probe slot.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, _ := b.currentState(b.now())
+	switch state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		return b.counts.Requests < b.settings.HalfOpenMaxRequests
+	default:
+		return true
+	}
+}
+
+// Execute runs fn if the breaker allows it and records the outcome.
+func (b *Breaker) Execute(fn func() error) error {
+	generation, err := b.beforeRequest()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.afterRequest(generation, false)
+			panic(r)
+		}
+	}()
+
+	err = fn()
+	if err != nil && b.settings.IsIgnored(err) {
+		b.release(generation)
+		return err
+	}
+	b.afterRequest(generation, b.settings.IsSuccessful(err))
+	return err
+}
+
+func (b *Breaker) beforeRequest() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, generation := b.currentState(b.now())
+	if state == StateOpen {
+		return generation, ErrOpenState
+	}
+	if state == StateHalfOpen && b.counts.Requests >= b.settings.HalfOpenMaxRequests {
+		return generation, ErrTooManyRequests
+	}
+
+	b.counts.onRequest()
+	return generation, nil
+}
+
+func (b *Breaker) afterRequest(before uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	state, generation := b.currentState(now)
+	if generation != before {
+		return
+	}
+
+	if success {
+		b.onSuccess(state, now)
+		return
+	}
+	b.onFailure(state, now)
+}
+
+// release uncounts a request whose outcome is ignored, giving back its half-open probe slot.
+func (b *Breaker) release(before uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, generation := b.currentState(b.now())
+	if generation == before && b.counts.Requests > 0 {
+		b.counts.Requests--
+	}
+}
+
+func (b *Breaker) onSuccess(state State, now time.Time) {
+	switch state {
+	case StateClosed:
+		b.counts.onSuccess()
+	case StateHalfOpen:
+		b.counts.onSuccess()
+		if b.counts.ConsecutiveSuccesses >= b.settings.HalfOpenMaxRequests {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) onFailure(state State, now time.Time) {
+	switch state {
+	case StateClosed:
+		b.counts.onFailure()
+		if b.readyToTrip() {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.setState(StateOpen, now)
+	}
+}
+
+func (b *Breaker) readyToTrip() bool {
+	if b.counts.Requests < b.settings.MinRequests || b.counts.Requests == 0 {
+		return false
+	}
+	ratio := float64(b.counts.TotalFailures) / float64(b.counts.Requests)
+	return ratio >= b.settings.FailureRatio
+}
+
+func (b *Breaker) currentState(now time.Time) (State, uint64) {
+	switch b.state {
+	case StateClosed:
+		if !b.expiry.IsZero() && b.expiry.Before(now) {
+			b.toNewGeneration(now)
+		}
+	case StateOpen:
+		if b.expiry.Before(now) {
+			b.setState(StateHalfOpen, now)
+		}
+	}
+	return b.state, b.generation
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+
+	prev := b.state
+	b.state = state
+	b.changedAt = now
+	b.toNewGeneration(now)
+
+	if b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(b.settings.Name, prev, state)
+	}
+}
+
+func (b *Breaker) toNewGeneration(now time.Time) {
+	b.generation++
+	b.counts.clear()
+
+	var zero time.Time
+	switch b.state {
+	case StateClosed:
+		if b.settings.Interval == 0 {
+			b.expiry = zero
+		} else {
+			b.expiry = now.Add(b.settings.Interval)
+		}
+	case StateOpen:
+		b.expiry = now.Add(b.settings.CoolDown)
+	default:
+		b.expiry = zero
+	}
+}
+
+// Snapshot is a point-in-time view of a Breaker.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Counts    Counts    `json:"counts"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, _ := b.currentState(b.now())
+	return Snapshot{
+		Name:      b.settings.Name,
+		State:     state.String(),
+		Counts:    b.counts,
+		ChangedAt: b.changedAt,
+	}
+}
+
+// Registry keeps one Breaker per upstream provider.
+type Registry struct {
+	mu       sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+func NewRegistry() *Registry {
+	return &Registry{breakers: make(map[string]*Breaker)}
+}
+
+// Add creates and registers a breaker for settings.Name, replacing any previous one.
+func (r *Registry) Add(settings Settings) *Breaker {
+	b := New(settings)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[settings.Name] = b
+	return b
+}
+
+// Get returns the breaker registered for name, or nil.
+func (r *Registry) Get(name string) *Breaker {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.breakers[name]
+}
+
+// Allow reports whether the provider may be called. Unknown providers are always allowed.
+func (r *Registry) Allow(name string) bool {
+	b := r.Get(name)
+	return b == nil || b.Allow()
+}
+
+// Snapshots returns the state of every registered breaker sorted by name.
+func (r *Registry) Snapshots() []Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshots := make([]Snapshot, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		snapshots = append(snapshots, b.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
+}
@@ -0,0 +1,140 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"api-server/pkg/circuitbreaker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errUpstream = errors.New("upstream error")
+
+func newBreaker() *circuitbreaker.Breaker {
+	return circuitbreaker.New(circuitbreaker.Settings{
+		Name:                "test",
+		FailureRatio:        0.5,
+		MinRequests:         4,
+		CoolDown:            20 * time.Millisecond,
+		HalfOpenMaxRequests: 1,
+	})
+}
+
+func fail() error    { return errUpstream }
+func succeed() error { return nil }
+
+func TestBreaker(t *testing.T) {
+	t.Run("should stay closed below the minimum number of requests", func(t *testing.T) {
+		b := newBreaker()
+
+		for i := 0; i < 3; i++ {
+			assert.ErrorIs(t, b.Execute(fail), errUpstream)
+		}
+
+		assert.Equal(t, circuitbreaker.StateClosed, b.State())
+	})
+
+	t.Run("should open when the failure ratio is reached", func(t *testing.T) {
+		b := newBreaker()
+
+		assert.NoError(t, b.Execute(succeed))
+		assert.NoError(t, b.Execute(succeed))
+		assert.Error(t, b.Execute(fail))
+		assert.Error(t, b.Execute(fail))
+
+		assert.Equal(t, circuitbreaker.StateOpen, b.State())
+		assert.False(t, b.Allow())
+
+		called := false
+		err := b.Execute(func() error { called = true; return nil })
+		assert.ErrorIs(t, err, circuitbreaker.ErrOpenState)
+		assert.False(t, called)
+	})
+
+	t.Run("should half-open after the cool-down and close on a successful probe", func(t *testing.T) {
+		b := newBreaker()
+		for i := 0; i < 4; i++ {
+			_ = b.Execute(fail)
+		}
+		assert.Equal(t, circuitbreaker.StateOpen, b.State())
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, circuitbreaker.StateHalfOpen, b.State())
+
+		assert.NoError(t, b.Execute(succeed))
+		assert.Equal(t, circuitbreaker.StateClosed, b.State())
+	})
+
+	t.Run("should reopen when the half-open probe fails", func(t *testing.T) {
+		b := newBreaker()
+		for i := 0; i < 4; i++ {
+			_ = b.Execute(fail)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Error(t, b.Execute(fail))
+		assert.Equal(t, circuitbreaker.StateOpen, b.State())
+	})
+
+	t.Run("should not count cancellations as failures", func(t *testing.T) {
+		b := newBreaker()
+		for i := 0; i < 10; i++ {
+			_ = b.Execute(func() error { return context.Canceled })
+		}
+
+		assert.Equal(t, circuitbreaker.StateClosed, b.State())
+		assert.Zero(t, b.Snapshot().Counts.Requests)
+	})
+
+	t.Run("should stay half-open when the probe is cancelled", func(t *testing.T) {
+		b := newBreaker()
+		for i := 0; i < 4; i++ {
+			_ = b.Execute(fail)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		assert.ErrorIs(t, b.Execute(func() error { return context.Canceled }), context.Canceled)
+		assert.Equal(t, circuitbreaker.StateHalfOpen, b.State())
+		assert.True(t, b.Allow(), "the probe slot is released")
+
+		assert.NoError(t, b.Execute(succeed))
+		assert.Equal(t, circuitbreaker.StateClosed, b.State())
+	})
+
+	t.Run("should notify state changes", func(t *testing.T) {
+		var transitions []string
+		b := circuitbreaker.New(circuitbreaker.Settings{
+			Name:        "notify",
+			MinRequests: 1,
+			CoolDown:    time.Minute,
+			OnStateChange: func(name string, from, to circuitbreaker.State) {
+				transitions = append(transitions, name+":"+from.String()+"->"+to.String())
+			},
+		})
+
+		_ = b.Execute(fail)
+
+		assert.Equal(t, []string{"notify:closed->open"}, transitions)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	registry := circuitbreaker.NewRegistry()
+	registry.Add(circuitbreaker.Settings{Name: "b", MinRequests: 1, CoolDown: time.Minute})
+	registry.Add(circuitbreaker.Settings{Name: "a", MinRequests: 1, CoolDown: time.Minute})
+
+	_ = registry.Get("b").Execute(fail)
+
+	assert.True(t, registry.Allow("a"))
+	assert.False(t, registry.Allow("b"))
+	assert.True(t, registry.Allow("unknown"))
+
+	snapshots := registry.Snapshots()
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, "a", snapshots[0].Name)
+	assert.Equal(t, "closed", snapshots[0].State)
+	assert.Equal(t, "open", snapshots[1].State)
+}
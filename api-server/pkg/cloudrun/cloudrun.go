@@ -0,0 +1,89 @@
+package cloudrun
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	envPort          = "PORT"
+	envService       = "K_SERVICE"
+	envRevision      = "K_REVISION"
+	envConfiguration = "K_CONFIGURATION"
+	envProject       = "GOOGLE_CLOUD_PROJECT"
+
+	HeaderTraceContext = "X-Cloud-Trace-Context"
+)
+
+// Metadata describes the Cloud Run revision the process runs in.
+type Metadata struct {
+	Service       string `json:"service,omitempty"`
+	Revision      string `json:"revision,omitempty"`
+	Configuration string `json:"configuration,omitempty"`
+	Project       string `json:"-"`
+}
+
+// FromEnv reads the variables injected by Cloud Run. Fields are empty elsewhere.
+func FromEnv() Metadata {
+	return Metadata{
+		Service:       os.Getenv(envService),
+		Revision:      os.Getenv(envRevision),
+		Configuration: os.Getenv(envConfiguration),
+		Project:       os.Getenv(envProject),
+	}
+}
+
+// OnCloudRun reports whether the process runs on Cloud Run.
+func (m Metadata) OnCloudRun() bool {
+	return m.Service != ""
+}
+
+// Port returns the port Cloud Run wants the container to listen on, or "" when not set.
+func Port() string {
+	return os.Getenv(envPort)
+}
+
+// TraceContext is the parsed X-Cloud-Trace-Context header ("TRACE_ID/SPAN_ID;o=OPTIONS").
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+func ParseTraceContext(header string) (TraceContext, bool) {
+	if header == "" {
+		return TraceContext{}, false
+	}
+
+	ids, options, _ := strings.Cut(header, ";")
+	traceID, spanID, _ := strings.Cut(ids, "/")
+	if !validTraceID(traceID) {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: options == "o=1",
+	}, true
+}
+
+// TraceResource is the value of the logging.googleapis.com/trace field.
+func (m Metadata) TraceResource(traceID string) string {
+	if m.Project == "" {
+		return traceID
+	}
+	return "projects/" + m.Project + "/traces/" + traceID
+}
+
+func validTraceID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
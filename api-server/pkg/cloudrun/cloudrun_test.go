@@ -0,0 +1,93 @@
+package cloudrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"api-server/pkg/logger"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	tc, ok := ParseTraceContext("105445aa7843bc8bf206b12000100000/1;o=1")
+	require.True(t, ok)
+	assert.Equal(t, TraceContext{TraceID: "105445aa7843bc8bf206b12000100000", SpanID: "1", Sampled: true}, tc)
+
+	tc, ok = ParseTraceContext("105445aa7843bc8bf206b12000100000")
+	require.True(t, ok)
+	assert.Equal(t, TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"}, tc)
+
+	for _, header := range []string{"", "not-a-trace/1;o=1", "105445aa7843bc8bf206b1200010000z/1"} {
+		_, ok := ParseTraceContext(header)
+		assert.False(t, ok, header)
+	}
+}
+
+func TestMetadata_FromEnv(t *testing.T) {
+	t.Setenv("K_SERVICE", "api-server")
+	t.Setenv("K_REVISION", "api-server-00042-abc")
+	t.Setenv("K_CONFIGURATION", "api-server")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+
+	md := FromEnv()
+	assert.True(t, md.OnCloudRun())
+	assert.Equal(t, "api-server-00042-abc", md.Revision)
+	assert.Equal(t, "projects/my-project/traces/abc", md.TraceResource("abc"))
+}
+
+func TestLogging(t *testing.T) {
+	var out bytes.Buffer
+	md := Metadata{Service: "api-server", Revision: "api-server-00042-abc", Project: "my-project"}
+	log := logger.New(&out, logger.Options{ReplaceAttr: ReplaceAttr, TraceAttrs: md.TraceAttrs}).With(md.Labels())
+
+	decode := func(t *testing.T) map[string]any {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		out.Reset()
+		return entry
+	}
+
+	t.Run("should write Cloud Logging severity, message and labels", func(t *testing.T) {
+		log.Info("server started", "port", "8080")
+		entry := decode(t)
+		assert.Equal(t, "INFO", entry["severity"])
+		assert.Equal(t, "server started", entry["message"])
+		assert.Equal(t, map[string]any{"service": "api-server", "revision": "api-server-00042-abc"},
+			entry["logging.googleapis.com/labels"])
+
+		log.Warn("provider skipped")
+		assert.Equal(t, "WARNING", decode(t)["severity"])
+
+		log.Error("listen failed")
+		assert.Equal(t, "ERROR", decode(t)["severity"])
+	})
+
+	t.Run("should correlate entries with the X-Cloud-Trace-Context trace", func(t *testing.T) {
+		ctx := ContextWithTrace(context.Background(), "105445aa7843bc8bf206b12000100000/7;o=1")
+		log.InfoContext(ctx, "request served")
+
+		entry := decode(t)
+		assert.Equal(t, "projects/my-project/traces/105445aa7843bc8bf206b12000100000", entry["logging.googleapis.com/trace"])
+		assert.Equal(t, "0000000000000007", entry["logging.googleapis.com/spanId"])
+		assert.Equal(t, true, entry["logging.googleapis.com/trace_sampled"])
+	})
+
+	t.Run("should prefer the OpenTelemetry span", func(t *testing.T) {
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx := trace.ContextWithSpanContext(
+			ContextWithTrace(context.Background(), "105445aa7843bc8bf206b12000100000/7;o=1"),
+			trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+		log.InfoContext(ctx, "request served")
+
+		entry := decode(t)
+		assert.Equal(t, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736", entry["logging.googleapis.com/trace"])
+		assert.Equal(t, "00f067aa0ba902b7", entry["logging.googleapis.com/spanId"])
+	})
+}
@@ -0,0 +1,111 @@
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names of the Cloud Logging structured log format.
+// See https://cloud.google.com/logging/docs/structured-logging.
+const (
+	keySeverity     = "severity"
+	keyMessage      = "message"
+	keyTrace        = "logging.googleapis.com/trace"
+	keySpanID       = "logging.googleapis.com/spanId"
+	keyTraceSampled = "logging.googleapis.com/trace_sampled"
+	keyLabels       = "logging.googleapis.com/labels"
+
+	KeyHTTPRequest = "httpRequest"
+)
+
+// HTTPRequest is the Cloud Logging HttpRequest of an access log entry.
+type HTTPRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	Status        int    `json:"status"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	Latency       string `json:"latency"`
+}
+
+// ReplaceAttr renames the slog level and message to the Cloud Logging severity and message.
+func ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch a.Key {
+	case slog.LevelKey:
+		return slog.String(keySeverity, severity(a.Value.Any()))
+	case slog.MessageKey:
+		a.Key = keyMessage
+	}
+	return a
+}
+
+func severity(level any) string {
+	l, _ := level.(slog.Level)
+	switch {
+	case l >= slog.LevelError:
+		return "ERROR"
+	case l >= slog.LevelWarn:
+		return "WARNING"
+	case l >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// Labels are the Cloud Run revision labels attached to every entry.
+func (m Metadata) Labels() slog.Attr {
+	var attrs []any
+	if m.Service != "" {
+		attrs = append(attrs, slog.String("service", m.Service))
+	}
+	if m.Revision != "" {
+		attrs = append(attrs, slog.String("revision", m.Revision))
+	}
+	if m.Configuration != "" {
+		attrs = append(attrs, slog.String("configuration", m.Configuration))
+	}
+	return slog.Group(keyLabels, attrs...)
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying the X-Cloud-Trace-Context of the request.
+func ContextWithTrace(ctx context.Context, header string) context.Context {
+	tc, ok := ParseTraceContext(header)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceAttrs correlates a log entry with the trace of ctx: the OpenTelemetry span when there
+// is one, otherwise the X-Cloud-Trace-Context set by the Cloud Run front end.
+func (m Metadata) TraceAttrs(ctx context.Context) []slog.Attr {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return []slog.Attr{
+			slog.String(keyTrace, m.TraceResource(sc.TraceID().String())),
+			slog.String(keySpanID, sc.SpanID().String()),
+			slog.Bool(keyTraceSampled, sc.IsSampled()),
+		}
+	}
+
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	if !ok {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String(keyTrace, m.TraceResource(tc.TraceID)), slog.Bool(keyTraceSampled, tc.Sampled)}
+	// O header traz o span ID em decimal, o Cloud Logging espera 16 dígitos hexa
+	if spanID, err := strconv.ParseUint(tc.SpanID, 10, 64); err == nil {
+		attrs = append(attrs, slog.String(keySpanID, fmt.Sprintf("%016x", spanID)))
+	}
+	return attrs
+}
@@ -1,9 +1,10 @@
 package env
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"time"
 )
 
 // GetString ...
@@ -26,13 +27,34 @@ func GetInt(envVar string, defaultValue int) int {
 	return defaultValue
 }
 
+// GetFloat ...
+func GetFloat(envVar string, defaultValue float64) float64 {
+	if valueStr := os.Getenv(envVar); valueStr != "" {
+		if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+			return value
+		}
+	}
+	return defaultValue
+}
+
+// GetDuration ...
+func GetDuration(envVar string, defaultValue time.Duration) time.Duration {
+	if valueStr := os.Getenv(envVar); valueStr != "" {
+		if value, err := time.ParseDuration(valueStr); err == nil {
+			return value
+		}
+	}
+	return defaultValue
+}
+
 // CheckRequired ...
-func CheckRequired(log *log.Logger, envVarArgs ...string) {
+func CheckRequired(log *slog.Logger, envVarArgs ...string) {
 	for _, envVar := range envVarArgs {
 		if os.Getenv(envVar) == "" {
-			log.Fatalf("Environment variable '%s' is required.", envVar)
+			log.Error("Environment variable is required", "name", envVar)
+			os.Exit(1)
 		}
 
-		log.Printf("Environment variable '%s' is ok.", envVar)
+		log.Debug("Environment variable is ok", "name", envVar)
 	}
 }
@@ -1 +1,79 @@
 package env_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api-server/pkg/env"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestEnvSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather-api-key")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	value, ok, err := env.EnvSecrets{LookupEnv: lookup(map[string]string{"KEY": "from-env"})}.Secret("KEY")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "from-env", value)
+
+	value, ok, err = env.EnvSecrets{LookupEnv: lookup(map[string]string{"KEY_FILE": path})}.Secret("KEY")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "from-file", value, "file content is trimmed")
+
+	_, ok, err = env.EnvSecrets{LookupEnv: lookup(nil)}.Secret("KEY")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = env.EnvSecrets{LookupEnv: lookup(map[string]string{"KEY": "a", "KEY_FILE": path})}.Secret("KEY")
+	assert.Error(t, err)
+
+	_, _, err = env.EnvSecrets{LookupEnv: lookup(map[string]string{"KEY_FILE": path + ".missing"})}.Secret("KEY")
+	assert.Error(t, err)
+}
+
+func TestSecretWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o600))
+
+	secret, err := env.NewSecret(env.FileSecret(path), "KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", secret.Get())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotated := make(chan string, 1)
+	go secret.Watch(ctx, 5*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)), func(value string) {
+		rotated <- value
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte("v2\n"), 0o600))
+	select {
+	case value := <-rotated:
+		assert.Equal(t, "v2", value)
+	case <-time.After(time.Second):
+		t.Fatal("rotation not picked up")
+	}
+	assert.Equal(t, "v2", secret.Get())
+
+	require.NoError(t, os.Remove(path))
+	changed, err := secret.Refresh()
+	assert.Error(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "v2", secret.Get(), "previous value kept on error")
+}
@@ -0,0 +1,128 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileSuffix names the variable holding the path of a mounted secret: WEATHER_API_KEY_FILE.
+const FileSuffix = "_FILE"
+
+// SecretProvider resolves a secret by name. ok is false when the secret is not set.
+type SecretProvider interface {
+	Secret(name string) (value string, ok bool, err error)
+}
+
+// SecretProviderFunc adapts a function to SecretProvider, e.g. to plug a secret manager.
+type SecretProviderFunc func(name string) (string, bool, error)
+
+func (f SecretProviderFunc) Secret(name string) (string, bool, error) {
+	return f(name)
+}
+
+// EnvSecrets reads a secret from NAME or from the file named by NAME_FILE, as mounted by
+// Cloud Run secret volumes or Docker secrets. Setting both is an error.
+type EnvSecrets struct {
+	LookupEnv func(string) (string, bool)
+}
+
+func (p EnvSecrets) Secret(name string) (string, bool, error) {
+	lookup := p.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	value, _ := lookup(name)
+	path, _ := lookup(name + FileSuffix)
+	switch {
+	case value != "" && path != "":
+		return "", false, fmt.Errorf("set either %s or %s%s, not both", name, name, FileSuffix)
+	case path != "":
+		return FileSecret(path).Secret(name)
+	case value != "":
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// FileSecret reads the secret from path, whatever its name.
+type FileSecret string
+
+func (path FileSecret) Secret(string) (string, bool, error) {
+	content, err := os.ReadFile(string(path))
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(string(content)), true, nil
+}
+
+// Secret keeps the value of a secret up to date with its provider.
+type Secret struct {
+	name     string
+	provider SecretProvider
+	value    atomic.Pointer[string]
+}
+
+// NewSecret loads the current value of the secret.
+func NewSecret(provider SecretProvider, name string) (*Secret, error) {
+	s := &Secret{name: name, provider: provider}
+	s.value.Store(new(string))
+	if _, err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Secret) Name() string {
+	return s.name
+}
+
+// Get returns the current value, safe to call from any goroutine.
+func (s *Secret) Get() string {
+	return *s.value.Load()
+}
+
+// Refresh reloads the secret and reports whether it changed. The previous value is kept
+// on error or when the secret is no longer set, so a half-written file never blanks it.
+func (s *Secret) Refresh() (bool, error) {
+	value, ok, err := s.provider.Secret(s.name)
+	if err != nil {
+		return false, fmt.Errorf("secret %s: %w", s.name, err)
+	}
+	if !ok || value == "" || value == s.Get() {
+		return false, nil
+	}
+	s.value.Store(&value)
+	return true, nil
+}
+
+// Watch refreshes the secret every interval until ctx is done, calling onChange with every new value.
+func (s *Secret) Watch(ctx context.Context, interval time.Duration, log *slog.Logger, onChange func(value string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := s.Refresh()
+		if err != nil {
+			log.Warn("Could not refresh secret", "name", s.name, "error", err)
+			continue
+		}
+		if changed {
+			log.Info("Secret rotated", "name", s.name)
+			if onChange != nil {
+				onChange(s.Get())
+			}
+		}
+	}
+}
@@ -10,19 +10,27 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func NewHTTPClient(timeout time.Duration) *http.Client {
+// Middleware wraps the transport of the client, e.g. to instrument every call.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+func NewHTTPClient(timeout time.Duration, middlewares ...Middleware) *http.Client {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 60 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          1,
+		IdleConnTimeout:       time.Second,
+		ExpectContinueTimeout: time.Second,
+		DisableKeepAlives:     true,
+	}
+	for _, middleware := range middlewares {
+		transport = middleware(transport)
+	}
+
 	return &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 60 * time.Second,
-			}).DialContext,
-			MaxIdleConns:          1,
-			IdleConnTimeout:       time.Second,
-			ExpectContinueTimeout: time.Second,
-			DisableKeepAlives:     true,
-		},
+		Timeout:   timeout,
+		Transport: transport,
 	}
 }
@@ -1,15 +1,57 @@
 package httpclient_test
 
 import (
+	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	httpclient "api-server/pkg/http_client"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewHTTPClient(t *testing.T) {
 	client := httpclient.NewHTTPClient(time.Minute)
 	assert.NotNil(t, client)
 }
+
+type countingTracker struct {
+	inFlight int
+	closed   bool
+}
+
+func (t *countingTracker) Start() (func(), bool) {
+	if t.closed {
+		return func() {}, false
+	}
+	t.inFlight++
+	return func() { t.inFlight-- }, true
+}
+
+func TestNewTrackedHTTPClient(t *testing.T) {
+	tracker := &countingTracker{}
+	mock := httpclient.NewHTTPMultMock()
+	mock.Get("https://brasilapi.com.br/api/cep/v1/01001000").Status(200).Body(`{}`)
+	mock.Get("https://viacep.com.br/ws/01001000/json/").Err(errors.New("connection reset"))
+	client := httpclient.NewTrackedHTTPClient(mock, tracker)
+
+	req, _ := http.NewRequest("GET", "https://brasilapi.com.br/api/cep/v1/01001000", nil)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tracker.inFlight, "call ends when the body is closed")
+	require.NoError(t, res.Body.Close())
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, 0, tracker.inFlight)
+
+	req, _ = http.NewRequest("GET", "https://viacep.com.br/ws/01001000/json/", nil)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, 0, tracker.inFlight)
+
+	tracker.closed = true
+	req, _ = http.NewRequest("GET", "https://brasilapi.com.br/api/cep/v1/01001000", nil)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, httpclient.ErrShuttingDown)
+}
@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every masked value.
+const Redacted = "REDACTED"
+
+// Default query parameters and headers masked by DefaultRedactor.
+var (
+	DefaultRedactedParams  = []string{"key", "api_key", "apikey", "token", "access_token", "password", "secret"}
+	DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "X-API-Key", "Cookie", "Set-Cookie"}
+)
+
+// DefaultRedactor is used by the upstream clients and the logger.
+var DefaultRedactor = NewRedactor(DefaultRedactedParams, DefaultRedactedHeaders)
+
+// minSecretLen avoids masking every occurrence of trivially short values.
+const minSecretLen = 4
+
+// Redactor masks secrets in URLs, headers and free text (e.g. error messages) before they are logged.
+type Redactor struct {
+	headers map[string]bool
+	param   *regexp.Regexp
+
+	mu      sync.RWMutex
+	secrets []string
+}
+
+func NewRedactor(params, headers []string) *Redactor {
+	r := &Redactor{headers: make(map[string]bool, len(headers))}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	if len(params) > 0 {
+		quoted := make([]string, len(params))
+		for i, p := range params {
+			quoted[i] = regexp.QuoteMeta(p)
+		}
+		// Matches name=value in a query string wherever it appears, so URLs inside error messages
+		// (e.g. *url.Error) are masked too.
+		r.param = regexp.MustCompile(`(?i)([?&;](?:` + strings.Join(quoted, "|") + `)=)[^&;#\s"']*`)
+	}
+	return r
+}
+
+// AddSecret masks every occurrence of value, wherever it appears.
+func (r *Redactor) AddSecret(value string) {
+	if len(value) < minSecretLen {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.secrets {
+		if s == value {
+			return
+		}
+	}
+	r.secrets = append(r.secrets, value, url.QueryEscape(value))
+}
+
+// String masks configured query parameters and known secrets in s.
+func (r *Redactor) String(s string) string {
+	if r.param != nil {
+		s = r.param.ReplaceAllString(s, "${1}"+Redacted)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+// URL masks configured query parameters, the user info password and known secrets of a URL.
+func (r *Redactor) URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return r.String(rawURL)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	return r.String(u.String())
+}
+
+// Header returns a copy of h with configured headers masked.
+func (r *Redactor) Header(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			values = []string{Redacted}
+		} else {
+			masked := make([]string, len(values))
+			for i, v := range values {
+				masked[i] = r.String(v)
+			}
+			values = masked
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// Error masks the URL of a *url.Error, so callers returning it up the stack never carry the secret.
+// Other errors are returned unchanged.
+func (r *Redactor) Error(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	return &url.Error{Op: urlErr.Op, URL: r.URL(urlErr.URL), Err: urlErr.Err}
+}
@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrShuttingDown is returned for calls started after the graceful shutdown stopped waiting for new ones.
+var ErrShuttingDown = errors.New("shutting down: no new upstream calls")
+
+// InFlightTracker registers an operation and returns the func that ends it, or ok false when it
+// no longer accepts operations.
+type InFlightTracker interface {
+	Start() (done func(), ok bool)
+}
+
+type trackedClient struct {
+	client  HTTPClient
+	tracker InFlightTracker
+}
+
+// NewTrackedHTTPClient keeps every call registered in tracker until its response body is
+// closed, so a graceful shutdown can wait for the upstream calls still running.
+func NewTrackedHTTPClient(client HTTPClient, tracker InFlightTracker) HTTPClient {
+	return &trackedClient{client: client, tracker: tracker}
+}
+
+func (c *trackedClient) Do(req *http.Request) (*http.Response, error) {
+	done, ok := c.tracker.Start()
+	if !ok {
+		return nil, ErrShuttingDown
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil || res == nil || res.Body == nil {
+		done()
+		return res, err
+	}
+
+	res.Body = &trackedBody{ReadCloser: res.Body, done: done}
+	return res, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,260 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Server is a network server run by the Manager.
+type Server interface {
+	// Listen binds the server. Failing to bind aborts the startup.
+	Listen() error
+	// Serve blocks until Shutdown and returns nil once shut down.
+	Serve() error
+	// Shutdown drains the server until ctx is done.
+	Shutdown(ctx context.Context) error
+}
+
+// Readiness is flipped on once every server listens and off before draining.
+type Readiness interface {
+	SetReady(ready bool)
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Manager starts servers and background workers and stops them gracefully.
+type Manager struct {
+	log             *slog.Logger
+	readiness       Readiness
+	preStopDelay    time.Duration
+	shutdownTimeout time.Duration
+	inFlight        *InFlight
+
+	servers []namedServer
+	workers []worker
+}
+
+type Option func(*Manager)
+
+func WithReadiness(readiness Readiness) Option {
+	return func(m *Manager) {
+		m.readiness = readiness
+	}
+}
+
+// WithPreStopDelay keeps serving for d after readiness is flipped off, so load balancers
+// stop routing to the instance before it drains.
+func WithPreStopDelay(d time.Duration) Option {
+	return func(m *Manager) {
+		m.preStopDelay = d
+	}
+}
+
+// WithShutdownTimeout bounds the drain of servers, workers and in-flight calls.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.shutdownTimeout = d
+	}
+}
+
+func New(log *slog.Logger, opts ...Option) *Manager {
+	m := &Manager{
+		log:             log,
+		shutdownTimeout: 10 * time.Second,
+		inFlight:        &InFlight{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *Manager) AddServer(name string, server Server) {
+	m.servers = append(m.servers, namedServer{name: name, server: server})
+}
+
+// Go runs fn in background until shutdown. fn must return once ctx is done;
+// a non-nil error other than the cancellation stops the whole process.
+func (m *Manager) Go(name string, fn func(ctx context.Context) error) {
+	m.workers = append(m.workers, worker{name: name, run: fn})
+}
+
+// InFlight tracks the operations shutdown waits for, such as upstream calls.
+func (m *Manager) InFlight() *InFlight {
+	return m.inFlight
+}
+
+// Run starts everything and blocks until ctx is done or a component fails, then shuts down.
+// It returns the error that stopped the process, or the shutdown error, or nil.
+func (m *Manager) Run(ctx context.Context) error {
+	for i, s := range m.servers {
+		if err := s.server.Listen(); err != nil {
+			m.shutdownServers(context.Background(), m.servers[:i])
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	for _, s := range m.servers {
+		s := s
+		g.Go(func() error {
+			if err := s.server.Serve(); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+			return nil
+		})
+	}
+
+	var workers sync.WaitGroup
+	for _, w := range m.workers {
+		w := w
+		workers.Add(1)
+		g.Go(func() error {
+			defer workers.Done()
+			if err := w.run(workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("%s: %w", w.name, err)
+			}
+			return nil
+		})
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- g.Wait() }()
+
+	m.setReady(true)
+	<-gctx.Done()
+
+	m.log.Info("Shutting down", "cause", context.Cause(gctx).Error())
+	m.setReady(false)
+
+	if m.preStopDelay > 0 && ctx.Err() != nil {
+		m.log.Info("Waiting before draining", "pre_stop_delay", m.preStopDelay.String())
+		time.Sleep(m.preStopDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	shutdownErr := m.shutdownServers(shutdownCtx, m.servers)
+	stopWorkers()
+	shutdownErr = errors.Join(shutdownErr,
+		waitGroup(shutdownCtx, &workers, "background workers"),
+		m.inFlight.Wait(shutdownCtx))
+
+	// Com workers travados o g.Wait nunca retorna: não espera além do timeout
+	select {
+	case err := <-served:
+		return errors.Join(err, shutdownErr)
+	case <-shutdownCtx.Done():
+		return errors.Join(shutdownErr, errors.New("shutdown timed out"))
+	}
+}
+
+func (m *Manager) shutdownServers(ctx context.Context, servers []namedServer) error {
+	errs := make([]error, len(servers))
+
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func(i int, s namedServer) {
+			defer wg.Done()
+			if err := s.server.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", s.name, err)
+			}
+		}(i, s)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) setReady(ready bool) {
+	if m.readiness != nil {
+		m.readiness.SetReady(ready)
+	}
+}
+
+// InFlight counts operations that must finish before the process exits.
+type InFlight struct {
+	mu     sync.Mutex
+	count  int
+	closed bool
+	done   chan struct{}
+}
+
+// Start registers an operation and returns the func that ends it. Once Wait began draining no
+// operation is registered anymore and ok is false.
+func (f *InFlight) Start() (done func(), ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return func() {}, false
+	}
+	f.count++
+	var once sync.Once
+	return func() { once.Do(f.end) }, true
+}
+
+func (f *InFlight) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count--
+	if f.closed && f.count == 0 {
+		close(f.done)
+	}
+}
+
+// Wait stops registering operations and blocks until every one ended or ctx is done.
+func (f *InFlight) Wait(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.closed {
+		f.closed = true
+		f.done = make(chan struct{})
+		if f.count == 0 {
+			close(f.done)
+		}
+	}
+	done := f.done
+	f.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight operations did not finish: %w", ctx.Err())
+	}
+}
+
+func waitGroup(ctx context.Context, wg *sync.WaitGroup, what string) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s did not finish: %w", what, ctx.Err())
+	}
+}
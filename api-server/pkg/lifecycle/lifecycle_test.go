@@ -0,0 +1,193 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-server/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder keeps the order of lifecycle events.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type fakeServer struct {
+	listenErr error
+	serveErr  error
+
+	events   *recorder
+	name     string
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newFakeServer(name string, events *recorder) *fakeServer {
+	return &fakeServer{name: name, events: events, stop: make(chan struct{})}
+}
+
+func (s *fakeServer) Listen() error {
+	s.events.record(s.name + " listen")
+	return s.listenErr
+}
+
+func (s *fakeServer) Serve() error {
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stop
+	return nil
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.events.record(s.name + " shutdown")
+	s.stopOnce.Do(func() { close(s.stop) })
+	return nil
+}
+
+type fakeReadiness struct {
+	events *recorder
+}
+
+func (r *fakeReadiness) SetReady(ready bool) {
+	if ready {
+		r.events.record("ready")
+	} else {
+		r.events.record("not ready")
+	}
+}
+
+func newTestManager(events *recorder, opts ...Option) *Manager {
+	opts = append([]Option{WithReadiness(&fakeReadiness{events: events}), WithShutdownTimeout(time.Second)}, opts...)
+	return New(logger.Discard(), opts...)
+}
+
+func TestManager_Run(t *testing.T) {
+	t.Run("should fail the startup when a server can not bind", func(t *testing.T) {
+		events := &recorder{}
+		m := newTestManager(events)
+		m.AddServer("http", newFakeServer("http", events))
+		grpcServer := newFakeServer("grpc", events)
+		grpcServer.listenErr = errors.New("address already in use")
+		m.AddServer("grpc", grpcServer)
+
+		err := m.Run(context.Background())
+		assert.EqualError(t, err, "grpc: address already in use")
+		assert.Equal(t, []string{"http listen", "grpc listen", "http shutdown"}, events.list())
+	})
+
+	t.Run("should flip readiness before draining and wait for workers and in-flight calls", func(t *testing.T) {
+		events := &recorder{}
+		m := newTestManager(events, WithPreStopDelay(10*time.Millisecond))
+		m.AddServer("http", newFakeServer("http", events))
+
+		var workerStopped atomic.Bool
+		m.Go("prober", func(ctx context.Context) error {
+			<-ctx.Done()
+			workerStopped.Store(true)
+			return ctx.Err()
+		})
+
+		done, _ := m.InFlight().Start()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			done()
+		}()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		require.NoError(t, m.Run(ctx))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.True(t, workerStopped.Load())
+		assert.Equal(t, []string{"http listen", "ready", "not ready", "http shutdown"}, events.list())
+	})
+
+	t.Run("should shut down when a server fails", func(t *testing.T) {
+		events := &recorder{}
+		m := newTestManager(events)
+		m.AddServer("http", newFakeServer("http", events))
+		grpcServer := newFakeServer("grpc", events)
+		grpcServer.serveErr = errors.New("accept: too many open files")
+		m.AddServer("grpc", grpcServer)
+
+		err := m.Run(context.Background())
+		assert.EqualError(t, err, "grpc: accept: too many open files")
+		assert.Contains(t, events.list(), "http shutdown")
+	})
+
+	t.Run("should not wait for a stuck worker past the shutdown timeout", func(t *testing.T) {
+		events := &recorder{}
+		m := newTestManager(events, WithShutdownTimeout(20*time.Millisecond))
+		block := make(chan struct{})
+		defer close(block)
+		m.Go("stuck", func(ctx context.Context) error {
+			<-block
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := m.Run(ctx)
+		assert.ErrorContains(t, err, "background workers did not finish")
+	})
+}
+
+func TestInFlight(t *testing.T) {
+	t.Run("should wait for the operations and refuse new ones while draining", func(t *testing.T) {
+		var f InFlight
+		done, ok := f.Start()
+		require.True(t, ok)
+
+		waited := make(chan error, 1)
+		go func() { waited <- f.Wait(context.Background()) }()
+		require.Eventually(t, func() bool {
+			late, ok := f.Start()
+			late()
+			return !ok
+		}, time.Second, time.Millisecond)
+
+		select {
+		case <-waited:
+			t.Fatal("Wait returned with an operation running")
+		default:
+		}
+		done()
+		done()
+		require.NoError(t, <-waited)
+		require.NoError(t, f.Wait(context.Background()), "waiting again returns at once")
+	})
+
+	t.Run("should give up when ctx is done", func(t *testing.T) {
+		var f InFlight
+		_, _ = f.Start()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, f.Wait(ctx), context.DeadlineExceeded)
+	})
+}
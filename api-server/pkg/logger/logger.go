@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys shared by every component, so the logs of one request can be queried together.
+const (
+	KeyRequestID  = "request_id"
+	KeyCEP        = "cep"
+	KeyCity       = "city"
+	KeyProvider   = "provider"
+	KeyAttempt    = "attempt"
+	KeyDurationMS = "duration_ms"
+	KeyStatus     = "status"
+	KeyError      = "error"
+	KeyTraceID    = "trace_id"
+	KeySpanID     = "span_id"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Options configures New.
+type Options struct {
+	// Format is FormatJSON or FormatText. Defaults to JSON.
+	Format string
+	Level  slog.Leveler
+	// ReplaceAttr rewrites attributes, e.g. to match the field names of a log backend.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+	// TraceAttrs returns the attributes correlating a record with the trace in its context.
+	// Defaults to trace_id and span_id of the OpenTelemetry span.
+	TraceAttrs func(ctx context.Context) []slog.Attr
+	// Redact masks secrets in the message and in every string or error attribute,
+	// e.g. httpclient.DefaultRedactor.String.
+	Redact func(string) string
+}
+
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: opts.ReplaceAttr}
+	if opts.Redact != nil {
+		handlerOpts.ReplaceAttr = redactAttr(opts.Redact, opts.ReplaceAttr)
+	}
+
+	var handler slog.Handler
+	if opts.Format == FormatText {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+
+	traceAttrs := opts.TraceAttrs
+	if traceAttrs == nil {
+		traceAttrs = SpanAttrs
+	}
+	return slog.New(&traceHandler{Handler: handler, traceAttrs: traceAttrs})
+}
+
+// Discard returns a logger dropping every record.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// ParseFormat validates a LOG_FORMAT value.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatText:
+		return FormatText, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q: must be json or text", s)
+	}
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q: must be debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// With adds attributes to the logger carried by ctx.
+func With(ctx context.Context, fallback *slog.Logger, args ...any) context.Context {
+	return WithContext(ctx, FromContext(ctx, fallback).With(args...))
+}
+
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+func Duration(d time.Duration) slog.Attr {
+	return slog.Int64(KeyDurationMS, d.Milliseconds())
+}
+
+func redactAttr(redact func(string) string, next func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Value.Kind() {
+		case slog.KindString:
+			a.Value = slog.StringValue(redact(a.Value.String()))
+		case slog.KindAny:
+			if err, ok := a.Value.Any().(error); ok {
+				a.Value = slog.StringValue(redact(err.Error()))
+			}
+		}
+		if next != nil {
+			return next(groups, a)
+		}
+		return a
+	}
+}
+
+// SpanAttrs returns trace_id and span_id of the OpenTelemetry span in ctx.
+func SpanAttrs(ctx context.Context) []slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []slog.Attr{slog.String(KeyTraceID, sc.TraceID().String()), slog.String(KeySpanID, sc.SpanID().String())}
+}
+
+// traceHandler adds the trace attributes of the record context.
+type traceHandler struct {
+	slog.Handler
+	traceAttrs func(ctx context.Context) []slog.Attr
+}
+
+func (h *traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(h.traceAttrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &traceHandler{Handler: h.Handler.WithAttrs(attrs), traceAttrs: h.traceAttrs}
+}
+
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	return &traceHandler{Handler: h.Handler.WithGroup(name), traceAttrs: h.traceAttrs}
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		level, err := ParseLevel(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, level)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("TEXT")
+	require.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	base := New(&out, Options{Level: slog.LevelInfo})
+
+	t.Run("should carry the request attributes in the context", func(t *testing.T) {
+		out.Reset()
+		ctx := WithContext(context.Background(), base.With(KeyRequestID, "req-1"))
+		ctx = With(ctx, base, KeyCEP, "01001000")
+
+		FromContext(ctx, base).InfoContext(ctx, "CEP resolved", KeyProvider, "ViaCEP", Duration(120*time.Millisecond))
+		FromContext(ctx, base).DebugContext(ctx, "below the level")
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		assert.Equal(t, "CEP resolved", entry["msg"])
+		assert.Equal(t, "req-1", entry[KeyRequestID])
+		assert.Equal(t, "01001000", entry[KeyCEP])
+		assert.Equal(t, "ViaCEP", entry[KeyProvider])
+		assert.Equal(t, float64(120), entry[KeyDurationMS])
+	})
+
+	t.Run("should fall back when the context has no logger", func(t *testing.T) {
+		assert.Same(t, base, FromContext(context.Background(), base))
+	})
+
+	t.Run("should add the OpenTelemetry trace", func(t *testing.T) {
+		out.Reset()
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx := trace.ContextWithSpanContext(context.Background(),
+			trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+		base.With(KeyProvider, "HGWeather").WarnContext(ctx, "Request failed", Err(errors.New("timeout")))
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry[KeyTraceID])
+		assert.Equal(t, "00f067aa0ba902b7", entry[KeySpanID])
+		assert.Equal(t, "timeout", entry[KeyError])
+	})
+
+	t.Run("should write text locally", func(t *testing.T) {
+		var text bytes.Buffer
+		New(&text, Options{Format: FormatText}).Info("Server running", "addr", ":8080")
+		assert.True(t, strings.Contains(text.String(), `msg="Server running" addr=:8080`), text.String())
+	})
+}
+
+func TestLoggerRedact(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, Options{Redact: func(s string) string {
+		return strings.ReplaceAll(s, "s3cr3t", "REDACTED")
+	}})
+
+	log.Error("call with s3cr3t failed", "url", "https://example.com/?key=s3cr3t",
+		Err(errors.New(`Get "https://example.com/?key=s3cr3t": timeout`)), slog.Group("req", "header", "s3cr3t"))
+
+	assert.NotContains(t, out.String(), "s3cr3t")
+	assert.Contains(t, out.String(), "https://example.com/?key=REDACTED")
+}
@@ -0,0 +1,23 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathItem_Operation(t *testing.T) {
+	item := &PathItem{}
+	*item.Operation("get") = &Operation{OperationID: "get"}
+	*item.Operation("POST") = &Operation{OperationID: "post"}
+	*item.Operation("Delete") = &Operation{OperationID: "delete"}
+
+	assert.Equal(t, "get", item.Get.OperationID)
+	assert.Equal(t, "post", item.Post.OperationID)
+	assert.Equal(t, "delete", item.Delete.OperationID)
+
+	for _, method := range []string{"PUT", "PATCH", "HEAD", ""} {
+		assert.Nil(t, item.Operation(method), method)
+	}
+	assert.Equal(t, "get", item.Get.OperationID, "an unknown method does not touch GET")
+}
@@ -0,0 +1,4 @@
+// Package pb holds the Go code generated from the protobuf definitions in /proto.
+package pb
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative temperature/v1/temperature.proto
@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: temperature/v1/temperature.proto
+
+package temperaturev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TemperatureService_GetTemperature_FullMethodName = "/temperature.v1.TemperatureService/GetTemperature"
+	TemperatureService_GetAddress_FullMethodName     = "/temperature.v1.TemperatureService/GetAddress"
+	TemperatureService_Watch_FullMethodName          = "/temperature.v1.TemperatureService/Watch"
+)
+
+// TemperatureServiceClient is the client API for TemperatureService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TemperatureService exposes the CEP to temperature lookup pipeline.
+type TemperatureServiceClient interface {
+	// GetTemperature returns the current temperature of the city of a CEP.
+	GetTemperature(ctx context.Context, in *GetTemperatureRequest, opts ...grpc.CallOption) (*GetTemperatureResponse, error)
+	// GetAddress resolves a CEP to its city and state.
+	GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*GetAddressResponse, error)
+	// Watch streams the temperature of a CEP, sending a message whenever it changes.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error)
+}
+
+type temperatureServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTemperatureServiceClient(cc grpc.ClientConnInterface) TemperatureServiceClient {
+	return &temperatureServiceClient{cc}
+}
+
+func (c *temperatureServiceClient) GetTemperature(ctx context.Context, in *GetTemperatureRequest, opts ...grpc.CallOption) (*GetTemperatureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTemperatureResponse)
+	err := c.cc.Invoke(ctx, TemperatureService_GetTemperature_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *temperatureServiceClient) GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*GetAddressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAddressResponse)
+	err := c.cc.Invoke(ctx, TemperatureService_GetAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *temperatureServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TemperatureService_ServiceDesc.Streams[0], TemperatureService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TemperatureService_WatchClient = grpc.ServerStreamingClient[WatchResponse]
+
+// TemperatureServiceServer is the server API for TemperatureService service.
+// All implementations must embed UnimplementedTemperatureServiceServer
+// for forward compatibility.
+//
+// TemperatureService exposes the CEP to temperature lookup pipeline.
+type TemperatureServiceServer interface {
+	// GetTemperature returns the current temperature of the city of a CEP.
+	GetTemperature(context.Context, *GetTemperatureRequest) (*GetTemperatureResponse, error)
+	// GetAddress resolves a CEP to its city and state.
+	GetAddress(context.Context, *GetAddressRequest) (*GetAddressResponse, error)
+	// Watch streams the temperature of a CEP, sending a message whenever it changes.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error
+	mustEmbedUnimplementedTemperatureServiceServer()
+}
+
+// UnimplementedTemperatureServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTemperatureServiceServer struct{}
+
+func (UnimplementedTemperatureServiceServer) GetTemperature(context.Context, *GetTemperatureRequest) (*GetTemperatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTemperature not implemented")
+}
+func (UnimplementedTemperatureServiceServer) GetAddress(context.Context, *GetAddressRequest) (*GetAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddress not implemented")
+}
+func (UnimplementedTemperatureServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedTemperatureServiceServer) mustEmbedUnimplementedTemperatureServiceServer() {}
+func (UnimplementedTemperatureServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeTemperatureServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TemperatureServiceServer will
+// result in compilation errors.
+type UnsafeTemperatureServiceServer interface {
+	mustEmbedUnimplementedTemperatureServiceServer()
+}
+
+func RegisterTemperatureServiceServer(s grpc.ServiceRegistrar, srv TemperatureServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTemperatureServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TemperatureService_ServiceDesc, srv)
+}
+
+func _TemperatureService_GetTemperature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemperatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemperatureServiceServer).GetTemperature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TemperatureService_GetTemperature_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemperatureServiceServer).GetTemperature(ctx, req.(*GetTemperatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemperatureService_GetAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TemperatureServiceServer).GetAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TemperatureService_GetAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TemperatureServiceServer).GetAddress(ctx, req.(*GetAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TemperatureService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TemperatureServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TemperatureService_WatchServer = grpc.ServerStreamingServer[WatchResponse]
+
+// TemperatureService_ServiceDesc is the grpc.ServiceDesc for TemperatureService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TemperatureService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "temperature.v1.TemperatureService",
+	HandlerType: (*TemperatureServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTemperature",
+			Handler:    _TemperatureService_GetTemperature_Handler,
+		},
+		{
+			MethodName: "GetAddress",
+			Handler:    _TemperatureService_GetAddress_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _TemperatureService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "temperature/v1/temperature.proto",
+}
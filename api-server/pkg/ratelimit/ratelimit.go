@@ -0,0 +1,217 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limit is a token bucket: Rate tokens are added per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the outcome of taking tokens from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the tokens are available (zero when allowed, or when more tokens
+	// than the burst were asked: they never will be).
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets. Implementations shared between instances (e.g. Redis)
+// must apply the take atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// TakeN takes n tokens at once, or none.
+	TakeN(ctx context.Context, key string, limit Limit, n int) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore is a Store local to the process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+		idleTTL: 10 * time.Minute,
+	}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return m.TakeN(ctx, key, limit, 1)
+}
+
+func (m *MemoryStore) TakeN(_ context.Context, key string, limit Limit, n int) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	res := Result{Limit: limit.Burst}
+	tokens := float64(n)
+	switch {
+	case b.tokens >= tokens:
+		b.tokens -= tokens
+		res.Allowed = true
+	case n <= limit.Burst:
+		res.RetryAfter = secondsToDuration((tokens - b.tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(math.Floor(b.tokens))
+	res.ResetAfter = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+
+	return res, nil
+}
+
+// sweep drops idle buckets, which are full again by then anyway.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.idleTTL {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.last) > m.idleTTL {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Policy maps callers to limits: API keys to named tiers, everyone else to Default.
+type Policy struct {
+	Default     Limit
+	Tiers       map[string]Limit
+	APIKeyTiers map[string]string
+}
+
+// LimitFor returns the tier name and limit for an API key ("" for anonymous callers).
+func (p Policy) LimitFor(apiKey string) (string, Limit) {
+	if apiKey != "" {
+		if tier, ok := p.APIKeyTiers[apiKey]; ok {
+			return p.LimitForTier(tier)
+		}
+	}
+	return "default", p.Default
+}
+
+// LimitForTier returns the limit of a named tier, falling back to Default.
+func (p Policy) LimitForTier(tier string) (string, Limit) {
+	if limit, ok := p.Tiers[tier]; ok {
+		return tier, limit
+	}
+	return "default", p.Default
+}
+
+// DynamicPolicy holds a Policy that can be replaced while requests are served.
+type DynamicPolicy struct {
+	p atomic.Pointer[Policy]
+}
+
+func NewDynamicPolicy(p Policy) *DynamicPolicy {
+	d := &DynamicPolicy{}
+	d.Store(p)
+	return d
+}
+
+func (d *DynamicPolicy) Load() Policy {
+	return *d.p.Load()
+}
+
+func (d *DynamicPolicy) Store(p Policy) {
+	d.p.Store(&p)
+}
+
+// ParseTiers parses "name=rate:burst,name=rate:burst".
+func ParseTiers(s string) (map[string]Limit, error) {
+	tiers := make(map[string]Limit)
+	for _, item := range splitList(s) {
+		name, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tier %q: expected name=rate:burst", item)
+		}
+
+		rateStr, burstStr, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid tier %q: expected name=rate:burst", item)
+		}
+
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate for tier %q: %w", name, err)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid burst for tier %q: %w", name, err)
+		}
+
+		tiers[strings.TrimSpace(name)] = Limit{Rate: rate, Burst: burst}
+	}
+	return tiers, nil
+}
+
+// ParseAPIKeyTiers parses "key=tier,key=tier".
+func ParseAPIKeyTiers(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, item := range splitList(s) {
+		key, tier, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid API key tier: expected key=tier")
+		}
+		keys[strings.TrimSpace(key)] = strings.TrimSpace(tier)
+	}
+	return keys, nil
+}
+
+// splitList splits on commas and lines, as lists mounted from files are one entry per line.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
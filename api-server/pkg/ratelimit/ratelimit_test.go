@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	t.Run("should allow up to the burst and then reject", func(t *testing.T) {
+		res, _ := store.Take(context.Background(), "a", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1, res.Remaining)
+
+		res, _ = store.Take(context.Background(), "a", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+
+		res, _ = store.Take(context.Background(), "a", limit)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.Equal(t, 2*time.Second, res.ResetAfter)
+	})
+
+	t.Run("should refill over time", func(t *testing.T) {
+		now = now.Add(1500 * time.Millisecond)
+
+		res, _ := store.Take(context.Background(), "a", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+	})
+
+	t.Run("should keep keys independent", func(t *testing.T) {
+		res, _ := store.Take(context.Background(), "b", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1, res.Remaining)
+	})
+}
+
+func TestMemoryStore_TakeN(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 5}
+
+	res, _ := store.TakeN(context.Background(), "a", limit, 4)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = store.TakeN(context.Background(), "a", limit, 3)
+	assert.False(t, res.Allowed, "all the tokens or none")
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, 2*time.Second, res.RetryAfter)
+
+	res, _ = store.TakeN(context.Background(), "a", limit, 6)
+	assert.False(t, res.Allowed)
+	assert.Zero(t, res.RetryAfter, "more than the burst never fits")
+}
+
+func TestPolicy_LimitFor(t *testing.T) {
+	policy := Policy{
+		Default:     Limit{Rate: 1, Burst: 1},
+		Tiers:       map[string]Limit{"partner": {Rate: 50, Burst: 100}},
+		APIKeyTiers: map[string]string{"secret": "partner", "orphan": "missing"},
+	}
+
+	tier, limit := policy.LimitFor("secret")
+	assert.Equal(t, "partner", tier)
+	assert.Equal(t, Limit{Rate: 50, Burst: 100}, limit)
+
+	tier, _ = policy.LimitFor("orphan")
+	assert.Equal(t, "default", tier)
+
+	tier, limit = policy.LimitFor("")
+	assert.Equal(t, "default", tier)
+	assert.Equal(t, Limit{Rate: 1, Burst: 1}, limit)
+}
+
+func TestParseTiers(t *testing.T) {
+	tiers, err := ParseTiers("partner=50:100, internal=0.5:2")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"partner":  {Rate: 50, Burst: 100},
+		"internal": {Rate: 0.5, Burst: 2},
+	}, tiers)
+
+	_, err = ParseTiers("partner=50")
+	assert.Error(t, err)
+
+	keys, err := ParseAPIKeyTiers("k1=partner,k2=internal")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k1": "partner", "k2": "internal"}, keys)
+
+	keys, err = ParseAPIKeyTiers("k1=partner\nk2=internal\n")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k1": "partner", "k2": "internal"}, keys, "one entry per line, as mounted from a file")
+}
+
+func TestDynamicPolicy(t *testing.T) {
+	policy := NewDynamicPolicy(Policy{Default: Limit{Rate: 1, Burst: 1}})
+	policy.Store(Policy{Default: Limit{Rate: 2, Burst: 4}})
+
+	assert.Equal(t, Limit{Rate: 2, Burst: 4}, policy.Load().Default)
+}
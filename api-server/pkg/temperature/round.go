@@ -0,0 +1,98 @@
+package temperature
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode decides what happens to a value exactly halfway between two candidates.
+type RoundingMode int
+
+const (
+	// HalfUp rounds halves away from zero (2.345 -> 2.35, -2.345 -> -2.35).
+	HalfUp RoundingMode = iota
+	// HalfEven rounds halves to the even neighbour (2.345 -> 2.34, 2.355 -> 2.36).
+	HalfEven
+)
+
+// MaxPrecision is the largest number of decimal places accepted.
+const MaxPrecision = 6
+
+func (m RoundingMode) String() string {
+	if m == HalfEven {
+		return "half_even"
+	}
+	return "half_up"
+}
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_") {
+	case "half_up":
+		return HalfUp, nil
+	case "half_even":
+		return HalfEven, nil
+	default:
+		return HalfUp, fmt.Errorf("unknown rounding mode %q", s)
+	}
+}
+
+// Round rounds x to precision decimal places. It works on the shortest decimal
+// representation of x, so 2.675 rounds to 2.68 even though the float is 2.67499999...
+func Round(x float64, precision int, mode RoundingMode) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) || precision < 0 {
+		return x
+	}
+
+	negative := x < 0
+	digits := strconv.FormatFloat(math.Abs(x), 'f', -1, 64)
+	intPart, frac, _ := strings.Cut(digits, ".")
+	if len(frac) <= precision {
+		return x
+	}
+
+	kept := intPart + frac[:precision]
+	next := frac[precision]
+	rest := strings.TrimRight(frac[precision+1:], "0")
+
+	roundUp := false
+	switch {
+	case next > '5':
+		roundUp = true
+	case next == '5' && mode == HalfUp:
+		roundUp = true
+	case next == '5' && mode == HalfEven:
+		lastKept := kept[len(kept)-1] - '0'
+		roundUp = rest != "" || lastKept%2 == 1
+	}
+
+	if roundUp {
+		kept = incrementDigits(kept)
+	}
+
+	splitAt := len(kept) - precision
+	rounded, _ := strconv.ParseFloat(kept[:splitAt]+"."+kept[splitAt:], 64)
+	if negative && rounded != 0 {
+		rounded = -rounded
+	}
+	return rounded
+}
+
+// incrementDigits adds one to a string of decimal digits.
+func incrementDigits(digits string) string {
+	b := []byte(digits)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '9' {
+			b[i]++
+			return string(b)
+		}
+		b[i] = '0'
+	}
+	return "1" + string(b)
+}
+
+// Rounded returns the temperature in unit rounded to precision decimal places.
+func (t Temperature) Rounded(unit Unit, precision int, mode RoundingMode) float64 {
+	return Round(t.In(unit), precision, mode)
+}
@@ -0,0 +1,127 @@
+package temperature
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unit is a temperature scale, identified by the symbol used in the API (temp_<symbol>).
+type Unit string
+
+const (
+	Celsius    Unit = "C"
+	Fahrenheit Unit = "F"
+	Kelvin     Unit = "K"
+	Rankine    Unit = "R"
+	Reaumur    Unit = "Re"
+	Delisle    Unit = "De"
+)
+
+// Units lists every supported unit in display order.
+var Units = []Unit{Celsius, Fahrenheit, Kelvin, Rankine, Reaumur, Delisle}
+
+// DefaultUnits are the units returned when none is requested.
+var DefaultUnits = []Unit{Celsius, Fahrenheit, Kelvin}
+
+var unitNames = map[string]Unit{
+	"c": Celsius, "celsius": Celsius,
+	"f": Fahrenheit, "fahrenheit": Fahrenheit,
+	"k": Kelvin, "kelvin": Kelvin,
+	"r": Rankine, "rankine": Rankine,
+	"re": Reaumur, "reaumur": Reaumur, "réaumur": Reaumur,
+	"de": Delisle, "delisle": Delisle,
+}
+
+// ParseUnit accepts a unit symbol or name, case-insensitively.
+func ParseUnit(s string) (Unit, error) {
+	unit, ok := unitNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return "", fmt.Errorf("unknown temperature unit %q", s)
+	}
+	return unit, nil
+}
+
+// ParseUnits parses a comma separated list of units, dropping duplicates.
+func ParseUnits(s string) ([]Unit, error) {
+	var units []Unit
+	seen := make(map[Unit]bool)
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		unit, err := ParseUnit(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[unit] {
+			seen[unit] = true
+			units = append(units, unit)
+		}
+	}
+	if len(units) == 0 {
+		return nil, fmt.Errorf("no temperature unit informed")
+	}
+	return units, nil
+}
+
+// Temperature is a temperature value, independent of the scale it is expressed in.
+type Temperature struct {
+	celsius float64
+}
+
+func FromCelsius(celsius float64) Temperature {
+	return Temperature{celsius: celsius}
+}
+
+// From builds a Temperature from a value in any unit.
+func From(value float64, unit Unit) Temperature {
+	switch unit {
+	case Fahrenheit:
+		return Temperature{celsius: (value - 32) / 1.8}
+	case Kelvin:
+		return Temperature{celsius: value - 273.15}
+	case Rankine:
+		return Temperature{celsius: (value - 491.67) / 1.8}
+	case Reaumur:
+		return Temperature{celsius: value * 1.25}
+	case Delisle:
+		return Temperature{celsius: 100 - value*2/3}
+	default:
+		return Temperature{celsius: value}
+	}
+}
+
+// In returns the value of the temperature in unit.
+func (t Temperature) In(unit Unit) float64 {
+	switch unit {
+	case Fahrenheit:
+		return ConvertCelsiusToFahrenheit(t.celsius)
+	case Kelvin:
+		return ConvertCelsiusToKelvin(t.celsius)
+	case Rankine:
+		return (t.celsius + 273.15) * 1.8
+	case Reaumur:
+		return t.celsius * 0.8
+	case Delisle:
+		return (100 - t.celsius) * 1.5
+	default:
+		return t.celsius
+	}
+}
+
+func (t Temperature) Celsius() float64 {
+	return t.celsius
+}
+
+func (t Temperature) String() string {
+	return fmt.Sprintf("%g°C", t.celsius)
+}
+
+func ConvertCelsiusToFahrenheit(celsius float64) float64 {
+	return (celsius * 1.8) + 32
+}
+
+func ConvertCelsiusToKelvin(celsius float64) float64 {
+	return celsius + 273.15
+}
@@ -0,0 +1,70 @@
+package temperature_test
+
+import (
+	"testing"
+
+	"api-server/pkg/temperature"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemperature_In(t *testing.T) {
+	temp := temperature.FromCelsius(25)
+
+	for unit, expected := range map[temperature.Unit]float64{
+		temperature.Celsius:    25,
+		temperature.Fahrenheit: 77,
+		temperature.Kelvin:     298.15,
+		temperature.Rankine:    536.67,
+		temperature.Reaumur:    20,
+		temperature.Delisle:    112.5,
+	} {
+		assert.InDelta(t, expected, temp.In(unit), 1e-9, string(unit))
+		assert.InDelta(t, 25, temperature.From(expected, unit).Celsius(), 1e-9, string(unit))
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	units, err := temperature.ParseUnits("c, F,kelvin,re,C")
+	assert.NoError(t, err)
+	assert.Equal(t, []temperature.Unit{temperature.Celsius, temperature.Fahrenheit, temperature.Kelvin, temperature.Reaumur}, units)
+
+	_, err = temperature.ParseUnits("C,X")
+	assert.Error(t, err)
+
+	_, err = temperature.ParseUnits(" , ")
+	assert.Error(t, err)
+}
+
+func TestRound(t *testing.T) {
+	for _, tc := range []struct {
+		value     float64
+		precision int
+		mode      temperature.RoundingMode
+		expected  float64
+	}{
+		{298.159, 2, temperature.HalfUp, 298.16},
+		{-3.456, 2, temperature.HalfUp, -3.46},
+		{2.675, 2, temperature.HalfUp, 2.68},
+		{-2.675, 2, temperature.HalfUp, -2.68},
+		{2.345, 2, temperature.HalfEven, 2.34},
+		{2.355, 2, temperature.HalfEven, 2.36},
+		{2.3451, 2, temperature.HalfEven, 2.35},
+		{9.995, 2, temperature.HalfUp, 10},
+		{0.5, 0, temperature.HalfEven, 0},
+		{1.5, 0, temperature.HalfEven, 2},
+		{-0.004, 2, temperature.HalfUp, 0},
+		{77, 1, temperature.HalfUp, 77},
+	} {
+		assert.Equal(t, tc.expected, temperature.Round(tc.value, tc.precision, tc.mode), "%v %d %s", tc.value, tc.precision, tc.mode)
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := temperature.ParseRoundingMode("half-even")
+	assert.NoError(t, err)
+	assert.Equal(t, temperature.HalfEven, mode)
+
+	_, err = temperature.ParseRoundingMode("ceil")
+	assert.Error(t, err)
+}